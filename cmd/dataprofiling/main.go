@@ -0,0 +1,10 @@
+package main
+
+import (
+	"github.com/mschneider/dataprofiling/profiling"
+	"os"
+)
+
+func main() {
+	os.Exit(profiling.Main())
+}
@@ -0,0 +1,30 @@
+package profiling
+
+// ApplyAliases reads a file of table.column<TAB>business name lines. Reports
+// and exports meant for people show the business names, while exports
+// addressing the data, like SQL constraints, keep the physical names.
+func (db Database) ApplyAliases(fileName string) {
+	lineReader := NewLineReader(fileName)
+	for {
+		fields := ReadRow(lineReader)
+		if len(fields) == 0 {
+			break
+		}
+		if len(fields) != 2 || fields[1] == "" {
+			panic("aliases need a column and its business name per line")
+		}
+		column := db.FindColumn(fields[0])
+		if column == nil {
+			panic("unknown column in aliases: " + fields[0])
+		}
+		column.alias = fields[1]
+	}
+}
+
+// Label names the column for people, by its alias if it has one.
+func (this *Column) Label() string {
+	if this.alias != "" {
+		return this.alias
+	}
+	return this.Name()
+}
@@ -0,0 +1,136 @@
+package profiling
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// tables with more numeric columns are only checked for their first ones,
+// as the number of sum and product hypotheses grows cubically
+const maxArithmeticColumns = 24
+
+// Relation is a hypothesis c = a + b, c = a * b or c ≈ a, where b is nil.
+// It is kept while every row satisfies it.
+type Relation struct {
+	c, a, b  *Column
+	operator string
+	rows     int
+}
+
+func (this *Relation) String() string {
+	if this.b == nil {
+		return fmt.Sprintf("%v ≈ %v", this.c.Label(), this.a.Label())
+	}
+	return fmt.Sprintf("%v = %v %v %v", this.c.Label(), this.a.Label(), this.operator, this.b.Label())
+}
+
+func ApproximatelyEqual(x float64, y float64, tolerance float64) bool {
+	return math.Abs(x-y) <= tolerance*math.Max(1, math.Max(math.Abs(x), math.Abs(y)))
+}
+
+// Holds checks the relation on one row of parsed values, where missing
+// values, which do not count against it, are NaN.
+func (this *Relation) Holds(values []float64, tolerance float64) bool {
+	c, a := values[this.c.field], values[this.a.field]
+	b := 0.0
+	if this.b != nil {
+		b = values[this.b.field]
+	}
+	if math.IsNaN(a) || math.IsNaN(b) || math.IsNaN(c) {
+		return true
+	}
+	this.rows++
+	switch this.operator {
+	case "+":
+		return ApproximatelyEqual(c, a+b, tolerance)
+	case "*":
+		return ApproximatelyEqual(c, a*b, tolerance)
+	}
+	return ApproximatelyEqual(c, a, tolerance)
+}
+
+// Relations hypothesizes every relation between the table's numeric columns.
+// Constant columns are left out, as they satisfy many relations by chance.
+func (this *Table) Relations() (relations []*Relation) {
+	var numeric []*Column
+	for _, column := range this.columns {
+		if (column.dataType == "int" || column.dataType == "float" || column.quantity != nil) && column.DistinctValues() > 1 && len(numeric) < maxArithmeticColumns {
+			numeric = append(numeric, column)
+		}
+	}
+	for j, c := range numeric {
+		for i, a := range numeric {
+			if a == c {
+				continue
+			}
+			// ≈ is symmetric
+			if i > j {
+				relations = append(relations, &Relation{c: c, a: a, operator: "≈"})
+			}
+			for _, b := range numeric[i+1:] {
+				if b != c {
+					relations = append(relations, &Relation{c: c, a: a, b: b, operator: "+"}, &Relation{c: c, a: a, b: b, operator: "*"})
+				}
+			}
+		}
+	}
+	return relations
+}
+
+// FindRelations reads the table's rows, dropping every relation a row
+// violates. The relations left have held on at least two rows.
+func (this *Table) FindRelations(tolerance float64) {
+	relations := this.Relations()
+	values := make([]float64, this.fields)
+	rows := this.OpenRows()
+	for len(relations) > 0 {
+		row := rows.Read()
+		if len(row) == 0 {
+			break
+		}
+		for _, column := range this.columns {
+			values[column.field] = math.NaN()
+			value := column.Normalize(row[column.field])
+			if column.quantity != nil {
+				if number, _, ok := ParseQuantity(value); ok {
+					values[column.field] = number
+				}
+			} else if number, err := strconv.ParseFloat(value, 64); err == nil {
+				values[column.field] = number
+			}
+		}
+		kept := relations[:0]
+		for _, relation := range relations {
+			if relation.Holds(values, tolerance) {
+				kept = append(kept, relation)
+			}
+		}
+		relations = kept
+	}
+	this.relations = nil
+	for _, relation := range relations {
+		if relation.rows >= 2 {
+			this.relations = append(this.relations, relation)
+		}
+	}
+}
+
+func (db Database) FindRelations(options *Options) {
+	RunWorkers(options.analysisWorkers, len(db), func(i int) {
+		db[i].FindRelations(options.relationTolerance)
+	})
+}
+
+func (db Database) PrintRelations() {
+	count := 0
+	for _, table := range db {
+		count += len(table.relations)
+	}
+	fmt.Println("found", count, "derived columns")
+	for _, table := range db {
+		for _, relation := range table.relations {
+			fmt.Println(relation)
+		}
+	}
+}
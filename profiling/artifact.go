@@ -0,0 +1,201 @@
+package profiling
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// An artifact describes a run well enough to verify and repeat it: the tool
+// build, the configuration including the seed, checksums of every input and
+// output file, and the results. With -sign-key, an Ed25519 signature of the
+// artifact is written next to it as <artifact>.sig.
+type artifact struct {
+	ArtifactVersion int               `json:"artifact_version"`
+	Tool            artifactTool      `json:"tool"`
+	Command         string            `json:"command"`
+	Arguments       []string          `json:"arguments"`
+	Configuration   map[string]string `json:"configuration"`
+	StartedAt       time.Time         `json:"started_at"`
+	FinishedAt      time.Time         `json:"finished_at"`
+	Inputs          []artifactFile    `json:"inputs"`
+	Results         map[string]int    `json:"results"`
+	Outputs         []artifactFile    `json:"outputs"`
+	PublicKey       string            `json:"public_key,omitempty"`
+}
+
+type artifactTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Built   string `json:"built"`
+	Go      string `json:"go"`
+}
+
+type artifactFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+func DigestFile(path string) artifactFile {
+	file, err := os.Open(path)
+	check(err)
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	check(err)
+	return artifactFile{path, size, hex.EncodeToString(hash.Sum(nil))}
+}
+
+// InputFiles lists the files the run read: the table definitions, the data
+// files and the configuration files given by flags.
+func (db Database) InputFiles(options *Options) (paths []string) {
+	if options.metanomeInput != "" {
+		paths = append(paths, options.metanomeInput)
+	} else if options.dataDir != "" {
+		paths = append(paths, options.dataDir+"mapping.tsv")
+	}
+	for _, table := range db {
+		if table.partitions != nil {
+			paths = append(paths, table.PartitionPaths()...)
+		} else {
+			paths = append(paths, table.path)
+		}
+	}
+	for _, path := range []string{options.typesFile, options.columnConfigFile, options.catalogFile, options.aliasesFile, options.glossaryFile, options.checkRulesFile} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ReadSigningKey reads an Ed25519 private key in PKCS #8 PEM form, as written
+// by openssl genpkey -algorithm ed25519.
+func ReadSigningKey(fileName string) ed25519.PrivateKey {
+	data, err := os.ReadFile(fileName)
+	check(err)
+	block, _ := pem.Decode(data)
+	if block == nil {
+		panic(fileName + " holds no PEM encoded key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	check(err)
+	signingKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		panic(fileName + " holds no Ed25519 private key")
+	}
+	return signingKey
+}
+
+// ReadVerificationKey reads an Ed25519 public key in PKIX PEM form, as
+// written by openssl pkey -pubout.
+func ReadVerificationKey(fileName string) ed25519.PublicKey {
+	data, err := os.ReadFile(fileName)
+	check(err)
+	block, _ := pem.Decode(data)
+	if block == nil {
+		panic(fileName + " holds no PEM encoded key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	check(err)
+	verificationKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		panic(fileName + " holds no Ed25519 public key")
+	}
+	return verificationKey
+}
+
+// WriteArtifact describes the finished run. Outputs are the files written
+// through WriteOutput.
+func (db Database) WriteArtifact(options *Options, command string) {
+	document := status.Document()
+	revision, built := BuildInfo()
+	result := artifact{ArtifactVersion: 1, Tool: artifactTool{"dataprofiling", version, revision, built, runtime.Version()},
+		Command: command, Arguments: options.arguments, Configuration: make(map[string]string),
+		StartedAt: options.started, FinishedAt: time.Now(), Inputs: []artifactFile{}, Results: document.Results, Outputs: []artifactFile{}}
+	options.flags.VisitAll(func(f *flag.Flag) {
+		result.Configuration[f.Name] = f.Value.String()
+	})
+	for _, path := range db.InputFiles(options) {
+		result.Inputs = append(result.Inputs, DigestFile(path))
+	}
+	outputs := append([]string(nil), document.Outputs...)
+	sort.Strings(outputs)
+	for _, path := range outputs {
+		result.Outputs = append(result.Outputs, DigestFile(path))
+	}
+	var key ed25519.PrivateKey
+	if options.signKey != "" {
+		key = ReadSigningKey(options.signKey)
+		result.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	check(err)
+	check(os.WriteFile(options.artifactFile, data, 0644))
+	if key != nil {
+		signature, err := key.Sign(nil, data, crypto.Hash(0))
+		check(err)
+		check(os.WriteFile(options.artifactFile+".sig", []byte(base64.StdEncoding.EncodeToString(signature)+"\n"), 0644))
+	}
+	fmt.Println("wrote artifact", options.artifactFile)
+}
+
+func VerifyFlags(options *Options, flags *flag.FlagSet) {
+	flags.StringVar(&options.publicKey, "public-key", "", "Ed25519 public key (PEM) the artifact must be signed with")
+}
+
+// RunVerify checks an artifact's signature and that its input and output
+// files are unchanged.
+func RunVerify(options *Options) {
+	if len(options.arguments) != 1 {
+		panic("provide an artifact")
+	}
+	fileName := options.arguments[0]
+	data, err := os.ReadFile(fileName)
+	check(err)
+	var document artifact
+	check(json.Unmarshal(data, &document))
+	problems := 0
+	if options.publicKey != "" {
+		encoded, err := os.ReadFile(fileName + ".sig")
+		check(err)
+		signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+		if err != nil || !ed25519.Verify(ReadVerificationKey(options.publicKey), data, signature) {
+			fmt.Println("signature does not match", options.publicKey)
+			problems++
+		} else {
+			fmt.Println("signature matches", options.publicKey)
+		}
+	} else if document.PublicKey != "" {
+		fmt.Println("artifact is signed, give -public-key to check the signature")
+	}
+	// paths are relative to the directory the run was started in
+	for _, recorded := range append(document.Inputs, document.Outputs...) {
+		if _, err := os.Stat(recorded.Path); err != nil {
+			fmt.Println("missing", recorded.Path)
+			problems++
+		} else if DigestFile(recorded.Path) != recorded {
+			fmt.Println("changed", recorded.Path)
+			problems++
+		}
+	}
+	if problems > 0 {
+		panic(fmt.Sprintf("%v problems with artifact %v", problems, fileName))
+	}
+	fmt.Println("verified", len(document.Inputs), "inputs and", len(document.Outputs), "outputs of", document.Tool.Name, document.Tool.Version, document.Command)
+}
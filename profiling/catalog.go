@@ -0,0 +1,88 @@
+package profiling
+
+import (
+	"fmt"
+	"os"
+)
+
+// A catalog file lists what the source database declares about its tables,
+// one tab separated line per fact, naming tables as mapping.tsv does:
+//
+//	primary key	<table>	<column>
+//	foreign key	<table>	<column>	<referenced table>	<referenced column>
+//	comment	<table>	<column>	<text>
+//	type	<table>	<column>	<SQL type>
+//
+// It is usually exported from information_schema, e.g. on PostgreSQL from
+// table_constraints joined with key_column_usage and constraint_column_usage,
+// col_description for the comments and information_schema.columns for the
+// types.
+
+// ImportCatalog reads a catalog file into the columns it describes. Facts
+// about tables or columns that are not profiled are skipped.
+func (db Database) ImportCatalog(fileName string) {
+	lineReader := NewLineReader(fileName)
+	find := func(line int, table string, column string) *Column {
+		result := db.FindColumn(table + "." + column)
+		if result == nil {
+			fmt.Fprintf(os.Stderr, "%v:%v: skipping unknown column %v.%v\n", fileName, line, table, column)
+		}
+		return result
+	}
+	for line := 1; ; line++ {
+		fields := ReadRow(lineReader)
+		if len(fields) == 0 {
+			break
+		}
+		switch {
+		case fields[0] == "primary key" && len(fields) == 3:
+			if column := find(line, fields[1], fields[2]); column != nil {
+				column.primaryKey = true
+			}
+		case fields[0] == "foreign key" && len(fields) == 5:
+			column, referenced := find(line, fields[1], fields[2]), find(line, fields[3], fields[4])
+			if column != nil && referenced != nil {
+				if column.foreignKeys == nil {
+					column.foreignKeys = make(map[*Column]bool)
+				}
+				column.foreignKeys[referenced] = true
+			}
+		case fields[0] == "comment" && len(fields) == 4:
+			if column := find(line, fields[1], fields[2]); column != nil {
+				column.comment = fields[3]
+			}
+		case fields[0] == "type" && len(fields) == 4:
+			column := find(line, fields[1], fields[2])
+			declaredType := ParseDeclaredType(fields[3])
+			if declaredType == nil {
+				fmt.Fprintf(os.Stderr, "%v:%v: skipping unknown type %v\n", fileName, line, fields[3])
+			} else if column != nil {
+				column.declaredType = declaredType
+			}
+		default:
+			panic(fmt.Sprintf("%v:%v: expected a primary key, foreign key, comment or type line", fileName, line))
+		}
+	}
+}
+
+// IsDeclared reports whether the catalog declares a <= b as a foreign key.
+func (this *InclusionGraph) IsDeclared(a *Column, b *Column) bool {
+	return a.foreignKeys[b]
+}
+
+// PrintDeclaredViolations lists the declared foreign keys the data does not
+// satisfy, which are either broken or not enforced by the database.
+func (this *InclusionGraph) PrintDeclaredViolations() {
+	declared, confirmed := 0, 0
+	for _, column := range this.nodes {
+		for referenced := range column.foreignKeys {
+			declared++
+			if this.adjacencyMatrix[column.index][referenced.index] {
+				confirmed++
+			} else {
+				fmt.Println("declared foreign key", column.Label(), "->", referenced.Label(), "does not hold")
+			}
+		}
+	}
+	fmt.Println("confirmed", confirmed, "of", declared, "declared foreign keys")
+}
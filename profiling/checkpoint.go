@@ -0,0 +1,468 @@
+package profiling
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A checkpoint directory holds one <table id>.profile file per analyzed table,
+// with the path separators of ids like sales/users escaped, and a
+// graph.checkpoint file with the validation progress, so that -resume
+// can skip all work that was finished before an interruption.
+//
+// A -profile-cache directory holds only the profiles, which later runs reuse
+// as long as the table's files and the settings its values were read with
+// are unchanged. Bloom filters are not kept, as they are rebuilt from the
+// distinct values quickly and for the -filter-bits of each run.
+
+func init() {
+	gob.Register(&intStatistics{})
+	gob.Register(&floatStatistics{})
+	gob.Register(&dateStatistics{})
+	gob.Register(&stringStatistics{})
+}
+
+type tableProfile struct {
+	Path       string
+	Partitions []string
+	// size and modification time of each file, and the settings the values
+	// were read with, missing in profiles of older versions
+	Sources  []sourceStamp
+	Settings string
+	RowCount int
+	// rows the profiled sample was drawn from, 0 for unsampled profiles
+	SampledFrom int
+	Columns     []columnProfile
+	// hash of the end of a single file table, telling -incremental whether
+	// rows were appended to the file, 0 if it cannot tell
+	Tail uint64
+}
+
+type sourceStamp struct {
+	Path    string
+	Size    int64
+	ModTime int64
+}
+
+type columnProfile struct {
+	Name     string
+	DataType string
+	Stats    Statistics
+	Values   []string
+	Nulls    int
+	// values not of the type, missing in profiles of older versions
+	Mistyped int
+}
+
+type graphCheckpoint struct {
+	Inclusions [][2]string
+	Candidates [][2]string
+	// coverage of each inclusion with -min-coverage
+	Coverages []float64
+	// the ValidationSettings the progress holds for, missing in checkpoints
+	// of older versions
+	Settings []string
+}
+
+type intStatisticsData struct {
+	Samples []string
+	Average float64
+	Maximum int64
+	Minimum int64
+	// HyperLogLog registers, missing in checkpoints of older versions
+	Distinct []uint8
+	Digest   tDigestData
+}
+
+// tDigestData holds the centroids of a t-digest by their means and weights.
+type tDigestData struct {
+	Means   []float64
+	Weights []float64
+	Minimum float64
+	Maximum float64
+}
+
+func (this *tDigest) Data() (data tDigestData) {
+	this.Compress()
+	for _, centroid := range this.centroids {
+		data.Means, data.Weights = append(data.Means, centroid.mean), append(data.Weights, centroid.weight)
+	}
+	data.Minimum, data.Maximum = this.minimum, this.maximum
+	return data
+}
+
+func (this *tDigest) SetData(data tDigestData) {
+	*this = tDigest{minimum: data.Minimum, maximum: data.Maximum}
+	for i, mean := range data.Means {
+		this.centroids = append(this.centroids, centroid{mean, data.Weights[i]})
+		this.count += data.Weights[i]
+	}
+}
+
+func (this *intStatistics) GobEncode() ([]byte, error) {
+	return EncodeGob(intStatisticsData{this.samples, this.average, this.maximum, this.minimum, this.distinct.registers, this.digest.Data()})
+}
+
+func (this *intStatistics) GobDecode(data []byte) error {
+	var decoded intStatisticsData
+	err := DecodeGob(data, &decoded)
+	this.samples, this.average, this.maximum, this.minimum = decoded.Samples, decoded.Average, decoded.Maximum, decoded.Minimum
+	this.distinct.registers = decoded.Distinct
+	this.digest.SetData(decoded.Digest)
+	return err
+}
+
+type floatStatisticsData struct {
+	Samples  []string
+	Average  float64
+	Maximum  float64
+	Minimum  float64
+	Distinct []uint8
+	Digest   tDigestData
+}
+
+func (this *floatStatistics) GobEncode() ([]byte, error) {
+	return EncodeGob(floatStatisticsData{this.samples, this.average, this.maximum, this.minimum, this.distinct.registers, this.digest.Data()})
+}
+
+func (this *floatStatistics) GobDecode(data []byte) error {
+	var decoded floatStatisticsData
+	err := DecodeGob(data, &decoded)
+	this.samples, this.average, this.maximum, this.minimum = decoded.Samples, decoded.Average, decoded.Maximum, decoded.Minimum
+	this.distinct.registers = decoded.Distinct
+	this.digest.SetData(decoded.Digest)
+	return err
+}
+
+type dateStatisticsData struct {
+	Samples  []string
+	Maximum  time.Time
+	Minimum  time.Time
+	Layouts  uint16
+	Distinct []uint8
+	Digest   tDigestData
+}
+
+func (this *dateStatistics) GobEncode() ([]byte, error) {
+	return EncodeGob(dateStatisticsData{this.samples, this.maximum, this.minimum, this.layouts, this.distinct.registers, this.digest.Data()})
+}
+
+func (this *dateStatistics) GobDecode(data []byte) error {
+	var decoded dateStatisticsData
+	err := DecodeGob(data, &decoded)
+	this.samples, this.maximum, this.minimum, this.layouts = decoded.Samples, decoded.Maximum, decoded.Minimum, decoded.Layouts
+	this.distinct.registers = decoded.Distinct
+	this.digest.SetData(decoded.Digest)
+	return err
+}
+
+type stringStatisticsData struct {
+	Samples       []string
+	AverageLength float64
+	Maximum       string
+	Minimum       string
+	Longest       string
+	Shortest      string
+	Distinct      []uint8
+	// missing in profiles of older versions
+	Patterns      map[string]int
+	OtherPatterns int
+}
+
+func (this *stringStatistics) GobEncode() ([]byte, error) {
+	return EncodeGob(stringStatisticsData{this.samples, this.averageLength, this.maximum, this.minimum, this.longest, this.shortest, this.distinct.registers,
+		this.patterns, this.otherPatterns})
+}
+
+func (this *stringStatistics) GobDecode(data []byte) error {
+	var decoded stringStatisticsData
+	err := DecodeGob(data, &decoded)
+	this.samples, this.averageLength = decoded.Samples, decoded.AverageLength
+	this.maximum, this.minimum, this.longest, this.shortest = decoded.Maximum, decoded.Minimum, decoded.Longest, decoded.Shortest
+	this.distinct.registers = decoded.Distinct
+	this.patterns, this.otherPatterns = decoded.Patterns, decoded.OtherPatterns
+	return err
+}
+
+func EncodeGob(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(value)
+	return buffer.Bytes(), err
+}
+
+func DecodeGob(data []byte, value interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(value)
+}
+
+// WriteGob replaces fileName atomically, so an interruption while writing
+// never leaves a truncated checkpoint behind.
+func WriteGob(fileName string, value interface{}) {
+	data, err := EncodeGob(value)
+	check(err)
+	check(os.WriteFile(fileName+".tmp", data, 0644))
+	check(os.Rename(fileName+".tmp", fileName))
+}
+
+func ReadGob(fileName string, value interface{}) bool {
+	data, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return false
+	}
+	check(err)
+	return DecodeGob(data, value) == nil
+}
+
+func (this *Table) ProfileFileName(checkpointDir string) string {
+	return filepath.Join(checkpointDir, this.FileId()+".profile")
+}
+
+// FileId returns the table's id for naming files in a single directory, as
+// ids of tables in subdirectories keep the path, escaping it reversibly so
+// that distinct ids never share a file.
+func (this *Table) FileId() string {
+	return url.PathEscape(filepath.ToSlash(this.id))
+}
+
+// Sources stamps the table's files, so that changed ones are analyzed again.
+func (this *Table) Sources() (stamps []sourceStamp) {
+	paths := this.PartitionPaths()
+	if this.partitions == nil {
+		paths = []string{this.path}
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		check(err)
+		stamps = append(stamps, sourceStamp{path, info.Size(), info.ModTime().UnixNano()})
+	}
+	return stamps
+}
+
+// ProfileSettings describes how the table's values are read and normalized.
+func (this *Table) ProfileSettings() string {
+	var settings strings.Builder
+	if this.format != nil {
+		fmt.Fprintf(&settings, "%+v", *this.format)
+	}
+	fmt.Fprintf(&settings, " header=%v types=%v:%v", this.hasHeader, this.typeSample, this.typeAgreement)
+	if this.sampling != nil {
+		fmt.Fprintf(&settings, " sample=%v", this.sampling)
+	}
+	for _, column := range this.columns {
+		fmt.Fprintf(&settings, " %v:%v", column.field, column.typeOverride)
+		if config := column.config; config != nil {
+			var tokens []string
+			for token := range config.nullTokens {
+				tokens = append(tokens, token)
+			}
+			sort.Strings(tokens)
+			fmt.Fprintf(&settings, ":%v:%v:%q", config.trim, config.caseFolding, tokens)
+		}
+	}
+	return settings.String()
+}
+
+func (this *Table) SaveProfile(checkpointDir string) {
+	for _, column := range this.columns {
+		if column.spilled != nil {
+			logger.Infof("not saving the profile of %v, as the values of %v were spilled to disk", this.QualifiedName(), column.name)
+			return
+		}
+	}
+	profile := tableProfile{Path: this.path, Partitions: this.PartitionPaths(), Sources: this.Sources(), Settings: this.ProfileSettings(), RowCount: this.rowCount, SampledFrom: this.sampledFrom}
+	if this.partitions == nil {
+		profile.Tail = TailHash(this.path, profile.Sources[0].Size)
+	}
+	for _, column := range this.columns {
+		values := make([]string, 0, len(column.values))
+		for value := range column.values {
+			values = append(values, value)
+		}
+		profile.Columns = append(profile.Columns, columnProfile{column.name, column.dataType, column.stats, values, column.nulls, column.mistyped})
+	}
+	WriteGob(this.ProfileFileName(checkpointDir), profile)
+}
+
+// LoadProfile restores the table's analysis results and reports whether a
+// profile matching the table's current definition and files was found.
+func (this *Table) LoadProfile(checkpointDir string) bool {
+	profile := this.ReadProfile(checkpointDir)
+	if profile == nil || fmt.Sprint(profile.Sources) != fmt.Sprint(this.Sources()) {
+		return false
+	}
+	this.RestoreProfile(profile)
+	return true
+}
+
+// ReadProfile returns the saved profile of the table if it matches the
+// table's current definition and settings, whether or not its files changed.
+func (this *Table) ReadProfile(checkpointDir string) *tableProfile {
+	var profile tableProfile
+	if !ReadGob(this.ProfileFileName(checkpointDir), &profile) {
+		return nil
+	}
+	if profile.Path != this.path || len(profile.Columns) != len(this.columns) || profile.Settings != this.ProfileSettings() {
+		return nil
+	}
+	for i, column := range this.columns {
+		if profile.Columns[i].Name != column.name {
+			return nil
+		}
+		if column.typeOverride != "" && profile.Columns[i].DataType != column.typeOverride {
+			return nil
+		}
+		// older versions kept the statistics of float columns as strings
+		if fmt.Sprintf("%T", profile.Columns[i].Stats) != fmt.Sprintf("%T", NewStatistics(profile.Columns[i].DataType)) {
+			return nil
+		}
+	}
+	return &profile
+}
+
+// RestoreProfile takes the table's analysis results from a saved profile.
+func (this *Table) RestoreProfile(profile *tableProfile) {
+	this.rowCount, this.sampledFrom = profile.RowCount, profile.SampledFrom
+	for i, column := range this.columns {
+		column.dataType = profile.Columns[i].DataType
+		column.stats = profile.Columns[i].Stats
+		column.nulls, column.mistyped = profile.Columns[i].Nulls, profile.Columns[i].Mistyped
+		for _, value := range profile.Columns[i].Values {
+			column.AddValue(value)
+		}
+		column.FinishValues()
+		column.grown = false
+	}
+}
+
+func (this *InclusionGraph) CheckpointFileName(checkpointDir string) string {
+	return filepath.Join(checkpointDir, "graph.checkpoint")
+}
+
+// SaveCheckpoint saves the inclusions found and the candidates left, among
+// them the pending ones taken from the columns but not validated yet.
+func (this *InclusionGraph) SaveCheckpoint(checkpointDir string, pending []*Candidate, settings []string) {
+	checkpoint := graphCheckpoint{Settings: settings}
+	for _, column := range this.nodes {
+		for _, other := range this.nodes {
+			if column != other && this.adjacencyMatrix[column.index][other.index] {
+				checkpoint.Inclusions = append(checkpoint.Inclusions, [2]string{column.String(), other.String()})
+				if this.coverage != nil {
+					checkpoint.Coverages = append(checkpoint.Coverages, this.coverage[[2]int{column.index, other.index}])
+				}
+			}
+		}
+		for candidate := range column.candidates {
+			checkpoint.Candidates = append(checkpoint.Candidates, [2]string{column.String(), candidate.String()})
+		}
+	}
+	for _, candidate := range pending {
+		checkpoint.Candidates = append(checkpoint.Candidates, [2]string{candidate.a.String(), candidate.b.String()})
+	}
+	WriteGob(this.CheckpointFileName(checkpointDir), checkpoint)
+}
+
+// ResumeValidation restores the validation progress of -resume, unless a
+// table had to be analyzed again, which may have changed its inclusions.
+func (db Database) ResumeValidation(options *Options, graph *InclusionGraph) bool {
+	if options.resume == "" {
+		return false
+	}
+	for _, table := range db {
+		if !table.resumed {
+			logger.Infof("validating from the start, as the profile of %v was not resumed", table.QualifiedName())
+			return false
+		}
+	}
+	if !graph.LoadCheckpoint(options.resume, options.ValidationSettings()) {
+		return false
+	}
+	logger.Infof("resuming validation with %v inclusions found and %v candidates left", graph.Count(), db.CandidateCount())
+	return true
+}
+
+// ValidationSettings describes the settings deciding which candidates are
+// generated and which inclusions accepted, for which alone the validation
+// progress of a checkpoint holds.
+func (this *Options) ValidationSettings() []string {
+	return []string{
+		"-nulls " + this.nulls,
+		fmt.Sprintf("-min-coverage %v", this.minCoverage),
+		"-candidate-index " + this.candidateIndex,
+		fmt.Sprintf("-lsh-rows %v", this.lshRows),
+		"-candidate-pruning " + this.candidatePruning,
+		fmt.Sprintf("-min-name-similarity %v", this.minNameSimilarity),
+		fmt.Sprintf("-filter-bits %v", this.filterBits),
+		fmt.Sprintf("-filter-hashes %v", this.filterHashes),
+		fmt.Sprintf("-target-fpp %v", this.targetFPP),
+		fmt.Sprintf("-filter-per-column=%v", this.filterPerColumn),
+		fmt.Sprintf("-geo-inclusions=%v", this.geoInclusions),
+		fmt.Sprintf("-include %q", this.include),
+		fmt.Sprintf("-exclude %q", this.exclude),
+		fmt.Sprintf("-partitions %q", this.partitions),
+	}
+}
+
+// CheckSettings returns an error naming the settings the checkpoint was saved
+// with that differ from the given ones.
+func (this *graphCheckpoint) CheckSettings(settings []string) error {
+	if len(this.Settings) != len(settings) {
+		return fmt.Errorf("its validation settings are unknown, as it was saved by another version")
+	}
+	var differing []string
+	for i, setting := range settings {
+		if this.Settings[i] != setting {
+			differing = append(differing, fmt.Sprintf("%v instead of %v", this.Settings[i], setting))
+		}
+	}
+	if len(differing) > 0 {
+		return fmt.Errorf("its validation ran with %v", strings.Join(differing, ", "))
+	}
+	return nil
+}
+
+// LoadCheckpoint restores the validated inclusions and the candidates that
+// were still waiting for validation, replacing candidate generation. It
+// refuses checkpoints saved with other settings, unless settings is nil.
+func (this *InclusionGraph) LoadCheckpoint(checkpointDir string, settings []string) bool {
+	var checkpoint graphCheckpoint
+	if !ReadGob(this.CheckpointFileName(checkpointDir), &checkpoint) {
+		return false
+	}
+	if settings != nil {
+		if err := checkpoint.CheckSettings(settings); err != nil {
+			panic(fmt.Errorf("cannot resume from %v, %v; resume with the same settings or start afresh without -resume", checkpointDir, err))
+		}
+	}
+	if this.coverage != nil && len(checkpoint.Coverages) != len(checkpoint.Inclusions) {
+		panic(fmt.Errorf("cannot resume from %v, it holds %v coverages for %v inclusions", checkpointDir, len(checkpoint.Coverages), len(checkpoint.Inclusions)))
+	}
+	columns := make(map[string]*Column)
+	for _, column := range this.nodes {
+		columns[column.String()] = column
+	}
+	for _, pair := range append(checkpoint.Inclusions, checkpoint.Candidates...) {
+		if columns[pair[0]] == nil || columns[pair[1]] == nil {
+			return false
+		}
+	}
+	for _, column := range this.nodes {
+		column.candidates = make(map[*Column]bool)
+	}
+	for i, pair := range checkpoint.Inclusions {
+		a, b := columns[pair[0]].index, columns[pair[1]].index
+		this.adjacencyMatrix[a][b] = true
+		if this.coverage != nil {
+			this.coverage[[2]int{a, b}] = checkpoint.Coverages[i]
+		}
+	}
+	for _, pair := range checkpoint.Candidates {
+		columns[pair[0]].candidates[columns[pair[1]]] = true
+	}
+	return true
+}
@@ -0,0 +1,239 @@
+package profiling
+
+import (
+	"fmt"
+	"github.com/parquet-go/parquet-go"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tables whose file ends in .parquet are read from Parquet files of any
+// compression. Their columns are the leaf columns of the file's schema, in
+// order, named by their paths unless mapping.tsv names them. Analysis reads
+// each column from its chunks in every row group on its own, without
+// assembling rows, while the phases needing whole rows read them row by row.
+// Values are written as text like database exports: dates and timestamps in
+// ISO format, decimals with their scale, and the values of a repeated column
+// joined by commas.
+
+func IsParquetFile(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".parquet")
+}
+
+// OpenParquet opens a Parquet file and returns its leaf columns.
+func OpenParquet(path string) (handle *os.File, file *parquet.File, leaves []*parquet.Column) {
+	handle, err := os.Open(path)
+	check(err)
+	info, err := handle.Stat()
+	check(err)
+	file, err = parquet.OpenFile(countingReaderAt{handle}, info.Size())
+	check(err)
+	var walk func(column *parquet.Column)
+	walk = func(column *parquet.Column) {
+		if column.Leaf() {
+			leaves = append(leaves, column)
+		}
+		for _, child := range column.Columns() {
+			walk(child)
+		}
+	}
+	walk(file.Root())
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].Index() < leaves[j].Index() })
+	return handle, file, leaves
+}
+
+// ParquetColumnNames returns the paths of the leaf columns of a Parquet file.
+func ParquetColumnNames(path string) (names []string) {
+	handle, _, leaves := OpenParquet(path)
+	defer handle.Close()
+	for _, leaf := range leaves {
+		names = append(names, strings.Join(leaf.Path(), "."))
+	}
+	return names
+}
+
+// FormatParquetValue writes a value of a leaf column as text.
+func FormatParquetValue(value parquet.Value, column *parquet.Column) string {
+	if value.IsNull() {
+		return ""
+	}
+	if logical := column.Type().LogicalType(); logical != nil {
+		switch {
+		case logical.Date != nil:
+			return time.Unix(int64(value.Int32())*24*60*60, 0).UTC().Format("2006-01-02")
+		case logical.Timestamp != nil:
+			unit := logical.Timestamp.Unit
+			switch {
+			case unit.Millis != nil:
+				return FormatSQLValue(time.UnixMilli(value.Int64()).UTC())
+			case unit.Micros != nil:
+				return FormatSQLValue(time.UnixMicro(value.Int64()).UTC())
+			}
+			return FormatSQLValue(time.Unix(0, value.Int64()).UTC())
+		case logical.Decimal != nil:
+			unscaled := new(big.Int)
+			switch value.Kind() {
+			case parquet.Int32:
+				unscaled.SetInt64(int64(value.Int32()))
+			case parquet.Int64:
+				unscaled.SetInt64(value.Int64())
+			default:
+				// big-endian two's complement
+				bytes := value.ByteArray()
+				unscaled.SetBytes(bytes)
+				if len(bytes) > 0 && bytes[0]&0x80 != 0 {
+					unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(bytes))))
+				}
+			}
+			scale := int(logical.Decimal.Scale)
+			return new(big.Rat).SetFrac(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)).FloatString(scale)
+		case logical.UUID != nil:
+			b := value.ByteArray()
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+		}
+	}
+	switch value.Kind() {
+	case parquet.Float:
+		return FormatFloat(float64(value.Float()))
+	case parquet.Double:
+		return FormatFloat(value.Double())
+	}
+	return value.String()
+}
+
+// EachParquetCell passes the value of a leaf column in each row to visit,
+// reading the column chunks of the row groups one after the other.
+func EachParquetCell(file *parquet.File, leaf *parquet.Column, visit func(cell string)) {
+	var cell []string
+	started := false
+	flush := func() {
+		if started {
+			visit(strings.Join(cell, ","))
+		}
+		cell, started = cell[:0], true
+	}
+	buffer := make([]parquet.Value, 1024)
+	for _, group := range file.RowGroups() {
+		pages := group.ColumnChunks()[leaf.Index()].Pages()
+		for {
+			page, err := pages.ReadPage()
+			if err == io.EOF {
+				break
+			}
+			check(err)
+			values := page.Values()
+			for {
+				n, err := values.ReadValues(buffer)
+				for _, value := range buffer[:n] {
+					if value.RepetitionLevel() == 0 {
+						flush()
+					}
+					if !value.IsNull() {
+						cell = append(cell, FormatParquetValue(value, leaf))
+					}
+				}
+				if err == io.EOF {
+					break
+				}
+				check(err)
+			}
+			parquet.Release(page)
+		}
+		check(pages.Close())
+	}
+	flush()
+}
+
+// AnalyzeParquet profiles the columns of a Parquet table one after the
+// other, each from its column chunks.
+func (this *Table) AnalyzeParquet() {
+	handle, file, leaves := OpenParquet(this.path)
+	defer handle.Close()
+	if this.fields > len(leaves) {
+		panic(fmt.Sprintf("%v has %v columns, but mapping.tsv names %v", this.path, len(leaves), this.fields))
+	}
+	this.rowCount = int(file.NumRows())
+	sampleSize := 1
+	if this.typeSample > sampleSize {
+		sampleSize = this.typeSample
+	}
+	for _, column := range this.columns {
+		column.nulls, column.mistyped = 0, 0
+		// the values read before the column's type is inferred from them
+		var sample []string
+		started := false
+		start := func() {
+			column.StartAnalysis(sample, this.typeAgreement)
+			for _, value := range sample {
+				column.Observe(value)
+			}
+			sample, started = nil, true
+		}
+		EachParquetCell(file, leaves[column.field], func(cell string) {
+			value := column.Normalize(cell)
+			if started {
+				column.Observe(value)
+				return
+			}
+			if sample = append(sample, value); len(sample) == sampleSize {
+				start()
+			}
+		})
+		if !started {
+			start()
+		}
+	}
+}
+
+// parquetRows reads the rows of a Parquet table with the values of its leaf
+// columns as text.
+type parquetRows struct {
+	handle *os.File
+	reader *parquet.Reader
+	leaves []*parquet.Column
+	rows   []parquet.Row
+	next   int
+	count  int
+}
+
+func OpenParquetRows(path string) *parquetRows {
+	handle, file, leaves := OpenParquet(path)
+	return &parquetRows{handle: handle, reader: parquet.NewReader(file), leaves: leaves, rows: make([]parquet.Row, 256)}
+}
+
+// Read returns the next row, or nil after the last one.
+func (this *parquetRows) Read() (fields []string) {
+	if this.next == this.count {
+		if this.reader == nil {
+			return nil
+		}
+		n, err := this.reader.ReadRows(this.rows)
+		if err != nil && err != io.EOF {
+			check(err)
+		}
+		this.next, this.count = 0, n
+		if n == 0 {
+			check(this.reader.Close())
+			this.handle.Close()
+			this.reader = nil
+			return nil
+		}
+	}
+	row := this.rows[this.next]
+	this.next++
+	cells := make([][]string, len(this.leaves))
+	for _, value := range row {
+		if !value.IsNull() {
+			cells[value.Column()] = append(cells[value.Column()], FormatParquetValue(value, this.leaves[value.Column()]))
+		}
+	}
+	fields = make([]string, len(this.leaves))
+	for i, cell := range cells {
+		fields[i] = strings.Join(cell, ",")
+	}
+	return fields
+}
@@ -0,0 +1,112 @@
+package profiling
+
+import (
+	"strings"
+)
+
+// ColumnConfig holds the settings of one column that differ from the
+// defaults, for data sets too inconsistent for global settings.
+type ColumnConfig struct {
+	trim bool
+	// lower or upper, empty to keep the case
+	caseFolding string
+	// values meaning null, which are profiled as empty values
+	nullTokens map[string]bool
+}
+
+// ApplyColumnConfig reads a file of table.column<TAB>setting... lines. The
+// settings are trim, lower, upper, exclude, type=<type> and null=<tokens>
+// with comma separated tokens, e.g.
+//
+//	orders.country	trim	upper	null=NA,n/a
+//	orders.comment	exclude
+//
+// Excluded columns are neither profiled nor searched for inclusions.
+func (db Database) ApplyColumnConfig(fileName string) {
+	lineReader := NewLineReader(fileName)
+	excluded := make(map[*Column]bool)
+	for {
+		fields := ReadRow(lineReader)
+		if len(fields) == 0 {
+			break
+		}
+		if len(fields) < 2 {
+			panic("column config needs a column and at least one setting per line")
+		}
+		column := db.FindColumn(fields[0])
+		if column == nil {
+			panic("unknown column in column config: " + fields[0])
+		}
+		if column.config == nil {
+			column.config = &ColumnConfig{}
+		}
+		for _, setting := range fields[1:] {
+			key, value, _ := strings.Cut(setting, "=")
+			switch key {
+			case "trim":
+				column.config.trim = true
+			case "lower", "upper":
+				column.config.caseFolding = key
+			case "exclude":
+				excluded[column] = true
+			case "type":
+				if !IsDataType(value) {
+					panic("unknown type in column config: " + value)
+				}
+				column.typeOverride = value
+			case "null":
+				column.config.nullTokens = make(map[string]bool)
+				for _, token := range strings.Split(value, ",") {
+					column.config.nullTokens[token] = true
+				}
+			default:
+				panic("unknown setting in column config: " + setting)
+			}
+		}
+	}
+	for _, table := range db {
+		var columns []*Column
+		for _, column := range table.columns {
+			if !excluded[column] {
+				columns = append(columns, column)
+			}
+		}
+		table.columns = columns
+	}
+}
+
+// ApplyNullTokens adds values meaning null to the null tokens of every
+// column.
+func (db Database) ApplyNullTokens(tokens []string) {
+	for _, column := range db.AllColumns() {
+		if column.config == nil {
+			column.config = &ColumnConfig{}
+		}
+		if column.config.nullTokens == nil {
+			column.config.nullTokens = make(map[string]bool)
+		}
+		for _, token := range tokens {
+			column.config.nullTokens[token] = true
+		}
+	}
+}
+
+// Normalize applies the column's settings to a value read from its file.
+func (this *Column) Normalize(value string) string {
+	if this.config == nil {
+		return value
+	}
+	if this.config.trim {
+		value = strings.TrimSpace(value)
+	}
+	if this.config.nullTokens[value] {
+		return ""
+	}
+	switch this.config.caseFolding {
+	case "lower":
+		value = strings.ToLower(value)
+	case "upper":
+		value = strings.ToUpper(value)
+	}
+	return value
+}
@@ -0,0 +1,251 @@
+package profiling
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The compare command reports the changes between two documents written by
+// -output json: tables, columns and inclusions found in only one of them, and
+// the columns of both whose profiles drifted by more than -drift-thresholds.
+// Unlike -baseline, it sees only the results rather than the values, so
+// range, cardinality and nulls are scored from the statistics as -baseline
+// does, and distribution by the largest difference between the newer
+// quantiles and the older histogram's distribution function, for numeric and
+// date columns only. Redacted minimums and maximums are not compared.
+
+type comparedColumn struct {
+	name   string
+	scores map[string]float64
+	// metrics exceeding their thresholds
+	flagged []string
+}
+
+type comparison struct {
+	newTables, removedTables         []string
+	newColumns, removedColumns       []string
+	newInclusions, removedInclusions []string
+	columns                          []*comparedColumn
+}
+
+func ReadResultDocument(fileName string) (document resultDocument) {
+	data, err := os.ReadFile(fileName)
+	check(err)
+	if err := json.Unmarshal(data, &document); err != nil {
+		panic(fileName + " is no document written by -output json: " + err.Error())
+	}
+	return document
+}
+
+// CDF estimates the share of values up to value, assuming the values of each
+// bin are spread evenly.
+func (this *Histogram) CDF(value float64) float64 {
+	total, below := 0, 0.0
+	for _, bin := range this.Bins {
+		total += bin.Count
+		switch {
+		case value >= bin.Upper:
+			below += float64(bin.Count)
+		case value > bin.Lower:
+			below += float64(bin.Count) * (value - bin.Lower) / (bin.Upper - bin.Lower)
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return below / float64(total)
+}
+
+// HistogramDistance returns the largest difference between the quantiles of
+// current and the distribution function of baseline.
+func HistogramDistance(baseline *Histogram, current *Histogram) (distance float64) {
+	for _, quantile := range current.Quantiles {
+		distance = math.Max(distance, math.Abs(baseline.CDF(quantile.Value)-quantile.Quantile))
+	}
+	return distance
+}
+
+// ResultRange returns a column's minimum and maximum as numbers, dates as
+// seconds since 1970, or false if they are not of its type.
+func ResultRange(result columnResult) (minimum float64, maximum float64, ok bool) {
+	if result.Minimum == nil || result.Maximum == nil {
+		return 0, 0, false
+	}
+	parse := func(value string) (float64, bool) {
+		if result.DataType == "date" {
+			date, layout := ParseDate(value)
+			return float64(date.Unix()), layout >= 0
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		return number, err == nil
+	}
+	minimum, okMinimum := parse(*result.Minimum)
+	maximum, okMaximum := parse(*result.Maximum)
+	return minimum, maximum, okMinimum && okMaximum
+}
+
+// CompareResults scores the drift of a column between two documents.
+func CompareResults(baseline columnResult, baselineRows int, current columnResult, currentRows int, thresholds map[string]float64) *comparedColumn {
+	compared := &comparedColumn{scores: make(map[string]float64)}
+	switch current.DataType {
+	case "int", "float", "date":
+		oldMinimum, oldMaximum, okOld := ResultRange(baseline)
+		newMinimum, newMaximum, okNew := ResultRange(current)
+		if okOld && okNew {
+			span := oldMaximum - oldMinimum
+			if span == 0 {
+				span = 1
+			}
+			compared.scores["range"] = math.Max(math.Abs(newMinimum-oldMinimum), math.Abs(newMaximum-oldMaximum)) / span
+		}
+	default:
+		if baseline.AverageLength != nil && current.AverageLength != nil {
+			compared.scores["range"] = RelativeChange(*baseline.AverageLength, *current.AverageLength)
+		}
+	}
+	compared.scores["cardinality"] = RelativeChange(float64(baseline.DistinctValues), float64(current.DistinctValues))
+	if baselineRows > 0 && currentRows > 0 {
+		compared.scores["nulls"] = math.Abs(float64(current.Nulls)/float64(currentRows) - float64(baseline.Nulls)/float64(baselineRows))
+	}
+	if baseline.Histogram != nil && current.Histogram != nil {
+		compared.scores["distribution"] = HistogramDistance(baseline.Histogram, current.Histogram)
+	}
+	for _, metric := range driftMetrics {
+		if compared.scores[metric] > thresholds[metric] {
+			compared.flagged = append(compared.flagged, metric)
+		}
+	}
+	if baseline.DataType != current.DataType {
+		compared.flagged = append(compared.flagged, "type")
+	}
+	return compared
+}
+
+// Differences returns the keys only in a and those only in b, sorted.
+func Differences(a map[string]bool, b map[string]bool) (onlyA []string, onlyB []string) {
+	for key := range a {
+		if !b[key] {
+			onlyA = append(onlyA, key)
+		}
+	}
+	for key := range b {
+		if !a[key] {
+			onlyB = append(onlyB, key)
+		}
+	}
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+	return onlyA, onlyB
+}
+
+func CompareDocuments(baseline resultDocument, current resultDocument, thresholds map[string]float64) (result comparison) {
+	tables := func(document resultDocument) map[string]tableResult {
+		tables := make(map[string]tableResult)
+		for _, table := range document.Tables {
+			tables[table.Name] = table
+		}
+		return tables
+	}
+	keys := func(tables map[string]tableResult) map[string]bool {
+		keys := make(map[string]bool)
+		for name := range tables {
+			keys[name] = true
+		}
+		return keys
+	}
+	oldTables, newTables := tables(baseline), tables(current)
+	result.removedTables, result.newTables = Differences(keys(oldTables), keys(newTables))
+	names := make([]string, 0, len(newTables))
+	for name := range newTables {
+		if _, ok := oldTables[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		oldTable, newTable := oldTables[name], newTables[name]
+		oldColumns, newColumns := make(map[string]bool), make(map[string]bool)
+		baselines := make(map[string]columnResult)
+		for _, column := range oldTable.Columns {
+			oldColumns[name+"."+column.Name] = true
+			baselines[column.Name] = column
+		}
+		for _, column := range newTable.Columns {
+			newColumns[name+"."+column.Name] = true
+			if old, ok := baselines[column.Name]; ok {
+				compared := CompareResults(old, oldTable.Rows, column, newTable.Rows, thresholds)
+				compared.name = name + "." + column.Name
+				result.columns = append(result.columns, compared)
+			}
+		}
+		removed, added := Differences(oldColumns, newColumns)
+		result.removedColumns = append(result.removedColumns, removed...)
+		result.newColumns = append(result.newColumns, added...)
+	}
+	inclusions := func(document resultDocument) map[string]bool {
+		inclusions := make(map[string]bool)
+		for _, inclusion := range document.Inclusions {
+			inclusions[inclusion.DependentTable+"."+inclusion.DependentColumn+" <= "+inclusion.ReferencedTable+"."+inclusion.ReferencedColumn] = true
+		}
+		return inclusions
+	}
+	result.removedInclusions, result.newInclusions = Differences(inclusions(baseline), inclusions(current))
+	return result
+}
+
+func (this *comparison) Print() {
+	for _, list := range []struct {
+		label string
+		items []string
+	}{
+		{"new table", this.newTables}, {"removed table", this.removedTables},
+		{"new column", this.newColumns}, {"removed column", this.removedColumns},
+		{"new inclusion", this.newInclusions}, {"removed inclusion", this.removedInclusions},
+	} {
+		for _, item := range list.items {
+			fmt.Println(list.label, item)
+		}
+	}
+	drifted := 0
+	w := NewOutput(terminalOutput)
+	for _, column := range this.columns {
+		if len(column.flagged) == 0 {
+			continue
+		}
+		if drifted == 0 {
+			fmt.Fprintln(w, "column\t"+strings.Join(driftMetrics, "\t")+"\tdrifted")
+		}
+		drifted++
+		fmt.Fprint(w, Colorize(column.name, cyan))
+		for _, metric := range driftMetrics {
+			fmt.Fprintf(w, "\t%.3f", column.scores[metric])
+		}
+		fmt.Fprintln(w, "\t"+Colorize(strings.Join(column.flagged, ", "), yellow))
+	}
+	w.Flush()
+	fmt.Printf("%v new and %v removed tables, %v new and %v removed columns, %v new and %v removed inclusions, %v of %v columns drifted\n",
+		len(this.newTables), len(this.removedTables), len(this.newColumns), len(this.removedColumns),
+		len(this.newInclusions), len(this.removedInclusions), drifted, len(this.columns))
+	status.Result("new tables", len(this.newTables))
+	status.Result("removed tables", len(this.removedTables))
+	status.Result("new columns", len(this.newColumns))
+	status.Result("removed columns", len(this.removedColumns))
+	status.Result("new inclusions", len(this.newInclusions))
+	status.Result("removed inclusions", len(this.removedInclusions))
+	status.Result("drifted columns", drifted)
+}
+
+// RunCompare reports the changes from the first document to the second.
+func RunCompare(options *Options) {
+	if len(options.arguments) != 2 {
+		panic("provide two documents written by -output json, the older one first")
+	}
+	baseline, current := ReadResultDocument(options.arguments[0]), ReadResultDocument(options.arguments[1])
+	result := CompareDocuments(baseline, current, ParseDriftThresholds(options.driftThresholds))
+	result.Print()
+}
@@ -0,0 +1,138 @@
+package profiling
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func CommandNames() (result []string) {
+	for _, command := range commands {
+		result = append(result, command.name)
+	}
+	return result
+}
+
+func CommandFlags(command *Command) (result []*flag.Flag) {
+	flags := command.NewFlagSet(new(Options), flag.ContinueOnError)
+	flags.VisitAll(func(f *flag.Flag) {
+		result = append(result, f)
+	})
+	return result
+}
+
+func IsBoolFlag(f *flag.Flag) bool {
+	value, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && value.IsBoolFlag()
+}
+
+// RunHelp prints the usage of the named command to stdout.
+func RunHelp(options *Options) {
+	command := commands[0]
+	if len(options.arguments) > 0 {
+		command, _ = FindCommand(options.arguments)
+		if command.name != options.arguments[0] {
+			fmt.Fprintln(os.Stderr, "unknown command", options.arguments[0])
+			command.PrintUsage(command.NewFlagSet(new(Options), flag.ExitOnError))
+			os.Exit(2)
+		}
+	}
+	flags := command.NewFlagSet(new(Options), flag.ContinueOnError)
+	flags.SetOutput(os.Stdout)
+	command.PrintUsage(flags)
+}
+
+func RunCompletion(options *Options) {
+	if len(options.arguments) != 1 {
+		panic("provide a shell: bash, zsh or fish")
+	}
+	switch options.arguments[0] {
+	case "bash":
+		fmt.Print(BashCompletion())
+	case "zsh":
+		fmt.Print("autoload -U +X bashcompinit && bashcompinit\n" + BashCompletion())
+	case "fish":
+		fmt.Print(FishCompletion())
+	default:
+		panic("unknown shell " + options.arguments[0])
+	}
+}
+
+// PositionalCompletion returns the compgen arguments completing a command's
+// positional argument.
+func PositionalCompletion(command *Command) string {
+	if strings.HasPrefix(command.usage, "<data-dir>") {
+		return "-d"
+	}
+	switch command.usage {
+	case "<path>":
+		return "-f"
+	case "":
+		return "-W \"\""
+	case "[command]":
+		return fmt.Sprintf("-W \"%v\"", strings.Join(CommandNames(), " "))
+	}
+	return fmt.Sprintf("-W \"%v\"", strings.ReplaceAll(command.usage, "|", " "))
+}
+
+func BashCompletion() string {
+	var script strings.Builder
+	script.WriteString("_dataprofiling() {\n")
+	script.WriteString("\tlocal cur=${COMP_WORDS[COMP_CWORD]} command=discover flags positional\n")
+	script.WriteString("\tif [ $COMP_CWORD -gt 1 ]; then command=${COMP_WORDS[1]}; fi\n")
+	script.WriteString("\tif [ $COMP_CWORD -eq 1 ] && [[ $cur != -* ]]; then\n")
+	fmt.Fprintf(&script, "\t\tCOMPREPLY=($(compgen -W \"%v\" -- \"$cur\") $(compgen -d -- \"$cur\"))\n", strings.Join(CommandNames(), " "))
+	script.WriteString("\t\treturn\n\tfi\n")
+	script.WriteString("\tcase $command in\n")
+	// anything that is not a command name is a data directory for discover
+	for i := range commands {
+		command := commands[(i+1)%len(commands)]
+		pattern := command.name
+		if command == commands[0] {
+			pattern = "*"
+		}
+		var names []string
+		for _, f := range CommandFlags(command) {
+			names = append(names, "-"+f.Name)
+		}
+		fmt.Fprintf(&script, "\t%v) flags=\"%v\" positional='%v' ;;\n", pattern, strings.Join(names, " "), PositionalCompletion(command))
+	}
+	script.WriteString("\tesac\n")
+	script.WriteString("\tif [[ $cur == -* ]]; then\n")
+	script.WriteString("\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	script.WriteString("\telse\n")
+	script.WriteString("\t\tCOMPREPLY=($(eval compgen $positional -- '\"$cur\"'))\n")
+	script.WriteString("\tfi\n}\n")
+	script.WriteString("complete -o filenames -F _dataprofiling dataprofiling\n")
+	return script.String()
+}
+
+func FishCompletion() string {
+	var script strings.Builder
+	names := strings.Join(CommandNames()[1:], " ")
+	script.WriteString("complete -c dataprofiling -f\n")
+	script.WriteString("complete -c dataprofiling -n '__fish_is_first_arg' -a '(__fish_complete_directories)'\n")
+	for _, command := range commands {
+		fmt.Fprintf(&script, "complete -c dataprofiling -n '__fish_use_subcommand' -a %v -d '%v'\n", command.name, command.description)
+		condition := "__fish_seen_subcommand_from " + command.name
+		if command == commands[0] {
+			condition = "not __fish_seen_subcommand_from " + names
+		}
+		for _, f := range CommandFlags(command) {
+			requiresValue := " -r"
+			if IsBoolFlag(f) {
+				requiresValue = ""
+			}
+			fmt.Fprintf(&script, "complete -c dataprofiling -n '%v' -o %v%v -d '%v'\n", condition, f.Name, requiresValue, strings.ReplaceAll(f.Usage, "'", "\\'"))
+		}
+		if strings.HasPrefix(command.usage, "<data-dir>") {
+			fmt.Fprintf(&script, "complete -c dataprofiling -n '__fish_seen_subcommand_from %v' -a '(__fish_complete_directories)'\n", command.name)
+		} else if command.usage == "<path>" {
+			fmt.Fprintf(&script, "complete -c dataprofiling -n '__fish_seen_subcommand_from %v' -F\n", command.name)
+		}
+	}
+	script.WriteString("complete -c dataprofiling -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'\n")
+	fmt.Fprintf(&script, "complete -c dataprofiling -n '__fish_seen_subcommand_from help' -a '%v'\n", strings.Join(CommandNames(), " "))
+	return script.String()
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package profiling
+
+import (
+	"time"
+)
+
+// CPUTime is not available on this platform.
+func CPUTime() time.Duration {
+	return 0
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package profiling
+
+import (
+	"syscall"
+	"time"
+)
+
+// CPUTime returns the user and system time consumed by the process so far.
+func CPUTime() time.Duration {
+	var usage syscall.Rusage
+	if syscall.Getrusage(syscall.RUSAGE_SELF, &usage) != nil {
+		return 0
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
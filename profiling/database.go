@@ -0,0 +1,271 @@
+package profiling
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The database command profiles the tables of a relational database: it
+// exports them through database/sql to tab separated files in the data
+// directory, writes their mapping.tsv and a catalog of their declared types
+// and keys, and discovers the inclusions between them like for any other
+// data directory. The drivers are only compiled into builds with the tag of
+// the same name, e.g. go build -tags postgres,mysql.
+
+func DatabaseFlags(options *Options, flags *flag.FlagSet) {
+	flags.StringVar(&options.driver, "driver", "postgres", "database/sql driver of -dsn: postgres or mysql, in builds with the tag of the same name, or duckdb")
+	flags.StringVar(&options.dsn, "dsn", "", "connection string of the database to profile, e.g. postgres://user@localhost/shop?sslmode=disable")
+	flags.StringVar(&options.schemas, "schemas", "", "comma separated schemas whose tables are profiled (default all but the system schemas)")
+	flags.StringVar(&options.tables, "tables", "", "comma separated patterns like orders or sales.* naming the tables to profile (default all)")
+}
+
+// schemas of the databases' own tables
+var systemSchemas = map[string]bool{"information_schema": true, "pg_catalog": true, "pg_toast": true, "mysql": true, "performance_schema": true, "sys": true}
+
+type sourceTable struct {
+	schema  string
+	name    string
+	columns []string
+	// declared types of the columns, as SQL types
+	types []string
+}
+
+func (this *sourceTable) QualifiedName() string {
+	return this.schema + "." + this.name
+}
+
+func OpenDatabase(driver string, dsn string) *sql.DB {
+	found := false
+	for _, name := range sql.Drivers() {
+		found = found || name == driver
+	}
+	if !found {
+		panic(fmt.Sprintf("this build has no %v driver, rebuild with -tags %v", driver, driver))
+	}
+	connection, err := sql.Open(driver, dsn)
+	check(err)
+	check(connection.Ping())
+	return connection
+}
+
+// QuoteSQLIdentifier quotes a schema, table or column name for the driver.
+func QuoteSQLIdentifier(driver string, name string) string {
+	if driver == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// FormatSQLValue renders a scanned value as text, with NULL as the empty
+// value and timestamps at midnight as dates.
+func FormatSQLValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(value)
+	case time.Time:
+		if value.Equal(value.Truncate(24 * time.Hour)) {
+			return value.Format("2006-01-02")
+		}
+		return value.Format("2006-01-02 15:04:05.999999999")
+	}
+	return fmt.Sprint(value)
+}
+
+// QueryStrings runs a query and passes each row's values as strings.
+func QueryStrings(connection *sql.DB, query string, visit func(values []string)) error {
+	rows, err := connection.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	scanned := make([]interface{}, len(columns))
+	targets := make([]interface{}, len(columns))
+	for i := range scanned {
+		targets[i] = &scanned[i]
+	}
+	values := make([]string, len(columns))
+	for rows.Next() {
+		if err = rows.Scan(targets...); err != nil {
+			return err
+		}
+		for i, value := range scanned {
+			values[i] = FormatSQLValue(value)
+		}
+		visit(values)
+	}
+	return rows.Err()
+}
+
+// MatchesTable reports whether a table is selected by -schemas and -tables.
+// Patterns without a schema match the tables of every schema.
+func (this *Options) MatchesTable(schema string, name string) bool {
+	if this.schemas == "" && systemSchemas[strings.ToLower(schema)] {
+		return false
+	}
+	if this.schemas != "" && !ContainsString(strings.Split(this.schemas, ","), schema) {
+		return false
+	}
+	if this.tables == "" {
+		return true
+	}
+	for _, pattern := range strings.Split(this.tables, ",") {
+		subject := name
+		if strings.Contains(pattern, ".") {
+			subject = schema + "." + name
+		}
+		if matched, err := filepath.Match(pattern, subject); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func ContainsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ListTables reads the selected base tables and their columns from
+// information_schema.
+func ListTables(connection *sql.DB, options *Options) (tables []*sourceTable) {
+	byName := make(map[string]*sourceTable)
+	err := QueryStrings(connection, `SELECT c.table_schema, c.table_name, c.column_name, c.data_type, c.character_maximum_length
+		FROM information_schema.columns c JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE t.table_type = 'BASE TABLE' ORDER BY c.table_schema, c.table_name, c.ordinal_position`, func(values []string) {
+		if !options.MatchesTable(values[0], values[1]) {
+			return
+		}
+		table := byName[values[0]+"."+values[1]]
+		if table == nil {
+			table = &sourceTable{schema: values[0], name: values[1]}
+			byName[table.QualifiedName()] = table
+			tables = append(tables, table)
+		}
+		declared := values[3]
+		if values[4] != "" {
+			declared += "(" + values[4] + ")"
+		}
+		table.columns = append(table.columns, values[2])
+		table.types = append(table.types, declared)
+	})
+	check(err)
+	return tables
+}
+
+// ExportTable writes the table's rows to a tab separated file with a header
+// row and returns its mapping.tsv line.
+func ExportTable(connection *sql.DB, driver string, table *sourceTable, dataDir string) (mapping []string) {
+	var columns []string
+	for _, column := range table.columns {
+		columns = append(columns, QuoteSQLIdentifier(driver, column))
+	}
+	// table ids are taken from the file name up to the first dot
+	fileName := strings.NewReplacer(".", "_", "/", "_").Replace(table.QualifiedName()) + ".tsv"
+	file, err := os.Create(dataDir + fileName)
+	check(err)
+	defer file.Close()
+	names := make([]string, len(table.columns))
+	for i, column := range table.columns {
+		names[i] = SanitizeValue(column)
+	}
+	fmt.Fprintln(file, strings.Join(names, "\t"))
+	rows := 0
+	check(QueryStrings(connection, fmt.Sprintf("SELECT %v FROM %v.%v", strings.Join(columns, ", "), QuoteSQLIdentifier(driver, table.schema), QuoteSQLIdentifier(driver, table.name)), func(values []string) {
+		for i, value := range values {
+			values[i] = SanitizeValue(value)
+		}
+		fmt.Fprintln(file, strings.Join(values, "\t"))
+		rows++
+	}))
+	logger.Infof("exported %v rows of %v", rows, table.QualifiedName())
+	return append([]string{table.QualifiedName(), fileName}, names...)
+}
+
+// WriteDatabaseCatalog writes the declared types, primary keys and foreign
+// keys of the exported tables as a catalog file. Keys are left out if the
+// database does not describe them in information_schema like PostgreSQL or
+// MySQL.
+func WriteDatabaseCatalog(connection *sql.DB, driver string, tables []*sourceTable, fileName string) {
+	exported := make(map[string]bool)
+	var lines []string
+	for _, table := range tables {
+		exported[table.QualifiedName()] = true
+		for i, column := range table.columns {
+			if ParseDeclaredType(table.types[i]) != nil {
+				lines = append(lines, strings.Join([]string{"type", table.QualifiedName(), SanitizeValue(column), table.types[i]}, "\t"))
+			}
+		}
+	}
+	keys := func(kind string, query string) {
+		err := QueryStrings(connection, query, func(values []string) {
+			if !exported[values[0]+"."+values[1]] || len(values) > 3 && !exported[values[3]+"."+values[4]] {
+				return
+			}
+			line := []string{kind, values[0] + "." + values[1], SanitizeValue(values[2])}
+			if len(values) > 3 {
+				line = append(line, values[3]+"."+values[4], SanitizeValue(values[5]))
+			}
+			lines = append(lines, strings.Join(line, "\t"))
+		})
+		if err != nil {
+			logger.Infof("skipping the %vs of the catalog: %v", kind, err)
+		}
+	}
+	keys("primary key", `SELECT k.table_schema, k.table_name, k.column_name FROM information_schema.table_constraints c
+		JOIN information_schema.key_column_usage k ON k.constraint_schema = c.constraint_schema AND k.constraint_name = c.constraint_name AND k.table_name = c.table_name
+		WHERE c.constraint_type = 'PRIMARY KEY'`)
+	if driver == "mysql" {
+		keys("foreign key", `SELECT table_schema, table_name, column_name, referenced_table_schema, referenced_table_name, referenced_column_name
+			FROM information_schema.key_column_usage WHERE referenced_table_name IS NOT NULL`)
+	} else {
+		keys("foreign key", `SELECT k.table_schema, k.table_name, k.column_name, r.table_schema, r.table_name, r.column_name FROM information_schema.referential_constraints c
+			JOIN information_schema.key_column_usage k ON k.constraint_schema = c.constraint_schema AND k.constraint_name = c.constraint_name
+			JOIN information_schema.key_column_usage r ON r.constraint_schema = c.unique_constraint_schema AND r.constraint_name = c.unique_constraint_name
+				AND r.ordinal_position = k.position_in_unique_constraint`)
+	}
+	sort.Strings(lines)
+	check(os.WriteFile(fileName, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+}
+
+// RunDatabase exports the selected tables into the data directory and
+// searches them for inclusions, checking the declared keys as well unless
+// another -catalog is given.
+func RunDatabase(options *Options) {
+	if options.dsn == "" {
+		panic("provide the database to profile with -dsn")
+	}
+	connection := OpenDatabase(options.driver, options.dsn)
+	defer connection.Close()
+	tables := ListTables(connection, options)
+	if len(tables) == 0 {
+		panic("no tables match -schemas and -tables")
+	}
+	check(os.MkdirAll(options.dataDir, 0755))
+	var mapping []string
+	for _, table := range tables {
+		mapping = append(mapping, strings.Join(ExportTable(connection, options.driver, table, options.dataDir), "\t"))
+	}
+	check(os.WriteFile(options.dataDir+"mapping.tsv", []byte(strings.Join(mapping, "\n")+"\n"), 0644))
+	if options.catalogFile == "" {
+		options.catalogFile = options.dataDir + "catalog.tsv"
+		WriteDatabaseCatalog(connection, options.driver, tables, options.catalogFile)
+	}
+	options.header = true
+	RunDiscover(options)
+}
@@ -0,0 +1,303 @@
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// share of non-empty distinct values that must parse with one layout for a
+// column to be profiled as dates
+const dateThreshold = 0.9
+
+// number of gaps listed in reports
+const reportedGaps = 5
+
+// dateLayouts are tried in order, the first parsing the first value wins.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006-01",
+	"02.01.2006",
+	"01/02/2006",
+	"20060102",
+}
+
+// DateProfile describes the continuity of the dates in a column: the step
+// between most consecutive dates and where steps are missing.
+type DateProfile struct {
+	layout       string
+	first, last  time.Time
+	granularity  string
+	missingSteps int
+	// consecutive dates with missing steps between them
+	gaps    [][2]time.Time
+	invalid int
+}
+
+var granularities = []struct {
+	name string
+	step time.Duration
+}{
+	{"second", time.Second},
+	{"minute", time.Minute},
+	{"hour", time.Hour},
+	{"day", 24 * time.Hour},
+	{"week", 7 * 24 * time.Hour},
+}
+
+// Missing counts the steps of the granularity after a and before b. Months
+// and years are calendar steps.
+func (this *DateProfile) Missing(a time.Time, b time.Time) (missing int) {
+	for _, granularity := range granularities {
+		if granularity.name == this.granularity {
+			return int((b.Sub(a) - 1) / granularity.step)
+		}
+	}
+	months := 1
+	if this.granularity == "year" {
+		months = 12
+	}
+	for next := a.AddDate(0, months, 0); next.Before(b); next = next.AddDate(0, months, 0) {
+		missing++
+	}
+	return missing
+}
+
+// Step returns the date n steps of the granularity after t.
+func (this *DateProfile) Step(t time.Time, n int) time.Time {
+	for _, granularity := range granularities {
+		if granularity.name == this.granularity {
+			return t.Add(time.Duration(n) * granularity.step)
+		}
+	}
+	if this.granularity == "year" {
+		return t.AddDate(n, 0, 0)
+	}
+	return t.AddDate(0, n, 0)
+}
+
+// Granularity returns the step between most consecutive dates, preferring
+// calendar months and years if all dates start one.
+func Granularity(dates []time.Time) string {
+	startsMonth, startsYear := true, true
+	for _, date := range dates {
+		if date.Day() != 1 || date.Hour() != 0 || date.Minute() != 0 || date.Second() != 0 || date.Nanosecond() != 0 {
+			startsMonth, startsYear = false, false
+		} else if date.Month() != time.January {
+			startsYear = false
+		}
+	}
+	if startsYear {
+		return "year"
+	}
+	if startsMonth {
+		return "month"
+	}
+	counts := make(map[string]int)
+	for i := 1; i < len(dates); i++ {
+		difference := dates[i].Sub(dates[i-1])
+		for j := len(granularities) - 1; j >= 0; j-- {
+			if difference%granularities[j].step == 0 {
+				counts[granularities[j].name]++
+				break
+			}
+		}
+	}
+	best := ""
+	for _, granularity := range granularities {
+		if counts[granularity.name] > counts[best] {
+			best = granularity.name
+		}
+	}
+	return best
+}
+
+// DetectDates profiles the continuity of columns whose values are dates.
+func (this *Column) DetectDates() {
+	if this.dataType != "string" && this.dataType != "int" && this.dataType != "date" {
+		return
+	}
+	var values []string
+	this.EachValue(func(value string) {
+		if value != "" {
+			values = append(values, value)
+		}
+	})
+	if len(values) < 2 {
+		return
+	}
+	sort.Strings(values)
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, values[0]); err != nil {
+			continue
+		}
+		profile := &DateProfile{layout: layout}
+		var dates []time.Time
+		for _, value := range values {
+			if date, err := time.Parse(layout, value); err == nil {
+				dates = append(dates, date)
+			} else {
+				profile.invalid++
+			}
+		}
+		if float64(len(dates))/float64(len(values)) < dateThreshold || len(dates) < 2 {
+			return
+		}
+		sort.Slice(dates, func(i, j int) bool {
+			return dates[i].Before(dates[j])
+		})
+		profile.first, profile.last = dates[0], dates[len(dates)-1]
+		profile.granularity = Granularity(dates)
+		if profile.granularity == "" {
+			return
+		}
+		for i := 1; i < len(dates); i++ {
+			if missing := profile.Missing(dates[i-1], dates[i]); missing > 0 {
+				profile.missingSteps += missing
+				profile.gaps = append(profile.gaps, [2]time.Time{dates[i-1], dates[i]})
+			}
+		}
+		this.dates = profile
+		return
+	}
+}
+
+func (this *DateProfile) Format(t time.Time) string {
+	return t.Format(this.layout)
+}
+
+func (this *DateProfile) String() string {
+	result := fmt.Sprintf("%v..%v, by %v, %v missing", this.Format(this.first), this.Format(this.last), this.granularity, this.missingSteps)
+	if len(this.gaps) > 0 {
+		var gaps []string
+		for i, gap := range this.gaps {
+			if i == reportedGaps {
+				gaps = append(gaps, "...")
+				break
+			}
+			gaps = append(gaps, this.Format(gap[0])+".."+this.Format(gap[1]))
+		}
+		result += " in " + strings.Join(gaps, ", ")
+	}
+	return result
+}
+
+// Document describes the profile for JSON exports.
+func (this *DateProfile) Document() map[string]interface{} {
+	gaps := [][2]string{}
+	for _, gap := range this.gaps {
+		gaps = append(gaps, [2]string{this.Format(gap[0]), this.Format(gap[1])})
+	}
+	return map[string]interface{}{"first": this.Format(this.first), "last": this.Format(this.last), "granularity": this.granularity,
+		"missing": this.missingSteps, "gaps": gaps, "invalid": this.invalid}
+}
+
+func (db Database) DetectDates() {
+	for _, column := range db.AllColumns() {
+		column.DetectDates()
+	}
+}
+
+// ParseDate parses a value with the first of the dateLayouts that fits,
+// returning its index, or -1 if none does.
+func ParseDate(value string) (date time.Time, layout int) {
+	for i, candidate := range dateLayouts {
+		if date, err := time.Parse(candidate, value); err == nil {
+			return date, i
+		}
+	}
+	return time.Time{}, -1
+}
+
+// dateStatistics compares the values of date columns as points in time. A
+// value is always parsed with the same layout, so the layouts seen in a
+// column must be among those of any column including it.
+type dateStatistics struct {
+	statistics
+	maximum time.Time
+	minimum time.Time
+	// bit set of the indices of the dateLayouts seen
+	layouts uint16
+	// sketch of the seconds since 1970
+	digest tDigest
+}
+
+// Layout returns the most specific layout seen, used to write dates.
+func (this *dateStatistics) Layout() string {
+	for i, layout := range dateLayouts {
+		if this.layouts&(1<<i) != 0 {
+			return layout
+		}
+	}
+	return dateLayouts[len(dateLayouts)-1]
+}
+
+func (this *dateStatistics) Format(t time.Time) string {
+	if this.layouts == 0 {
+		return ""
+	}
+	return t.Format(this.Layout())
+}
+
+func (this *dateStatistics) Print(w io.Writer) {
+	fmt.Fprint(w, "max: ", Redact(this.Format(this.maximum)), " \t| min: ", Redact(this.Format(this.minimum)), " \t| dis: ~", this.EstimatedDistinct())
+	this.Histogram().Print(w)
+}
+
+func (this *dateStatistics) Add(s string) {
+	this.Sample(s)
+	this.distinct.Add(s)
+	date, layout := ParseDate(s)
+	if layout < 0 {
+		return
+	}
+	if this.layouts == 0 || date.Before(this.minimum) {
+		this.minimum = date
+	}
+	if this.layouts == 0 || date.After(this.maximum) {
+		this.maximum = date
+	}
+	this.layouts |= 1 << layout
+	this.digest.Add(float64(date.Unix()))
+}
+
+func (this *dateStatistics) FinishAnalysis(rowCount int) {
+}
+
+func (this *dateStatistics) ResumeAnalysis(rowCount int) {
+}
+
+func (this *dateStatistics) SimiliarTo(s Statistics) bool {
+	other := s.(*dateStatistics)
+	if this.layouts == 0 {
+		return true
+	}
+	return this.layouts&^other.layouts == 0 && !this.minimum.Before(other.minimum) && !this.maximum.After(other.maximum) &&
+		this.distinct.MayBeIncludedIn(&other.distinct)
+}
+
+// Histogram returns the histogram of the seconds, with the quantiles and
+// bins also written as dates.
+func (this *dateStatistics) Histogram() *Histogram {
+	result := this.digest.Histogram()
+	if result == nil {
+		return nil
+	}
+	date := func(seconds float64) string {
+		return this.Format(time.Unix(int64(math.Round(seconds)), 0).UTC())
+	}
+	for i := range result.Quantiles {
+		result.Quantiles[i].Date = date(result.Quantiles[i].Value)
+	}
+	for i := range result.Bins {
+		result.Bins[i].From, result.Bins[i].To = date(result.Bins[i].Lower), date(result.Bins[i].Upper)
+	}
+	return result
+}
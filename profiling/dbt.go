@@ -0,0 +1,75 @@
+package profiling
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Summary describes a column's type and statistics in one line.
+func (this *Column) Summary() string {
+	var stats bytes.Buffer
+	this.stats.Print(&stats)
+	summary := strings.Join(strings.Fields(strings.ReplaceAll(stats.String(), "\t", "")), " ")
+	summary = fmt.Sprintf("%v, %v distinct values, %v", this.dataType, this.DistinctValues(), summary)
+	if this.comment != "" {
+		summary = this.comment + " (" + summary + ")"
+	}
+	if this.alias != "" {
+		summary = this.alias + ": " + summary
+	}
+	return summary
+}
+
+// ForeignKeys returns the inclusions referencing a unique column, which are
+// the ones worth declaring as foreign keys, keyed by the dependent column.
+func (this *InclusionGraph) ForeignKeys() (result map[*Column][]*Column) {
+	result = make(map[*Column][]*Column)
+	for _, column := range this.nodes {
+		for _, referenced := range this.nodes {
+			if column != referenced && this.adjacencyMatrix[column.index][referenced.index] && referenced.IsUnique() {
+				result[column] = append(result[column], referenced)
+			}
+		}
+	}
+	return result
+}
+
+// ModelName names the table's dbt model, which cannot contain dots.
+func (this *Table) ModelName() string {
+	return strings.ReplaceAll(this.QualifiedName(), ".", "_")
+}
+
+// ExportDbt writes a dbt schema.yml patch describing every column and adding
+// a relationships test for every foreign key like inclusion.
+func (this *InclusionGraph) ExportDbt(w io.Writer) {
+	foreignKeys := this.ForeignKeys()
+	fmt.Fprintln(w, "version: 2")
+	fmt.Fprintln(w, "models:")
+	var table *Table
+	for _, column := range this.nodes {
+		if column.table != table {
+			table = column.table
+			fmt.Fprintln(w, "  - name:", strconv.Quote(table.ModelName()))
+			fmt.Fprintln(w, "    columns:")
+		}
+		fmt.Fprintln(w, "      - name:", strconv.Quote(column.name))
+		fmt.Fprintln(w, "        description:", strconv.Quote(column.Summary()))
+		if column.pii != "" {
+			fmt.Fprintln(w, "        meta:")
+			fmt.Fprintln(w, "          pii:", strconv.Quote(column.pii))
+			fmt.Fprintf(w, "          pii_confidence: %.2f\n", column.piiConfidence)
+		}
+		if len(foreignKeys[column]) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, "        tests:")
+		for _, referenced := range foreignKeys[column] {
+			fmt.Fprintln(w, "          - relationships:")
+			fmt.Fprintf(w, "              to: %v\n", strconv.Quote("ref('"+referenced.table.ModelName()+"')"))
+			fmt.Fprintln(w, "              field:", strconv.Quote(referenced.name))
+		}
+	}
+}
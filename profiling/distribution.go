@@ -0,0 +1,241 @@
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// number of equal width bins of a numeric column's histogram
+const histogramBins = 10
+
+// quantiles reported for numeric columns
+var reportedQuantiles = []float64{0.01, 0.05, 0.25, 0.5, 0.75, 0.95, 0.99}
+
+// tDigest sketches the distribution of a numeric column in a single pass as
+// a merging t-digest: the values are clustered into centroids that are small
+// near the extremes and large around the median, so quantiles are estimated
+// most precisely in the tails.
+type tDigest struct {
+	centroids []centroid
+	// values added since the centroids were last merged
+	buffer  []float64
+	count   float64
+	minimum float64
+	maximum float64
+}
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// compression of the digest, bounding its number of centroids
+const tDigestCompression = 100
+
+func (this *tDigest) Add(value float64) {
+	if this.count == 0 || value < this.minimum {
+		this.minimum = value
+	}
+	if this.count == 0 || value > this.maximum {
+		this.maximum = value
+	}
+	this.count++
+	this.buffer = append(this.buffer, value)
+	if len(this.buffer) >= 5*tDigestCompression {
+		this.Compress()
+	}
+}
+
+// scale maps a quantile to the index of the centroid it belongs to, a
+// centroid may span at most one index
+func tDigestScale(q float64) float64 {
+	return tDigestCompression / (2 * math.Pi) * math.Asin(2*math.Max(0, math.Min(1, q))-1)
+}
+
+// Compress merges the buffered values into the centroids.
+func (this *tDigest) Compress() {
+	if len(this.buffer) == 0 {
+		return
+	}
+	all := append([]centroid(nil), this.centroids...)
+	for _, value := range this.buffer {
+		all = append(all, centroid{value, 1})
+	}
+	this.buffer = this.buffer[:0]
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+	this.centroids = this.centroids[:0]
+	before := 0.0
+	current := all[0]
+	for _, next := range all[1:] {
+		if tDigestScale((before+current.weight+next.weight)/this.count)-tDigestScale(before/this.count) <= 1 {
+			current.mean += (next.mean - current.mean) * next.weight / (current.weight + next.weight)
+			current.weight += next.weight
+		} else {
+			this.centroids = append(this.centroids, current)
+			before += current.weight
+			current = next
+		}
+	}
+	this.centroids = append(this.centroids, current)
+}
+
+// Quantile estimates the value below which the share q of the values lies,
+// interpolating between the centers of the centroids.
+func (this *tDigest) Quantile(q float64) float64 {
+	this.Compress()
+	centroids := this.centroids
+	if len(centroids) == 0 {
+		return math.NaN()
+	}
+	index := q * this.count
+	first, last := centroids[0], centroids[len(centroids)-1]
+	if index <= first.weight/2 {
+		return this.minimum + (first.mean-this.minimum)*index/(first.weight/2)
+	}
+	cumulative := first.weight / 2
+	for i := 0; i+1 < len(centroids); i++ {
+		step := (centroids[i].weight + centroids[i+1].weight) / 2
+		if index <= cumulative+step {
+			return centroids[i].mean + (centroids[i+1].mean-centroids[i].mean)*(index-cumulative)/step
+		}
+		cumulative += step
+	}
+	return last.mean + (this.maximum-last.mean)*math.Min(1, (index-cumulative)/(last.weight/2))
+}
+
+// CDF estimates the share of the values below value.
+func (this *tDigest) CDF(value float64) float64 {
+	this.Compress()
+	centroids := this.centroids
+	if len(centroids) == 0 || value < this.minimum {
+		return 0
+	}
+	if value >= this.maximum {
+		return 1
+	}
+	// interpolates the weight between two points, guarding equal ones
+	between := func(x0 float64, x1 float64, weight float64) float64 {
+		if x1 <= x0 {
+			return weight
+		}
+		return weight * (value - x0) / (x1 - x0)
+	}
+	first, last := centroids[0], centroids[len(centroids)-1]
+	if value < first.mean {
+		return between(this.minimum, first.mean, first.weight/2) / this.count
+	}
+	cumulative := first.weight / 2
+	for i := 0; i+1 < len(centroids); i++ {
+		step := (centroids[i].weight + centroids[i+1].weight) / 2
+		if value < centroids[i+1].mean {
+			return (cumulative + between(centroids[i].mean, centroids[i+1].mean, step)) / this.count
+		}
+		cumulative += step
+	}
+	return (cumulative + between(last.mean, this.maximum, last.weight/2)) / this.count
+}
+
+// Histogram describes the distribution of a numeric column by estimated
+// quantiles and the counts of equal width bins between the minimum and the
+// maximum, which are exact for digests of no more centroids than bins and
+// estimated otherwise, adding up to the number of values either way.
+type Histogram struct {
+	Quantiles []QuantileValue `json:"quantiles"`
+	Bins      []HistogramBin  `json:"bins"`
+}
+
+// Values of date columns are seconds since 1970, and written as dates in the
+// column's layout as well.
+type QuantileValue struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+	Date     string  `json:"date,omitempty"`
+}
+
+type HistogramBin struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"`
+	From  string  `json:"from,omitempty"`
+	To    string  `json:"to,omitempty"`
+}
+
+// Histogram returns the digest's histogram, or nil if it has no values.
+func (this *tDigest) Histogram() *Histogram {
+	if this.count == 0 {
+		return nil
+	}
+	result := &Histogram{Quantiles: []QuantileValue{}, Bins: []HistogramBin{}}
+	for _, q := range reportedQuantiles {
+		result.Quantiles = append(result.Quantiles, QuantileValue{Quantile: q, Value: this.Quantile(q)})
+	}
+	width := (this.maximum - this.minimum) / histogramBins
+	if width == 0 {
+		return &Histogram{result.Quantiles, []HistogramBin{{Lower: this.minimum, Upper: this.maximum, Count: int(this.count)}}}
+	}
+	for i := 0; i < histogramBins; i++ {
+		result.Bins = append(result.Bins, HistogramBin{Lower: this.minimum + float64(i)*width, Upper: this.minimum + float64(i+1)*width})
+	}
+	if len(this.centroids) <= histogramBins {
+		for _, centroid := range this.centroids {
+			bin := int((centroid.mean - this.minimum) / width)
+			if bin >= histogramBins {
+				bin = histogramBins - 1
+			}
+			result.Bins[bin].Count += int(centroid.weight)
+		}
+		return result
+	}
+	// rounding the cumulative counts rather than each bin's keeps the total
+	below := 0
+	for i := range result.Bins {
+		cumulative := int(this.count)
+		if i < histogramBins-1 {
+			cumulative = int(math.Round(this.CDF(result.Bins[i].Upper) * this.count))
+		}
+		result.Bins[i].Count = cumulative - below
+		below = cumulative
+	}
+	return result
+}
+
+// Print appends the quartiles and the sparkline of the histogram to a line
+// of statistics. Quantiles are values of the column just like the minimum
+// and maximum, so they are left out when redacting.
+func (this *Histogram) Print(w io.Writer) {
+	if this != nil && redaction == "" {
+		fmt.Fprintf(w, " \t| p25/p50/p75: %v \t| hist: %v", strings.Join(this.Quartiles(), "/"), this.Sparkline())
+	}
+	fmt.Fprintln(w)
+}
+
+// Quartiles returns the 25th, 50th and 75th percentile as text.
+func (this *Histogram) Quartiles() (quartiles []string) {
+	for _, quantile := range this.Quantiles[2:5] {
+		if quantile.Date != "" {
+			quartiles = append(quartiles, quantile.Date)
+		} else {
+			quartiles = append(quartiles, fmt.Sprintf("%.6g", quantile.Value))
+		}
+	}
+	return quartiles
+}
+
+// Sparkline draws the bin counts as bars of eight heights.
+func (this *Histogram) Sparkline() string {
+	bars := []rune("▁▂▃▄▅▆▇█")
+	largest := 0
+	for _, bin := range this.Bins {
+		if bin.Count > largest {
+			largest = bin.Count
+		}
+	}
+	var line strings.Builder
+	for _, bin := range this.Bins {
+		line.WriteRune(bars[(len(bars)-1)*bin.Count/int(math.Max(1, float64(largest)))])
+	}
+	return line.String()
+}
@@ -0,0 +1,63 @@
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ExportDot writes the inclusions as a Graphviz graph: columns are nodes
+// clustered by table, edges point from the dependent to the referenced
+// column. Only columns taking part in an inclusion are drawn.
+func (this *InclusionGraph) ExportDot(w io.Writer) {
+	included := make(map[*Column]bool)
+	for _, column := range this.nodes {
+		for _, referenced := range this.nodes {
+			if column != referenced && this.adjacencyMatrix[column.index][referenced.index] {
+				included[column], included[referenced] = true, true
+			}
+		}
+	}
+	fmt.Fprintln(w, "digraph inclusions {")
+	fmt.Fprintln(w, "\trankdir=LR;")
+	fmt.Fprintln(w, "\tnode [shape=box];")
+	for i, table := range this.Tables() {
+		var columns []*Column
+		for _, column := range table.columns {
+			if included[column] {
+				columns = append(columns, column)
+			}
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\tsubgraph cluster_%v {\n", i)
+		fmt.Fprintf(w, "\t\tlabel=%v;\n", strconv.Quote(table.QualifiedName()))
+		for _, column := range columns {
+			label := column.name
+			if column.alias != "" {
+				label = column.alias
+			}
+			fmt.Fprintf(w, "\t\t%v [label=%v];\n", strconv.Quote(column.String()), strconv.Quote(label))
+		}
+		fmt.Fprintln(w, "\t}")
+	}
+	for _, column := range this.nodes {
+		for _, referenced := range this.nodes {
+			if column != referenced && this.adjacencyMatrix[column.index][referenced.index] {
+				fmt.Fprintf(w, "\t%v -> %v;\n", strconv.Quote(column.String()), strconv.Quote(referenced.String()))
+			}
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// Tables returns the tables of the graph's columns in order.
+func (this *InclusionGraph) Tables() (tables []*Table) {
+	for i, column := range this.nodes {
+		if i == 0 || column.table != this.nodes[i-1].table {
+			tables = append(tables, column.table)
+		}
+	}
+	return tables
+}
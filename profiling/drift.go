@@ -0,0 +1,187 @@
+package profiling
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// driftMetrics are compared with the -drift-thresholds of the same name.
+var driftMetrics = []string{"range", "cardinality", "nulls", "distribution"}
+
+// Drift scores how much a column changed since a baseline profile:
+//
+//	range         shift of the minimum or maximum relative to the baseline's
+//	              range, for strings the change of the average length
+//	cardinality   relative change of the number of distinct values
+//	nulls         absolute change of the share of empty values
+//	distribution  Kolmogorov-Smirnov distance of the distinct numbers, or
+//	              Jaccard distance of the distinct strings
+type Drift struct {
+	column      *Column
+	scores      map[string]float64
+	typeChanged bool
+	// metrics exceeding their thresholds
+	flagged []string
+}
+
+// ParseDriftThresholds reads comma separated metric=threshold pairs, keeping
+// the defaults of metrics not mentioned.
+func ParseDriftThresholds(text string) (thresholds map[string]float64) {
+	thresholds = map[string]float64{"range": 0.1, "cardinality": 0.2, "nulls": 0.05, "distribution": 0.3}
+	if text == "" {
+		return thresholds
+	}
+	for _, pair := range strings.Split(text, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if _, ok := thresholds[parts[0]]; !ok || len(parts) != 2 {
+			panic("drift thresholds need metric=threshold pairs of " + strings.Join(driftMetrics, ", ") + ", got " + pair)
+		}
+		threshold, err := strconv.ParseFloat(parts[1], 64)
+		check(err)
+		thresholds[parts[0]] = threshold
+	}
+	return thresholds
+}
+
+func RelativeChange(baseline float64, current float64) float64 {
+	return math.Abs(current-baseline) / math.Max(math.Abs(baseline), 1)
+}
+
+// KolmogorovSmirnov returns the largest difference between the empirical
+// distribution functions of two sorted samples.
+func KolmogorovSmirnov(a []float64, b []float64) (distance float64) {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		x := math.Min(a[i], b[j])
+		for i < len(a) && a[i] <= x {
+			i++
+		}
+		for j < len(b) && b[j] <= x {
+			j++
+		}
+		distance = math.Max(distance, math.Abs(float64(i)/float64(len(a))-float64(j)/float64(len(b))))
+	}
+	return distance
+}
+
+func JaccardDistance(a map[string]bool, b map[string]bool) float64 {
+	shared := 0
+	for value := range a {
+		if b[value] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return 1 - float64(shared)/float64(union)
+}
+
+func SortedNumbers(values map[string]bool) (numbers []float64) {
+	for value := range values {
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Float64s(numbers)
+	return numbers
+}
+
+// CompareProfile scores the column's drift from its baseline profile.
+func (this *Column) CompareProfile(baseline columnProfile, baselineRows int, thresholds map[string]float64) *Drift {
+	drift := &Drift{column: this, scores: make(map[string]float64), typeChanged: baseline.DataType != this.dataType}
+	values := make(map[string]bool)
+	for _, value := range baseline.Values {
+		values[value] = true
+	}
+	switch current := this.stats.(type) {
+	case *intStatistics:
+		if stats, ok := baseline.Stats.(*intStatistics); ok {
+			span := math.Max(float64(stats.maximum-stats.minimum), 1)
+			drift.scores["range"] = math.Max(math.Abs(float64(current.minimum-stats.minimum)), math.Abs(float64(current.maximum-stats.maximum))) / span
+		}
+	case *floatStatistics:
+		if stats, ok := baseline.Stats.(*floatStatistics); ok {
+			span := stats.maximum - stats.minimum
+			if span == 0 {
+				span = 1
+			}
+			drift.scores["range"] = math.Max(math.Abs(current.minimum-stats.minimum), math.Abs(current.maximum-stats.maximum)) / span
+		}
+	case *dateStatistics:
+		if stats, ok := baseline.Stats.(*dateStatistics); ok {
+			span := math.Max(stats.maximum.Sub(stats.minimum).Seconds(), 1)
+			drift.scores["range"] = math.Max(math.Abs(current.minimum.Sub(stats.minimum).Seconds()), math.Abs(current.maximum.Sub(stats.maximum).Seconds())) / span
+		}
+	case *stringStatistics:
+		if stats, ok := baseline.Stats.(*stringStatistics); ok {
+			drift.scores["range"] = RelativeChange(stats.averageLength, current.averageLength)
+		}
+	}
+	drift.scores["cardinality"] = RelativeChange(float64(len(values)), float64(this.DistinctValues()))
+	if baselineRows > 0 && this.table.rowCount > 0 {
+		drift.scores["nulls"] = math.Abs(float64(this.nulls)/float64(this.table.rowCount) - float64(baseline.Nulls)/float64(baselineRows))
+	}
+	if this.dataType == "int" || this.dataType == "float" {
+		drift.scores["distribution"] = KolmogorovSmirnov(SortedNumbers(values), SortedNumbers(this.values))
+	} else {
+		drift.scores["distribution"] = JaccardDistance(values, this.values)
+	}
+	for _, metric := range driftMetrics {
+		if drift.scores[metric] > thresholds[metric] {
+			drift.flagged = append(drift.flagged, metric)
+		}
+	}
+	if drift.typeChanged {
+		drift.flagged = append(drift.flagged, "type")
+	}
+	return drift
+}
+
+// CompareProfiles scores the drift of every column found in the profiles of
+// the baseline directory, which a run with -checkpoint-dir wrote.
+func (db Database) CompareProfiles(baselineDir string, thresholds map[string]float64) (drifts []*Drift) {
+	for _, table := range db {
+		var profile tableProfile
+		if !ReadGob(table.ProfileFileName(baselineDir), &profile) {
+			fmt.Fprintln(os.Stderr, "no baseline profile of", table.QualifiedName())
+			continue
+		}
+		baselines := make(map[string]columnProfile)
+		for _, column := range profile.Columns {
+			baselines[column.Name] = column
+		}
+		for _, column := range table.columns {
+			if baseline, ok := baselines[column.name]; ok {
+				drifts = append(drifts, column.CompareProfile(baseline, profile.RowCount, thresholds))
+			}
+		}
+	}
+	return drifts
+}
+
+func (db Database) PrintDrift(options *Options) {
+	drifts := db.CompareProfiles(options.baselineDir, ParseDriftThresholds(options.driftThresholds))
+	w := NewOutput(terminalOutput)
+	fmt.Fprintln(w, "column\t"+strings.Join(driftMetrics, "\t")+"\tdrifted")
+	drifted := 0
+	for _, drift := range drifts {
+		fmt.Fprint(w, Colorize(drift.column.Label(), cyan))
+		for _, metric := range driftMetrics {
+			fmt.Fprintf(w, "\t%.3f", drift.scores[metric])
+		}
+		if len(drift.flagged) > 0 {
+			drifted++
+			fmt.Fprint(w, "\t"+Colorize(strings.Join(drift.flagged, ", "), yellow))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	fmt.Println(drifted, "of", len(drifts), "columns drifted from the baseline")
+	status.Result("drifted columns", drifted)
+}
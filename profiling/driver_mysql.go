@@ -0,0 +1,6 @@
+//go:build mysql
+
+package profiling
+
+// registers the mysql driver of the database command
+import _ "github.com/go-sql-driver/mysql"
@@ -0,0 +1,6 @@
+//go:build postgres
+
+package profiling
+
+// registers the postgres driver of the database command
+import _ "github.com/lib/pq"
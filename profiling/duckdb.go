@@ -0,0 +1,159 @@
+//go:build duckdb
+
+package profiling
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/duckdb/duckdb-go/v2"
+	"strings"
+)
+
+// duckDBValidator validates candidates with set differences in an embedded
+// DuckDB reading the data files directly, so validation does not depend on
+// the value sets fitting into memory: DuckDB spills large ones to disk.
+type duckDBValidator struct {
+	connection  *sql.DB
+	ignoreNulls bool
+}
+
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func QuoteString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// ReadCSV returns a DuckDB table function reading the table's files with all
+// columns as text, named by their ids, as values are compared as text.
+func (this *Table) ReadCSV() string {
+	if IsParquetFile(this.path) {
+		var columns []string
+		for i := 0; i < this.fields; i++ {
+			columns = append(columns, fmt.Sprintf("CAST(#%v AS VARCHAR) AS c%03d", i+1, i))
+		}
+		return fmt.Sprintf("(SELECT %v FROM read_parquet(%v))", strings.Join(columns, ", "), QuoteString(this.path))
+	}
+	var files, columns []string
+	dataFields := this.DataFields()
+	if this.partitions != nil {
+		files = this.PartitionPaths()
+	} else {
+		files = []string{this.path}
+	}
+	for i, file := range files {
+		files[i] = QuoteString(file)
+	}
+	// every field needs a name, including those of excluded columns
+	for i := 0; i < dataFields; i++ {
+		columns = append(columns, fmt.Sprintf("'c%03d': 'VARCHAR'", i))
+	}
+	format := this.format
+	if format == nil {
+		format = &FileFormat{separator: '\t'}
+	}
+	if format.widths != nil {
+		panic("the duckdb validator cannot read the fixed width files of " + this.QualifiedName() + ", use -validator memory or spider")
+	}
+	char := func(r rune) string {
+		if r == 0 {
+			return "''"
+		}
+		return QuoteString(string(r))
+	}
+	delimiter := char(format.separator)
+	if format.delimiter != "" {
+		delimiter = QuoteString(format.delimiter)
+	}
+	escape := format.escape
+	if escape == 0 {
+		// quotes inside quoted fields are written twice
+		escape = format.quote
+	}
+	return fmt.Sprintf("read_csv([%v], auto_detect = false, strict_mode = false, columns = {%v}, delim = %v, quote = %v, escape = %v, header = %v, skip = %v, ignore_errors = %v, null_padding = %v, hive_partitioning = %v, hive_types_autocast = false)",
+		strings.Join(files, ", "), strings.Join(columns, ", "), delimiter, char(format.quote), char(escape),
+		this.hasHeader, format.skipLines, this.RaggedRows() == "skip", this.RaggedRows() == "pad", this.partitions != nil)
+}
+
+// Expression selects a column of its table's view, normalized like Normalize
+// does in memory. Partition columns keep the key as their name.
+func (this *Column) Expression() (expression string) {
+	name := this.id
+	if this.partition {
+		name = this.name
+	}
+	// empty fields are read as NULL, but are values like any other in memory
+	expression = fmt.Sprintf("coalesce(%v, '')", QuoteIdentifier(name))
+	if this.config == nil {
+		return expression
+	}
+	if this.config.trim {
+		expression = fmt.Sprintf("trim(%v, ' ' || chr(9) || chr(10) || chr(11) || chr(12) || chr(13))", expression)
+	}
+	if len(this.config.nullTokens) > 0 {
+		var tokens []string
+		for token := range this.config.nullTokens {
+			tokens = append(tokens, QuoteString(token))
+		}
+		expression = fmt.Sprintf("CASE WHEN %v IN (%v) THEN '' ELSE %v END", expression, strings.Join(tokens, ", "), expression)
+	}
+	switch this.config.caseFolding {
+	case "lower":
+		expression = fmt.Sprintf("lower(%v)", expression)
+	case "upper":
+		expression = fmt.Sprintf("upper(%v)", expression)
+	}
+	return expression
+}
+
+func NewDuckDBValidator(db Database, ignoreNulls bool) Validator {
+	connection, err := sql.Open("duckdb", "")
+	check(err)
+	// a single connection, as views are local to the in-memory database
+	connection.SetMaxOpenConns(1)
+	for _, table := range db {
+		_, err := connection.Exec(fmt.Sprintf("CREATE VIEW %v AS SELECT * FROM %v", QuoteIdentifier(table.id), table.ReadCSV()))
+		check(err)
+	}
+	return &duckDBValidator{connection, ignoreNulls}
+}
+
+// Dependent selects the values of a dependent column, without the empty one
+// if nulls are ignored.
+func (this *duckDBValidator) Dependent(column *Column) string {
+	query := fmt.Sprintf("SELECT %v AS v FROM %v", column.Expression(), QuoteIdentifier(column.table.id))
+	if this.ignoreNulls {
+		query += fmt.Sprintf(" WHERE %v <> ''", column.Expression())
+	}
+	return query
+}
+
+func (this *duckDBValidator) Check(candidate *Candidate) bool {
+	a, b := candidate.a, candidate.b
+	var missing bool
+	check(this.connection.QueryRow(fmt.Sprintf("SELECT EXISTS (%v EXCEPT SELECT %v FROM %v)",
+		this.Dependent(a), b.Expression(), QuoteIdentifier(b.table.id))).Scan(&missing))
+	return !missing
+}
+
+func (this *duckDBValidator) Coverage(candidate *Candidate) float64 {
+	a, b := candidate.a, candidate.b
+	var missing, distinct int
+	check(this.connection.QueryRow(fmt.Sprintf("SELECT (SELECT count(*) FROM (%v EXCEPT SELECT %v FROM %v)), (SELECT count(DISTINCT v) FROM (%v))",
+		this.Dependent(a), b.Expression(), QuoteIdentifier(b.table.id), this.Dependent(a))).Scan(&missing, &distinct))
+	if distinct == 0 {
+		return 1
+	}
+	return 1 - float64(missing)/float64(distinct)
+}
+
+// Parallel queries wait for the single connection, but DuckDB runs each of
+// them on all cores.
+func (this *duckDBValidator) Parallel() bool {
+	return true
+}
+
+func (this *duckDBValidator) Close() {
+	check(this.connection.Close())
+}
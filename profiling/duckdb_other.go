@@ -0,0 +1,9 @@
+//go:build !duckdb
+
+package profiling
+
+// NewDuckDBValidator is only available in builds with the duckdb tag, which
+// need cgo.
+func NewDuckDBValidator(db Database, ignoreNulls bool) Validator {
+	panic("this build has no DuckDB support, rebuild with -tags duckdb")
+}
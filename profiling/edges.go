@@ -0,0 +1,101 @@
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Edge is an inclusion a <= b of the graph.
+type Edge struct {
+	a, b *Column
+}
+
+// Score is the share of b's distinct values that a covers, high for columns
+// referencing most of a key.
+func (this Edge) Score() float64 {
+	if this.b.DistinctValues() == 0 {
+		return 0
+	}
+	return float64(this.a.DistinctValues()) / float64(this.b.DistinctValues())
+}
+
+// EdgeFilter selects the edges with a minimum score touching one of the
+// tables, or any table if there are none.
+type EdgeFilter struct {
+	tables   map[string]bool
+	minScore float64
+}
+
+// ParseEdgeFilter reads comma separated table names.
+func ParseEdgeFilter(tables string, minScore float64) (filter EdgeFilter) {
+	filter.minScore = minScore
+	if tables != "" {
+		filter.tables = make(map[string]bool)
+		for _, table := range strings.Split(tables, ",") {
+			filter.tables[table] = true
+		}
+	}
+	return filter
+}
+
+func (this EdgeFilter) Accepts(edge Edge) bool {
+	if this.tables != nil && !this.tables[edge.a.table.QualifiedName()] && !this.tables[edge.b.table.QualifiedName()] {
+		return false
+	}
+	return edge.Score() >= this.minScore
+}
+
+// Edges passes the accepted edges to visit one at a time, ordered by the
+// indexes of their columns and starting at cursor, 0 for the first edge.
+// It stops when visit returns false and returns the cursor of the edge after
+// the last one visited, or -1 if there are none left. Edges are never
+// collected, so graphs whose closure has millions of edges can be streamed.
+func (this *InclusionGraph) Edges(filter EdgeFilter, cursor int, visit func(edge Edge) bool) (next int) {
+	size := len(this.nodes)
+	for position := cursor; position < size*size; position++ {
+		i, j := position/size, position%size
+		if i == j || !this.adjacencyMatrix[i][j] {
+			continue
+		}
+		edge := Edge{this.nodes[i], this.nodes[j]}
+		if filter.Accepts(edge) && !visit(edge) {
+			return position + 1
+		}
+	}
+	return -1
+}
+
+// EdgePage returns up to limit accepted edges from cursor on, and the cursor
+// of the next page, which is -1 after the last one.
+func (this *InclusionGraph) EdgePage(filter EdgeFilter, cursor int, limit int) (edges []Edge, next int) {
+	next = this.Edges(filter, cursor, func(edge Edge) bool {
+		edges = append(edges, edge)
+		return len(edges) < limit
+	})
+	return edges, next
+}
+
+// ExportEdges writes the accepted edges to edges-<n>.tsv files of at most
+// chunkSize edges each.
+func (this *InclusionGraph) ExportEdges(dir string, filter EdgeFilter, chunkSize int) {
+	if chunkSize < 1 {
+		panic("edge files need room for at least one edge")
+	}
+	check(os.MkdirAll(dir, 0755))
+	for chunk, cursor := 0, 0; cursor >= 0; chunk++ {
+		var edges []Edge
+		edges, cursor = this.EdgePage(filter, cursor, chunkSize)
+		if len(edges) == 0 {
+			break
+		}
+		WriteOutput(filepath.Join(dir, fmt.Sprintf("edges-%06d.tsv", chunk)), func(w io.Writer) {
+			fmt.Fprintln(w, "dependent\treferenced\tscore")
+			for _, edge := range edges {
+				fmt.Fprintf(w, "%v\t%v\t%.4f\n", edge.a.Name(), edge.b.Name(), edge.Score())
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package profiling
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type expectationSuite struct {
+	Name         string                 `json:"expectation_suite_name"`
+	Expectations []expectation          `json:"expectations"`
+	Meta         map[string]interface{} `json:"meta"`
+}
+
+type expectation struct {
+	Type   string                 `json:"expectation_type"`
+	Kwargs map[string]interface{} `json:"kwargs"`
+	Meta   map[string]interface{} `json:"meta"`
+}
+
+func NewExpectation(expectationType string, column *Column) expectation {
+	return expectation{expectationType, map[string]interface{}{"column": column.name}, map[string]interface{}{}}
+}
+
+// Expectations turns the column's profile into Great Expectations
+// expectations.
+func (this *Column) Expectations(foreignKeys []*Column) (result []expectation) {
+	// ranges hold values of the data, which redaction keeps out
+	if stats, ok := this.stats.(*intStatistics); ok && redaction == "" {
+		between := NewExpectation("expect_column_values_to_be_between", this)
+		between.Kwargs["min_value"] = stats.minimum
+		between.Kwargs["max_value"] = stats.maximum
+		result = append(result, between)
+	}
+	if stats, ok := this.stats.(*floatStatistics); ok && redaction == "" {
+		between := NewExpectation("expect_column_values_to_be_between", this)
+		between.Kwargs["min_value"] = stats.minimum
+		between.Kwargs["max_value"] = stats.maximum
+		result = append(result, between)
+	}
+	if !this.HasNulls() {
+		result = append(result, NewExpectation("expect_column_values_to_not_be_null", this))
+	}
+	if valueSet := this.ValueSet(); valueSet != nil {
+		inSet := NewExpectation("expect_column_values_to_be_in_set", this)
+		inSet.Kwargs["value_set"] = valueSet
+		result = append(result, inSet)
+	}
+	for _, referenced := range foreignKeys {
+		// not part of the core expectation gallery, see meta.notes
+		exists := NewExpectation("expect_column_values_to_exist_in_other_table", this)
+		exists.Kwargs["other_table"] = referenced.table.QualifiedName()
+		exists.Kwargs["other_column"] = referenced.name
+		exists.Meta["notes"] = "discovered inclusion dependency " + this.Name() + " <= " + referenced.Name()
+		result = append(result, exists)
+	}
+	return result
+}
+
+func (this *InclusionGraph) ExpectationSuite(table *Table) (suite expectationSuite) {
+	foreignKeys := this.ForeignKeys()
+	suite.Name = table.QualifiedName()
+	suite.Expectations = []expectation{}
+	suite.Meta = map[string]interface{}{"great_expectations_version": "0.15.50", "generated_by": "dataprofiling " + version}
+	for _, column := range table.columns {
+		suite.Expectations = append(suite.Expectations, column.Expectations(foreignKeys[column])...)
+	}
+	return suite
+}
+
+// ExportExpectations writes one <table>.json expectation suite per table.
+func (this *InclusionGraph) ExportExpectations(dir string, db Database) {
+	check(os.MkdirAll(dir, 0755))
+	for _, table := range db {
+		suite := this.ExpectationSuite(table)
+		WriteOutput(filepath.Join(dir, table.QualifiedName()+".json"), func(w io.Writer) {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			check(encoder.Encode(suite))
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package profiling
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// FindColumn looks a column up by its qualified name (table.column) or by
+// its identifier as printed in the inclusion list (t000[c000]).
+func (db Database) FindColumn(name string) *Column {
+	for _, column := range db.AllColumns() {
+		if column.Name() == name || column.String() == name {
+			return column
+		}
+	}
+	return nil
+}
+
+// MissingValues returns up to limit of the column's distinct values missing
+// from the other column, in order.
+func (this *Column) MissingValues(other *Column, ignoreNulls bool, limit int) (result []string) {
+	this.EachComparedValue(other, func(value string, contained bool) bool {
+		if !contained && (value != "" || !ignoreNulls) {
+			result = append(result, value)
+		}
+		return len(result) < limit
+	})
+	return result
+}
+
+// Explain walks a <= b through the same stages as candidate generation and
+// validation and reports the first one rejecting it.
+func (db Database) Explain(a *Column, b *Column, options *Options) {
+	fmt.Println("explaining", a.Label(), "<=", b.Label())
+	for _, column := range []*Column{a, b} {
+		if !column.IsSearched(options) {
+			fmt.Println("rejected by search,", column.Label(), "holds coordinates, which are searched with -geo-inclusions only")
+			return
+		}
+	}
+	if options.candidateIndex == "lsh" {
+		found := false
+		for _, other := range NewLSHIndex([]*Column{a, b}, options.lshRows, 1).Similar(a, options.minCoverage) {
+			found = found || other == b
+		}
+		if !found {
+			fmt.Println("rejected by candidate index, the MinHash signatures of", a.Label(), "and", b.Label(), "share no band or", b.Label(), "cannot contain", a.Label())
+			return
+		}
+		fmt.Println("passed candidate index")
+	}
+	if a.dataType != b.dataType {
+		fmt.Println("rejected by type:", a.dataType, "vs", b.dataType)
+		return
+	}
+	fmt.Println("passed type:", a.dataType)
+	approximate := options.minCoverage < 1
+	if approximate {
+		if !a.MayCover(b, options.minCoverage) {
+			fmt.Println("rejected by counts,", b.Label(), "has", b.DistinctValues(), "distinct values, too few to cover", options.minCoverage, "of the", a.DistinctValues(), "of", a.Label())
+			return
+		}
+		fmt.Println("passed counts")
+	} else {
+		if !a.MayBeIncludedIn(b) {
+			fmt.Println("rejected by counts,", a.Label(), "has", a.DistinctValues(), "distinct values and", a.Bits(), "bloom filter bits set,", b.Label(), b.DistinctValues(), "and", b.Bits())
+			return
+		}
+		fmt.Println("passed counts")
+		if !a.stats.SimiliarTo(b.stats) {
+			if !a.stats.DistinctSketch().MayBeIncludedIn(b.stats.DistinctSketch()) {
+				fmt.Println("rejected by distinct value sketch, a register of the HyperLogLog sketch of", a.Label(), "exceeds that of", b.Label())
+				return
+			}
+			fmt.Println("rejected by statistics, the values of", a.Label(), "are not within the bounds of", b.Label())
+			a.stats.Print(os.Stdout)
+			b.stats.Print(os.Stdout)
+			return
+		}
+		fmt.Println("passed statistics")
+		if !a.filter.SimiliarTo(b.filter) {
+			missingBits := a.filter.Difference(b.filter).Count()
+			fmt.Println("rejected by bloom filter,", missingBits, "of", a.Bits(), "bits are not set for", b.Label())
+			return
+		}
+		fmt.Println("passed bloom filter")
+	}
+	if options.candidatePruning == "heuristic" {
+		if !a.PlausibleReference(b, options.minNameSimilarity) {
+			fmt.Println("rejected by candidate pruning, the names are less similar than -min-name-similarity", options.minNameSimilarity, "or the declared types are incompatible")
+			return
+		}
+		fmt.Println("passed candidate pruning")
+	}
+	validator := NewValidator(options, db)
+	defer validator.Close()
+	candidate := &Candidate{a, b}
+	if approximate {
+		if coverage := validator.Coverage(candidate); coverage < options.minCoverage {
+			fmt.Println("rejected by coverage check, only", strconv.FormatFloat(100*coverage, 'f', 1, 64)+"% of the values of", a.Label(), "are in", b.Label()+", below -min-coverage")
+			db.PrintMissingValues(a, b, options)
+			return
+		}
+		fmt.Println("passed coverage check,", a.Label(), "is covered by", b.Label())
+		return
+	}
+	if !validator.Check(candidate) {
+		fmt.Println("rejected by exact check, values missing in", b.Label()+":")
+		db.PrintMissingValues(a, b, options)
+		return
+	}
+	fmt.Println("passed exact check,", a.Label(), "is included in", b.Label())
+}
+
+func (db Database) PrintMissingValues(a *Column, b *Column, options *Options) {
+	for _, value := range a.MissingValues(b, options.IgnoreNulls(), 10) {
+		fmt.Printf("\t%q\n", Redact(value))
+	}
+}
+
+func RunExplain(options *Options) {
+	db := LoadDatabase(options)
+	db.PrintLoaded(options)
+	a, b := db.FindColumn(options.arguments[1]), db.FindColumn(options.arguments[2])
+	if a == nil || b == nil {
+		panic("unknown column")
+	}
+	db.BuildFilters(options)
+	db.Explain(a, b, options)
+}
@@ -0,0 +1,107 @@
+package profiling
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// A table whose definition or files cannot be read, e.g. as a file is missing
+// or unreadable, no longer ends the run: its error is recorded, the table is
+// skipped, and the other tables are profiled and validated as usual. The run
+// then lists the failures and exits with status 1. Failures of the run as a
+// whole, like an unwritable output, still end it right away, as does any
+// failure with -fail-fast. Runtime errors are bugs rather than bad input and
+// are never recorded.
+
+// the failure a run ends with when interrupted
+var errInterrupted = errors.New("interrupted")
+
+type tableFailure struct {
+	table   string
+	phase   string
+	failure interface{}
+}
+
+type Failures struct {
+	mutex    sync.Mutex
+	failures []tableFailure
+	failFast bool
+}
+
+func (this *Failures) Record(table string, phase string, failure interface{}) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.failures = append(this.failures, tableFailure{table, phase, failure})
+	logger.Errorf("skipping %v, its %v failed: %v", table, phase, failure)
+}
+
+// Guard runs work on a table and tells whether it succeeded, recording the
+// failure it panicked with otherwise.
+func (this *Failures) Guard(table string, phase string, work func()) (ok bool) {
+	if this.failFast {
+		work()
+		return true
+	}
+	defer func() {
+		if failure := recover(); failure != nil {
+			if _, bug := failure.(runtime.Error); bug {
+				panic(failure)
+			}
+			this.Record(table, phase, failure)
+			ok = false
+		}
+	}()
+	work()
+	return true
+}
+
+func (this *Failures) Count() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return len(this.failures)
+}
+
+// Report lists the failures once the run is done.
+func (this *Failures) Report() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if len(this.failures) == 0 {
+		return
+	}
+	logger.Errorf("%v tables failed and were skipped:", len(this.failures))
+	for _, failure := range this.failures {
+		logger.Errorf("  %v (%v): %v", failure.table, failure.phase, failure.failure)
+		status.Error(failure.String())
+	}
+}
+
+func (this tableFailure) String() string {
+	return fmt.Sprintf("%v: %v failed: %v", this.table, this.phase, this.failure)
+}
+
+// Err describes the skipped tables, nil if none were.
+func (this *Failures) Err() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if len(this.failures) == 0 {
+		return nil
+	}
+	descriptions := make([]string, len(this.failures))
+	for i, failure := range this.failures {
+		descriptions[i] = failure.String()
+	}
+	return fmt.Errorf("skipped %v tables: %v", len(this.failures), strings.Join(descriptions, "; "))
+}
+
+// WithoutFailed returns the tables which did not fail.
+func (db Database) WithoutFailed() (result Database) {
+	for _, table := range db {
+		if !table.failed {
+			result = append(result, table)
+		}
+	}
+	return result
+}
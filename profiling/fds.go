@@ -0,0 +1,226 @@
+package profiling
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// Functional dependencies X → A within a table are found with TANE's lattice
+// search: the rows are partitioned by their values of a column set, and
+// X → A holds if the partition by X is not refined by adding A. Column sets
+// are bit masks of the columns' positions in the table, so only tables of up
+// to 64 columns are searched. Only minimal dependencies are reported, and an
+// empty determinant marks a constant column.
+
+const maxDependencyColumns = 64
+
+type FunctionalDependency struct {
+	determinant []*Column
+	dependent   *Column
+}
+
+func (this *FunctionalDependency) String() string {
+	var names []string
+	for _, column := range this.determinant {
+		names = append(names, column.name)
+	}
+	return fmt.Sprintf("%v[%v] → %v", this.dependent.table.QualifiedName(), strings.Join(names, ", "), this.dependent.name)
+}
+
+// strippedPartition lists the sets of rows agreeing on the values of a
+// column set, leaving out rows whose values are unique.
+type strippedPartition struct {
+	classes [][]int32
+}
+
+// Error is the number of rows to remove for the column set to become unique,
+// which is the same for X and X ∪ {A} exactly if X → A.
+func (this *strippedPartition) Error() (result int) {
+	for _, class := range this.classes {
+		result += len(class) - 1
+	}
+	return result
+}
+
+// Product partitions the rows by the column sets of both partitions. owner
+// must hold -1 for every row and is left that way.
+func (this *strippedPartition) Product(other *strippedPartition, owner []int32) (result *strippedPartition) {
+	result = &strippedPartition{}
+	for i, class := range this.classes {
+		for _, row := range class {
+			owner[row] = int32(i)
+		}
+	}
+	groups := make([][]int32, len(this.classes))
+	for _, class := range other.classes {
+		for _, row := range class {
+			if i := owner[row]; i >= 0 {
+				groups[i] = append(groups[i], row)
+			}
+		}
+		for _, row := range class {
+			if i := owner[row]; i >= 0 {
+				if len(groups[i]) > 1 {
+					result.classes = append(result.classes, groups[i])
+				}
+				groups[i] = nil
+			}
+		}
+	}
+	for _, class := range this.classes {
+		for _, row := range class {
+			owner[row] = -1
+		}
+	}
+	return result
+}
+
+// ReadPartitions partitions the rows by each column's values in one pass.
+func (this *Table) ReadPartitions() (partitions []*strippedPartition) {
+	classes := make([]map[string][]int32, len(this.columns))
+	for i := range classes {
+		classes[i] = make(map[string][]int32)
+	}
+	rows := this.OpenRows()
+	var row int32
+	for {
+		fields := rows.Read()
+		if len(fields) == 0 {
+			break
+		}
+		for i, column := range this.columns {
+			value := column.Normalize(fields[column.field])
+			classes[i][value] = append(classes[i][value], row)
+		}
+		row++
+	}
+	for i := range this.columns {
+		partition := &strippedPartition{}
+		for _, class := range classes[i] {
+			if len(class) > 1 {
+				partition.classes = append(partition.classes, class)
+			}
+		}
+		partitions = append(partitions, partition)
+	}
+	return partitions
+}
+
+// ColumnSet returns the columns of a bit mask.
+func (this *Table) ColumnSet(set uint64) (columns []*Column) {
+	for ; set != 0; set &= set - 1 {
+		columns = append(columns, this.columns[bits.TrailingZeros64(set)])
+	}
+	return columns
+}
+
+// FindDependencies searches the minimal functional dependencies with at most
+// maxDeterminant columns on the left side level by level, pruning the
+// dependent columns each column set may still determine (TANE's C+).
+func (this *Table) FindDependencies(maxDeterminant int) {
+	this.dependencies = nil
+	if this.rowCount == 0 || len(this.columns) > maxDependencyColumns {
+		return
+	}
+	all := ^uint64(0) >> (maxDependencyColumns - len(this.columns))
+	errors := map[uint64]int{0: this.rowCount - 1}
+	candidates := map[uint64]uint64{0: all}
+	partitions := make(map[uint64]*strippedPartition)
+	var level []uint64
+	for i, partition := range this.ReadPartitions() {
+		set := uint64(1) << i
+		partitions[set], errors[set] = partition, partition.Error()
+		level = append(level, set)
+	}
+	owner := make([]int32, this.rowCount)
+	for i := range owner {
+		owner[i] = -1
+	}
+	for size := 1; len(level) > 0; size++ {
+		var kept []uint64
+		for _, set := range level {
+			remaining := all
+			for subsets := set; subsets != 0; subsets &= subsets - 1 {
+				remaining &= candidates[set&^(subsets&-subsets)]
+			}
+			for dependents := set & remaining; dependents != 0; dependents &= dependents - 1 {
+				dependent := dependents & -dependents
+				if errors[set&^dependent] == errors[set] {
+					this.dependencies = append(this.dependencies, &FunctionalDependency{this.ColumnSet(set &^ dependent),
+						this.columns[bits.TrailingZeros64(dependent)]})
+					remaining &^= dependent | all&^set
+				}
+			}
+			if candidates[set] = remaining; remaining != 0 {
+				kept = append(kept, set)
+			}
+		}
+		if size > maxDeterminant {
+			break
+		}
+		level = this.NextDependencyLevel(kept, partitions, errors, owner)
+	}
+}
+
+// NextDependencyLevel joins the column sets of a level differing only in
+// their last column, keeping those all of whose subsets are in the level.
+func (this *Table) NextDependencyLevel(level []uint64, partitions map[uint64]*strippedPartition, errors map[uint64]int, owner []int32) (next []uint64) {
+	sort.Slice(level, func(i, j int) bool { return level[i] < level[j] })
+	kept := make(map[uint64]bool, len(level))
+	for _, set := range level {
+		kept[set] = true
+	}
+	last := func(set uint64) uint64 {
+		return uint64(1) << (63 - bits.LeadingZeros64(set))
+	}
+	for i, x := range level {
+		for _, y := range level[i+1:] {
+			if x&^last(x) != y&^last(y) {
+				continue
+			}
+			set := x | y
+			valid := true
+			for subsets := set; subsets != 0 && valid; subsets &= subsets - 1 {
+				valid = kept[set&^(subsets&-subsets)]
+			}
+			if valid {
+				partitions[set] = partitions[x].Product(partitions[y], owner)
+				errors[set] = partitions[set].Error()
+				next = append(next, set)
+			}
+		}
+	}
+	for _, set := range level {
+		delete(partitions, set)
+	}
+	return next
+}
+
+// FindDependencies searches the functional dependencies of all tables, with
+// determinants of -max-determinant columns at most.
+func (db Database) FindDependencies(options *Options) {
+	for _, table := range db {
+		if len(table.columns) > maxDependencyColumns {
+			logger.Infof("skipping functional dependencies of %v having more than %v columns", table.QualifiedName(), maxDependencyColumns)
+		}
+	}
+	RunWorkers(options.analysisWorkers, len(db), func(i int) {
+		db[i].FindDependencies(options.maxDeterminant)
+	})
+}
+
+func (db Database) PrintDependencies() {
+	count := 0
+	for _, table := range db {
+		count += len(table.dependencies)
+	}
+	fmt.Println("found", count, "functional dependencies")
+	status.Result("functional dependencies", count)
+	for _, table := range db {
+		for _, dependency := range table.dependencies {
+			fmt.Println(dependency)
+		}
+	}
+}
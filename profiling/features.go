@@ -0,0 +1,168 @@
+package profiling
+
+import (
+	"encoding/csv"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"unicode"
+)
+
+// number of hash functions in a column's MinHash signature
+const minHashSize = 16
+
+// A MinHash signature keeps the smallest value of each of size hash functions
+// over a set of values. The share of equal positions in two signatures
+// estimates the Jaccard similarity of their sets. The hash functions are
+// fixed, so signatures of different runs are comparable.
+
+// NewMinHashSignature returns the signature of no values, to which AddMinHash
+// adds them.
+func NewMinHashSignature(size int) (signature []uint64) {
+	signature = make([]uint64, size)
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+	return signature
+}
+
+// AddMinHash adds a value to a signature.
+func AddMinHash(signature []uint64, value string) {
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	base := hash.Sum64()
+	for i := range signature {
+		if h := SplitMix64(base + uint64(i)*0x9e3779b97f4a7c15); h < signature[i] {
+			signature[i] = h
+		}
+	}
+}
+
+// SplitMix64 scrambles x, deriving independent hashes from a single one.
+func SplitMix64(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// Quartiles returns the minimum, the quartiles and the maximum of sorted.
+func Quartiles(sorted []float64) (result []float64) {
+	for _, q := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		if len(sorted) == 0 {
+			result = append(result, 0)
+		} else {
+			result = append(result, sorted[int(q*float64(len(sorted)-1))])
+		}
+	}
+	return result
+}
+
+func FeatureNames() (names []string) {
+	names = []string{"distinct", "uniqueness", "has_nulls",
+		"min_length", "max_length", "avg_length", "stddev_length", "entropy",
+		"digit_ratio", "letter_ratio", "upper_ratio", "space_ratio", "punct_ratio",
+		"q0", "q1", "q2", "q3", "q4"}
+	for i := 0; i < minHashSize; i++ {
+		names = append(names, fmt.Sprintf("minhash_%02d", i))
+	}
+	return names
+}
+
+// Features describes a column's distinct values as numbers, in the order of
+// FeatureNames. The quartiles are those of the values for numeric columns
+// and of the value lengths otherwise. MinHash values are scaled to [0, 1).
+func (this *Column) Features() (features []float64) {
+	var lengths, numbers []float64
+	characters := make(map[rune]float64)
+	var total, digits, letters, upper, spaces, punctuation float64
+	signature := NewMinHashSignature(minHashSize)
+	this.EachValue(func(value string) {
+		AddMinHash(signature, value)
+		lengths = append(lengths, float64(len([]rune(value))))
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			numbers = append(numbers, number)
+		}
+		for _, r := range value {
+			total++
+			characters[r]++
+			switch {
+			case unicode.IsDigit(r):
+				digits++
+			case unicode.IsLetter(r):
+				letters++
+				if unicode.IsUpper(r) {
+					upper++
+				}
+			case unicode.IsSpace(r):
+				spaces++
+			case unicode.IsPunct(r) || unicode.IsSymbol(r):
+				punctuation++
+			}
+		}
+	})
+	sort.Float64s(lengths)
+	var sum, squares, entropy float64
+	for _, length := range lengths {
+		sum += length
+		squares += length * length
+	}
+	average, deviation := 0.0, 0.0
+	if len(lengths) > 0 {
+		average = sum / float64(len(lengths))
+		deviation = math.Sqrt(math.Max(0, squares/float64(len(lengths))-average*average))
+	}
+	for _, count := range characters {
+		p := count / total
+		entropy -= p * math.Log2(p)
+	}
+	ratio := func(count float64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return count / total
+	}
+	nulls := 0.0
+	if this.HasNulls() {
+		nulls = 1
+	}
+	uniqueness := 0.0
+	if this.table.rowCount > 0 {
+		uniqueness = float64(this.DistinctValues()) / float64(this.table.rowCount)
+	}
+	features = []float64{float64(this.DistinctValues()), uniqueness, nulls}
+	if len(lengths) > 0 {
+		features = append(features, lengths[0], lengths[len(lengths)-1])
+	} else {
+		features = append(features, 0, 0)
+	}
+	features = append(features, average, deviation, entropy,
+		ratio(digits), ratio(letters), ratio(upper), ratio(spaces), ratio(punctuation))
+	if this.dataType == "int" || this.dataType == "float" {
+		sort.Float64s(numbers)
+		features = append(features, Quartiles(numbers)...)
+	} else {
+		features = append(features, Quartiles(lengths)...)
+	}
+	for _, h := range signature {
+		features = append(features, float64(h)/math.Pow(2, 64))
+	}
+	return features
+}
+
+// ExportFeatures writes a CSV file with a row of features per column.
+func (db Database) ExportFeatures(w io.Writer) {
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{"column", "table", "type"}, FeatureNames()...))
+	for _, column := range db.AllColumns() {
+		row := []string{column.Name(), column.table.QualifiedName(), column.dataType}
+		for _, feature := range column.Features() {
+			row = append(row, strconv.FormatFloat(feature, 'g', 6, 64))
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	check(writer.Error())
+}
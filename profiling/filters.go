@@ -0,0 +1,135 @@
+package profiling
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// -include and -exclude limit discovery to some of the tables and columns of
+// the data directory. Their comma separated patterns name tables, like orders
+// or sales.orders, or columns, like orders.customer_id, with * and ? as
+// wildcards, e.g. sales.* for the tables of the sales schema or *.id for the
+// id columns of all tables. Like those of -tables, patterns without a schema
+// match in every schema. Patterns enclosed in slashes, like /^(sales|crm)\./,
+// are regular expressions found anywhere in the name. A column is profiled if
+// it or its table matches an -include pattern, when there are any, and
+// neither matches an -exclude pattern. Tables left without columns are not
+// read at all.
+
+type NameFilter struct {
+	include, exclude []namePattern
+}
+
+type namePattern struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+func ParseNamePatterns(list string) (patterns []namePattern, err error) {
+	for _, text := range strings.Split(list, ",") {
+		text = strings.TrimSpace(text)
+		switch {
+		case text == "":
+		case len(text) >= 2 && strings.HasPrefix(text, "/") && strings.HasSuffix(text, "/"):
+			regex, err := regexp.Compile(text[1 : len(text)-1])
+			if err != nil {
+				return nil, fmt.Errorf("bad pattern %v: %v", text, err)
+			}
+			patterns = append(patterns, namePattern{regex: regex})
+		default:
+			if _, err := path.Match(text, ""); err != nil {
+				return nil, fmt.Errorf("bad pattern %v: %v", text, err)
+			}
+			patterns = append(patterns, namePattern{glob: text})
+		}
+	}
+	return patterns, nil
+}
+
+// ParseNameFilter returns the filter of -include and -exclude, or nil if both
+// are empty.
+func ParseNameFilter(include string, exclude string) (filter *NameFilter, err error) {
+	if include == "" && exclude == "" {
+		return nil, nil
+	}
+	filter = &NameFilter{}
+	if filter.include, err = ParseNamePatterns(include); err != nil {
+		return nil, fmt.Errorf("-include: %v", err)
+	}
+	if filter.exclude, err = ParseNamePatterns(exclude); err != nil {
+		return nil, fmt.Errorf("-exclude: %v", err)
+	}
+	return filter, nil
+}
+
+func (this namePattern) Matches(name string) bool {
+	if this.regex != nil {
+		return this.regex.MatchString(name)
+	}
+	matched, _ := path.Match(this.glob, name)
+	return matched
+}
+
+// MatchesColumn tells whether a pattern matches the column or its table, by
+// their names with or without the schema.
+func MatchesColumn(patterns []namePattern, column *Column) bool {
+	table := column.table
+	for _, pattern := range patterns {
+		for _, name := range []string{table.QualifiedName(), table.name, column.Name(), table.name + "." + column.name} {
+			if pattern.Matches(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (this *NameFilter) Selects(column *Column) bool {
+	return (len(this.include) == 0 || MatchesColumn(this.include, column)) && !MatchesColumn(this.exclude, column)
+}
+
+// ApplyNameFilter returns the tables with the columns the filter selects,
+// leaving out the rules and declared foreign keys involving other columns.
+func (db Database) ApplyNameFilter(filter *NameFilter) (result Database) {
+	selected := make(map[*Column]bool)
+	columns := 0
+	for _, table := range db {
+		var kept []*Column
+		for _, column := range table.columns {
+			columns++
+			if filter.Selects(column) {
+				kept = append(kept, column)
+				selected[column] = true
+			}
+		}
+		if len(kept) > 0 {
+			table.columns = kept
+			result = append(result, table)
+		}
+	}
+	if len(result) == 0 {
+		panic("-include and -exclude leave no columns to profile")
+	}
+	for _, table := range result {
+		var checkers []*ruleChecker
+		for _, checker := range table.checkers {
+			if selected[checker.column] && (checker.referenced == nil || selected[checker.referenced]) {
+				checkers = append(checkers, checker)
+			} else {
+				logger.Infof("not checking rule %v, which involves columns left out by -include or -exclude", checker.rule.Id)
+			}
+		}
+		table.checkers = checkers
+		for _, column := range table.columns {
+			for referenced := range column.foreignKeys {
+				if !selected[referenced] {
+					delete(column.foreignKeys, referenced)
+				}
+			}
+		}
+	}
+	logger.Infof("profiling %v of %v columns in %v of %v tables selected by -include and -exclude", len(selected), columns, len(result), len(db))
+	return result
+}
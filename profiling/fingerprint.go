@@ -0,0 +1,173 @@
+package profiling
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// number of hash functions in a column fingerprint, split into bands of
+// fingerprintRows for locality sensitive hashing
+const (
+	fingerprintSize = 64
+	fingerprintRows = 4
+)
+
+// Fingerprint sketches a column's distinct values, without the empty one, by
+// a MinHash signature truncated to 32 bits per hash. Fingerprints are stable
+// across runs and datasets, so exported ones can be matched later.
+type Fingerprint struct {
+	// the column's name, prefixed by its fingerprints file if not profiled
+	// in this run
+	name      string
+	table     string
+	known     bool
+	distinct  int
+	signature []uint32
+}
+
+func (this *Column) Fingerprint() *Fingerprint {
+	fingerprint := &Fingerprint{name: this.Name(), table: this.table.QualifiedName()}
+	signature := NewMinHashSignature(fingerprintSize)
+	this.EachValue(func(value string) {
+		if value != "" {
+			AddMinHash(signature, value)
+			fingerprint.distinct++
+		}
+	})
+	for _, hash := range signature {
+		fingerprint.signature = append(fingerprint.signature, uint32(hash))
+	}
+	return fingerprint
+}
+
+func (this *Fingerprint) String() string {
+	data := make([]byte, 4*len(this.signature))
+	for i, hash := range this.signature {
+		binary.BigEndian.PutUint32(data[4*i:], hash)
+	}
+	return hex.EncodeToString(data)
+}
+
+func ParseFingerprint(text string) (signature []uint32, ok bool) {
+	data, err := hex.DecodeString(text)
+	if err != nil || len(data) != 4*fingerprintSize {
+		return nil, false
+	}
+	for i := 0; i < len(data); i += 4 {
+		signature = append(signature, binary.BigEndian.Uint32(data[i:]))
+	}
+	return signature, true
+}
+
+// Similarity estimates the Jaccard similarity of the fingerprinted columns.
+func (this *Fingerprint) Similarity(other *Fingerprint) float64 {
+	equal := 0
+	for i, hash := range this.signature {
+		if hash == other.signature[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(this.signature))
+}
+
+// ExportFingerprints writes column<TAB>distinct values<TAB>fingerprint lines.
+func (db Database) ExportFingerprints(w io.Writer) {
+	for _, column := range db.AllColumns() {
+		fingerprint := column.Fingerprint()
+		fmt.Fprintf(w, "%v\t%v\t%v\n", fingerprint.name, fingerprint.distinct, fingerprint)
+	}
+}
+
+// ReadFingerprints reads a file written by -fingerprints. Its columns are
+// named file:column to tell them from the profiled ones.
+func ReadFingerprints(fileName string) (fingerprints []*Fingerprint) {
+	lineReader := NewLineReader(fileName)
+	for line := 1; ; line++ {
+		fields := ReadRow(lineReader)
+		if len(fields) == 0 {
+			break
+		}
+		if len(fields) != 3 {
+			panic(fmt.Sprintf("%v:%v: expected a column, its number of distinct values and its fingerprint", fileName, line))
+		}
+		distinct, err := strconv.Atoi(fields[1])
+		signature, ok := ParseFingerprint(fields[2])
+		if err != nil || !ok {
+			panic(fmt.Sprintf("%v:%v: bad number of distinct values or fingerprint", fileName, line))
+		}
+		name := fileName + ":" + fields[0]
+		fingerprints = append(fingerprints, &Fingerprint{name: name, table: name[:strings.LastIndex(name, ".")], known: true, distinct: distinct, signature: signature})
+	}
+	return fingerprints
+}
+
+type Duplicate struct {
+	a, b       *Fingerprint
+	similarity float64
+}
+
+// FindDuplicates returns the pairs of columns of different tables, at least
+// one of them profiled in this run, whose fingerprints are at least threshold similar and whose numbers of distinct
+// values differ by at most that ratio. Only pairs agreeing on all hashes of
+// one band are compared. Enumerations are left out, as many columns share
+// their few values.
+func FindDuplicates(fingerprints []*Fingerprint, threshold float64) (duplicates []*Duplicate) {
+	buckets := make(map[string][]int)
+	for i, fingerprint := range fingerprints {
+		if fingerprint.distinct <= maxValueSetSize {
+			continue
+		}
+		for band := 0; band < fingerprintSize; band += fingerprintRows {
+			key := fmt.Sprint(band, fingerprint.signature[band:band+fingerprintRows])
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+	compared := make(map[[2]int]bool)
+	for _, bucket := range buckets {
+		for x, i := range bucket {
+			for _, j := range bucket[x+1:] {
+				a, b := fingerprints[i], fingerprints[j]
+				if compared[[2]int{i, j}] || a.table == b.table || (a.known && b.known) {
+					continue
+				}
+				compared[[2]int{i, j}] = true
+				ratio := float64(a.distinct) / float64(b.distinct)
+				if similarity := a.Similarity(b); similarity >= threshold && math.Min(ratio, 1/ratio) >= threshold {
+					duplicates = append(duplicates, &Duplicate{a, b, similarity})
+				}
+			}
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		if duplicates[i].a.name != duplicates[j].a.name {
+			return duplicates[i].a.name < duplicates[j].a.name
+		}
+		return duplicates[i].b.name < duplicates[j].b.name
+	})
+	return duplicates
+}
+
+// PrintDuplicates reports near-identical columns among the profiled ones and
+// those of the -known-fingerprints files.
+func (db Database) PrintDuplicates(options *Options) {
+	var fingerprints []*Fingerprint
+	for _, column := range db.AllColumns() {
+		fingerprints = append(fingerprints, column.Fingerprint())
+	}
+	if options.knownFingerprints != "" {
+		for _, fileName := range strings.Split(options.knownFingerprints, ",") {
+			fingerprints = append(fingerprints, ReadFingerprints(fileName)...)
+		}
+	}
+	duplicates := FindDuplicates(fingerprints, options.duplicateThreshold)
+	fmt.Println("found", len(duplicates), "duplicate columns")
+	for _, duplicate := range duplicates {
+		fmt.Printf("%v ≈ %v\t%.2f\n", duplicate.a.name, duplicate.b.name, duplicate.similarity)
+	}
+}
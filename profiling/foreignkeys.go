@@ -0,0 +1,92 @@
+package profiling
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// foreign key features and their weights in the score
+var foreignKeyWeights = map[string]float64{
+	"unique":      0.35,
+	"name":        0.25,
+	"cardinality": 0.15,
+	"share":       0.15,
+	"coverage":    0.10,
+}
+
+var foreignKeyFeatures = []string{"unique", "name", "cardinality", "share", "coverage"}
+
+// ForeignKeyCandidate scores how likely an inclusion a <= b is a foreign key,
+// as the weighted mean of features between 0 and 1:
+//
+//	unique       share of b's rows with a distinct value, 1 for keys
+//	name         similarity of the column names, 0.5 if both are unnamed
+//	cardinality  distinct values of a relative to enumerations, as inclusions
+//	             of a few codes are often coincidental
+//	share        share of b's distinct values a covers
+//	coverage     share of a's distinct values b contains, below 1 only for
+//	             approximate inclusions
+type ForeignKeyCandidate struct {
+	edge     Edge
+	features map[string]float64
+	score    float64
+}
+
+func (this *InclusionGraph) ScoreForeignKey(edge Edge) *ForeignKeyCandidate {
+	a, b := edge.a, edge.b
+	candidate := &ForeignKeyCandidate{edge: edge, features: make(map[string]float64)}
+	if b.table.rowCount > 0 {
+		candidate.features["unique"] = float64(b.DistinctValues()) / float64(b.table.rowCount)
+	}
+	candidate.features["name"] = 0.5
+	if similarity := NameSimilarity(a, b); similarity >= 0 {
+		candidate.features["name"] = similarity
+	}
+	candidate.features["cardinality"] = math.Min(1, float64(a.DistinctValues())/float64(2*maxValueSetSize))
+	candidate.features["share"] = edge.Score()
+	candidate.features["coverage"] = 1
+	if this.coverage != nil {
+		candidate.features["coverage"] = this.coverage[[2]int{a.index, b.index}]
+	}
+	for _, feature := range foreignKeyFeatures {
+		candidate.score += foreignKeyWeights[feature] * candidate.features[feature]
+	}
+	return candidate
+}
+
+// RankForeignKeys scores every inclusion, the likeliest foreign keys first.
+func (this *InclusionGraph) RankForeignKeys() (candidates []*ForeignKeyCandidate) {
+	this.Edges(EdgeFilter{}, 0, func(edge Edge) bool {
+		candidates = append(candidates, this.ScoreForeignKey(edge))
+		return true
+	})
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	return candidates
+}
+
+// PrintForeignKeys prints the limit best ranked foreign key candidates with
+// their features.
+func (this *InclusionGraph) PrintForeignKeys(limit int) {
+	candidates := this.RankForeignKeys()
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	fmt.Println("likeliest", len(candidates), "foreign keys")
+	w := NewOutput(terminalOutput)
+	fmt.Fprint(w, "dependent\treferenced\tscore")
+	for _, feature := range foreignKeyFeatures {
+		fmt.Fprint(w, "\t"+feature)
+	}
+	fmt.Fprintln(w)
+	for _, candidate := range candidates {
+		fmt.Fprintf(w, "%v\t%v\t%.3f", Colorize(candidate.edge.a.Label(), cyan), Colorize(candidate.edge.b.Label(), cyan), candidate.score)
+		for _, feature := range foreignKeyFeatures {
+			fmt.Fprintf(w, "\t%.2f", candidate.features[feature])
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
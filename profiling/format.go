@@ -0,0 +1,416 @@
+package profiling
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// FileFormat describes delimited files with quoting, or files of fixed width
+// columns, as opposed to the plain tab separated files read by ReadRow. A
+// quote or escape of 0 disables it.
+type FileFormat struct {
+	separator rune
+	// a delimiter of several characters, starting with separator
+	delimiter               string
+	quote                   rune
+	escape                  rune
+	skipLines               int
+	strictQuotes            bool
+	ignoreLeadingWhiteSpace bool
+	skipDifferingLines      bool
+	// widths of the fields of fixed width files, in characters
+	widths []int
+}
+
+// ParseFileFormat reads a format given by -format or after a file in
+// mapping.tsv: tsv for plain tab separated files, which have no FileFormat,
+// csv for RFC 4180 files, optionally followed by a colon, the delimiter and
+// the quote character, as in csv:; or csv:|', or fixed for files of fixed
+// width fields, followed by a colon and the widths in characters, as in
+// fixed:10,4,25. Two different characters after the colon are always the
+// delimiter and the quote, so csv:<> delimits by < and quotes by >, while
+// any other text is taken whole as the delimiter, like || or ;;;. A
+// delimiter is followed by another colon before a quote character to take
+// two different characters as the delimiter, as in csv:<>:", or to give
+// several characters a quote, as in csv:||:', where an empty quote character
+// disables quoting. A tab is written \t.
+func ParseFileFormat(spec string) (*FileFormat, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if spec == "tsv" {
+		return nil, nil
+	}
+	if parts[0] == "fixed" {
+		return ParseFixedWidths(spec, parts[1:])
+	}
+	if parts[0] != "csv" {
+		return nil, fmt.Errorf("unknown format %v, use tsv, csv[:<delimiter>[<quote>]] or fixed:<width>,<width>...", spec)
+	}
+	format := &FileFormat{separator: ',', quote: '"'}
+	if len(parts) == 2 {
+		delimiter, quote := strings.ReplaceAll(parts[1], `\t`, "\t"), "\""
+		if i := strings.LastIndex(delimiter, ":"); i > 0 {
+			delimiter, quote = delimiter[:i], delimiter[i+1:]
+		} else if characters := []rune(delimiter); len(characters) == 2 && characters[0] != characters[1] {
+			delimiter, quote = string(characters[0]), string(characters[1])
+		}
+		characters := []rune(quote)
+		if delimiter == "" || len(characters) > 1 {
+			return nil, fmt.Errorf("format %v needs a delimiter and at most a quote character after the colon", spec)
+		}
+		format.quote = 0
+		if len(characters) == 1 {
+			format.quote = characters[0]
+		}
+		format.separator, _ = utf8.DecodeRuneInString(delimiter)
+		if utf8.RuneCountInString(delimiter) > 1 {
+			format.delimiter = delimiter
+		}
+		if strings.ContainsRune(delimiter, format.quote) || strings.ContainsRune(delimiter, '\n') || format.quote == '\n' {
+			return nil, fmt.Errorf("format %v needs a delimiter and a quote character other than each other and a line break", spec)
+		}
+	}
+	return format, nil
+}
+
+// ParseFixedWidths reads the widths of a fixed width format.
+func ParseFixedWidths(spec string, widths []string) (*FileFormat, error) {
+	if len(widths) == 0 || widths[0] == "" {
+		return nil, fmt.Errorf("format %v needs the widths of the fields after the colon, as in fixed:10,4,25", spec)
+	}
+	format := &FileFormat{}
+	for _, text := range strings.Split(widths[0], ",") {
+		width, err := strconv.Atoi(text)
+		if err != nil || width <= 0 {
+			return nil, fmt.Errorf("format %v needs positive widths, got %v", spec, text)
+		}
+		format.widths = append(format.widths, width)
+	}
+	return format, nil
+}
+
+// SplitFileFormat separates the format a mapped file may name after an @, as
+// in orders.csv@csv:;, from the file.
+func SplitFileFormat(file string) (path string, spec string) {
+	i := -1
+	for _, format := range []string{"@csv", "@tsv", "@fixed"} {
+		if j := strings.LastIndex(file, format); j > i {
+			i = j
+		}
+	}
+	if i < 0 {
+		return file, ""
+	}
+	return file[:i], file[i+1:]
+}
+
+// RowReader reads the rows of one table, in its format if it has one. The
+// files of a partitioned table are read one after the other, appending the
+// partition values to each row.
+type RowReader struct {
+	reader    *bufio.Reader
+	format    *FileFormat
+	table     *Table
+	partition int
+	parquet   *parquetRows
+	// with -sample-rate, the generator keeping rows at the rate
+	random *rand.Rand
+	rate   float64
+	// with -sample-rows, the sampled rows not read yet
+	replay [][]string
+	// rows of the files read so far, sampled or not
+	scanned int
+	// with -ragged-rows, how to handle rows without the expected number of
+	// fields, and the counts of the files read so far
+	policy string
+	fields int
+	files  []*raggedFile
+}
+
+// raggedFile counts the rows of a file with fewer or more fields than its
+// table, first being the data row number of the first of them.
+type raggedFile struct {
+	path  string
+	rows  int
+	short int
+	long  int
+	first int
+}
+
+func (this *RowReader) readRow() []string {
+	if this.parquet != nil {
+		return this.parquet.Read()
+	}
+	if this.reader == nil {
+		return nil
+	}
+	if this.format == nil {
+		return ReadRow(this.reader)
+	}
+	return this.format.ReadRow(this.reader)
+}
+
+// Read returns the next row, or the next sampled one when sampling.
+func (this *RowReader) Read() (fields []string) {
+	if this.replay != nil {
+		if len(this.replay) == 0 {
+			return nil
+		}
+		fields, this.replay = this.replay[0], this.replay[1:]
+		return fields
+	}
+	for {
+		fields = this.readAllRows()
+		if len(fields) == 0 || this.random == nil {
+			return fields
+		}
+		this.scanned++
+		if this.random.Float64() < this.rate {
+			return fields
+		}
+	}
+}
+
+func (this *RowReader) readAllRows() (fields []string) {
+	for {
+		fields = this.readRow()
+		if this.table != nil {
+			for len(fields) == 0 && this.NextPartition(this.table.hasHeader) {
+				fields = this.readRow()
+			}
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+		if this.files != nil {
+			var keep bool
+			if fields, keep = this.FitRow(fields); !keep {
+				continue
+			}
+		}
+		if this.table != nil {
+			fields = append(fields, this.table.partitions[this.partition].values...)
+		}
+		return fields
+	}
+}
+
+// CheckFields makes the reader apply -ragged-rows to the rows of a file with
+// the given number of fields.
+func (this *RowReader) CheckFields(policy string, fields int, path string) {
+	this.policy, this.fields = policy, fields
+	this.files = []*raggedFile{{path: path}}
+}
+
+// FitRow applies -ragged-rows to a row of the current file: error stops at
+// it, skip leaves it out, pad fills missing fields with empty values and
+// drops extra ones.
+func (this *RowReader) FitRow(fields []string) ([]string, bool) {
+	file := this.files[len(this.files)-1]
+	file.rows++
+	if len(fields) == this.fields {
+		return fields, true
+	}
+	if len(fields) < this.fields {
+		file.short++
+	} else {
+		file.long++
+	}
+	if file.first == 0 {
+		file.first = file.rows
+	}
+	switch this.policy {
+	case "skip":
+		return nil, false
+	case "pad":
+		if len(fields) > this.fields {
+			return fields[:this.fields], true
+		}
+		return append(fields, make([]string, this.fields-len(fields))...), true
+	}
+	panic(fmt.Sprintf("data row %v of %v has %v fields instead of %v, see -ragged-rows", file.rows, file.path, len(fields), this.fields))
+}
+
+// ReportRaggedRows summarizes the rows of each file read that -ragged-rows
+// skipped or padded.
+func (this *RowReader) ReportRaggedRows() {
+	for _, file := range this.files {
+		if file.short+file.long == 0 {
+			continue
+		}
+		action := "padded"
+		if this.policy == "skip" {
+			action = "skipped"
+		}
+		logger.Infof("%v: %v %v of %v rows, %v with fewer and %v with more than %v fields, the first being data row %v",
+			file.path, action, file.short+file.long, file.rows, file.short, file.long, this.fields, file.first)
+	}
+}
+
+func (this *RowReader) SkipLines() {
+	if this.format != nil {
+		for i := 0; i < this.format.skipLines; i++ {
+			this.reader.ReadString('\n')
+		}
+	}
+}
+
+// NextPartition continues with the next file of a partitioned table,
+// skipping its header if it is not the first file.
+func (this *RowReader) NextPartition(skipHeader bool) bool {
+	if this.partition+1 >= len(this.table.partitions) {
+		return false
+	}
+	this.partition++
+	this.reader = NewLineReader(this.table.partitions[this.partition].path)
+	if this.files != nil {
+		this.files = append(this.files, &raggedFile{path: this.table.partitions[this.partition].path})
+	}
+	this.SkipLines()
+	if skipHeader {
+		this.readRow()
+	}
+	return true
+}
+
+// ReadRow reads one record, which spans several lines if a quoted field
+// contains line breaks. Quotes inside quoted fields are written twice or
+// preceded by the escape character.
+func (this *FileFormat) ReadRow(reader *bufio.Reader) (fields []string) {
+	if this.widths != nil {
+		return this.ReadFixedWidthRow(reader)
+	}
+	var field strings.Builder
+	inQuotes, empty := false, true
+	next := func(expected rune) bool {
+		r, _, err := reader.ReadRune()
+		if err == nil && r == expected && expected != 0 {
+			return true
+		}
+		if err == nil {
+			reader.UnreadRune()
+		}
+		return false
+	}
+	for {
+		r, _, err := reader.ReadRune()
+		if err == io.EOF {
+			if empty {
+				return nil
+			}
+			return append(fields, strings.TrimSuffix(field.String(), "\r"))
+		}
+		check(err)
+		empty = false
+		switch {
+		case r == this.escape && this.escape != 0:
+			if next(this.quote) {
+				field.WriteRune(this.quote)
+			} else if next(this.escape) {
+				field.WriteRune(this.escape)
+			} else {
+				field.WriteRune(r)
+			}
+		case r == this.quote && this.quote != 0:
+			if inQuotes && next(this.quote) {
+				field.WriteRune(r)
+			} else {
+				if !inQuotes && this.ignoreLeadingWhiteSpace && strings.TrimSpace(field.String()) == "" {
+					field.Reset()
+				}
+				inQuotes = !inQuotes
+			}
+		case inQuotes:
+			// line breaks inside quoted fields are read as \n, whichever
+			// the file uses
+			if r == '\r' && next('\n') {
+				r = '\n'
+			}
+			field.WriteRune(r)
+		case r == this.separator && this.ReadDelimiter(reader):
+			fields = append(fields, field.String())
+			field.Reset()
+		case r == '\n':
+			return append(fields, strings.TrimSuffix(field.String(), "\r"))
+		case !this.strictQuotes:
+			field.WriteRune(r)
+		}
+	}
+}
+
+// ReadDelimiter tells whether the separator just read starts the delimiter,
+// reading the rest of it if so.
+func (this *FileFormat) ReadDelimiter(reader *bufio.Reader) bool {
+	if this.delimiter == "" {
+		return true
+	}
+	rest := this.delimiter[utf8.RuneLen(this.separator):]
+	if next, err := reader.Peek(len(rest)); err != nil || string(next) != rest {
+		return false
+	}
+	reader.Discard(len(rest))
+	return true
+}
+
+// ReadFixedWidthRow cuts a line into fields of the format's widths, trimming
+// the spaces padding them. Fields beyond the end of a short line are empty,
+// and characters beyond the last field are ignored.
+func (this *FileFormat) ReadFixedWidthRow(reader *bufio.Reader) (fields []string) {
+	line, err := reader.ReadString('\n')
+	if err != io.EOF {
+		check(err)
+	}
+	if line == "" {
+		return nil
+	}
+	characters := []rune(strings.TrimRight(line, "\r\n"))
+	start := 0
+	for _, width := range this.widths {
+		end := start + width
+		if end > len(characters) {
+			end = len(characters)
+		}
+		if start > end {
+			start = end
+		}
+		fields = append(fields, strings.Trim(string(characters[start:end]), " "))
+		start += width
+	}
+	return fields
+}
+
+// utf16Reader transcodes UTF-16 text in the given byte order to UTF-8.
+type utf16Reader struct {
+	reader *bufio.Reader
+	order  binary.ByteOrder
+}
+
+func (this *utf16Reader) Read(p []byte) (n int, err error) {
+	var unit [2]byte
+	for n+2*utf8.UTFMax <= len(p) {
+		if _, err = io.ReadFull(this.reader, unit[:]); err != nil {
+			break
+		}
+		r := rune(this.order.Uint16(unit[:]))
+		if utf16.IsSurrogate(r) {
+			if _, err = io.ReadFull(this.reader, unit[:]); err != nil {
+				break
+			}
+			r = utf16.DecodeRune(r, rune(this.order.Uint16(unit[:])))
+		}
+		n += utf8.EncodeRune(p[n:], r)
+	}
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
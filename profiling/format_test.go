@@ -0,0 +1,40 @@
+package profiling
+
+import "testing"
+
+func TestParseFileFormat(t *testing.T) {
+	tests := []struct {
+		spec      string
+		separator rune
+		delimiter string
+		quote     rune
+	}{
+		{"csv", ',', "", '"'},
+		{"csv:;", ';', "", '"'},
+		{"csv:;'", ';', "", '\''},
+		{"csv:||", '|', "||", '"'},
+		{"csv:<>", '<', "", '>'},
+		{"csv:<>:\"", '<', "<>", '"'},
+		{"csv:||:'", '|', "||", '\''},
+		{"csv:||:", '|', "||", 0},
+		{`csv:\t`, '\t', "", '"'},
+	}
+	for _, test := range tests {
+		format, err := ParseFileFormat(test.spec)
+		if err != nil {
+			t.Errorf("%v: %v", test.spec, err)
+			continue
+		}
+		if format.separator != test.separator || format.delimiter != test.delimiter || format.quote != test.quote {
+			t.Errorf("%v: got separator %q, delimiter %q and quote %q, want %q, %q and %q", test.spec, format.separator, format.delimiter, format.quote, test.separator, test.delimiter, test.quote)
+		}
+	}
+	for _, spec := range []string{"csv:", "csv:\"", "csv:'':'", "xml"} {
+		if _, err := ParseFileFormat(spec); err == nil {
+			t.Errorf("%v: no error", spec)
+		}
+	}
+	if format, err := ParseFileFormat("tsv"); format != nil || err != nil {
+		t.Errorf("tsv: got %v and %v, want no format", format, err)
+	}
+}
@@ -0,0 +1,244 @@
+package profiling
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Generator writes synthetic tables matching the profiles of the analyzed
+// ones: their types, ranges, cardinalities and null shares. Columns included
+// in a unique column draw their values from the values generated for it, so
+// foreign keys hold in the synthetic data as well. Apart from small value
+// sets, which are copied unless -redact is given, no value of the data ends
+// up in the output: strings only keep the shape of the profiled examples.
+type Generator struct {
+	options *Options
+	random  *rand.Rand
+	// referenced unique column of each dependent column
+	references map[*Column]*Column
+	pools      map[*Column][]string
+	generating map[*Column]bool
+}
+
+func GenerateFlags(options *Options, flags *flag.FlagSet) {
+	QueryFlags(options, flags)
+	flags.StringVar(&options.outputDir, "out", "", "directory to write the synthetic tables and their mapping.tsv to")
+	flags.IntVar(&options.rows, "rows", 0, "number of rows per synthetic table (default the profiled row count)")
+}
+
+// RowCount returns the number of rows to generate for the table.
+func (this *Generator) RowCount(table *Table) int {
+	if this.options.rows > 0 {
+		return this.options.rows
+	}
+	return table.rowCount
+}
+
+// Cardinality scales the column's number of distinct non-empty values to the
+// rows generated. Columns with few distinct values keep their number.
+func (this *Generator) Cardinality(column *Column) int {
+	rows := this.RowCount(column.table)
+	if column.IsUnique() {
+		return rows
+	}
+	distinct := column.DistinctValues()
+	if column.HasNulls() {
+		distinct--
+	}
+	if column.table.rowCount > 0 && float64(distinct)/float64(column.table.rowCount) > 0.1 {
+		distinct = int(math.Round(float64(distinct) * float64(rows) / float64(column.table.rowCount)))
+	}
+	if distinct > rows {
+		distinct = rows
+	}
+	if distinct < 1 {
+		distinct = 1
+	}
+	return distinct
+}
+
+// Pool returns the distinct values the column's rows are drawn from.
+func (this *Generator) Pool(column *Column) []string {
+	if pool, ok := this.pools[column]; ok {
+		return pool
+	}
+	size := this.Cardinality(column)
+	var pool []string
+	// cycles of inclusions between unique columns are broken here
+	if referenced := this.references[column]; referenced != nil && !this.generating[referenced] {
+		this.generating[column] = true
+		pool = append([]string(nil), this.Pool(referenced)...)
+		this.random.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+		if size < len(pool) {
+			pool = pool[:size]
+		}
+		delete(this.generating, column)
+	} else if values := column.SortedValues(); len(values) <= maxValueSetSize && len(values) == size && redaction == "" && column.pii == "" {
+		// both leave out the empty value, so columns with nulls are copied too
+		pool = values
+	} else {
+		pool = this.Distinct(column, size)
+	}
+	this.pools[column] = pool
+	return pool
+}
+
+// Distinct generates up to size distinct values like the column's. If their
+// range allows too few of them, integers continue above the maximum, strings
+// get a suffix and dates stay fewer.
+func (this *Generator) Distinct(column *Column, size int) (pool []string) {
+	seen := make(map[string]bool)
+	for attempts := 0; len(pool) < size; attempts++ {
+		value := this.Value(column)
+		if seen[value] || value == "" {
+			if attempts < 10*size {
+				continue
+			}
+			if column.dates != nil {
+				break
+			}
+			if stats, ok := column.stats.(*intStatistics); ok {
+				value = strconv.FormatInt(stats.maximum+int64(attempts), 10)
+			} else {
+				value += strconv.Itoa(len(pool))
+			}
+		}
+		if !seen[value] {
+			seen[value] = true
+			pool = append(pool, value)
+		}
+	}
+	return pool
+}
+
+// Value generates one random value within the column's profile.
+func (this *Generator) Value(column *Column) string {
+	if dates := column.dates; dates != nil {
+		steps := dates.Missing(dates.first, dates.last) + 1
+		return dates.Format(dates.Step(dates.first, this.random.Intn(steps+1)))
+	}
+	switch stats := column.stats.(type) {
+	case *intStatistics:
+		if stats.maximum <= stats.minimum {
+			return strconv.FormatInt(stats.minimum, 10)
+		}
+		return strconv.FormatInt(stats.minimum+this.random.Int63n(stats.maximum-stats.minimum+1), 10)
+	case *floatStatistics:
+		return this.Float(column)
+	case *dateStatistics:
+		seconds := stats.maximum.Unix() - stats.minimum.Unix()
+		return stats.Format(stats.minimum.Add(time.Duration(this.random.Int63n(seconds+1)) * time.Second))
+	case *stringStatistics:
+		examples := []string{stats.minimum, stats.maximum, stats.longest, stats.shortest}
+		return Reshape(examples[this.random.Intn(len(examples))], this.random)
+	}
+	return ""
+}
+
+// Float generates a number between the column's smallest and largest, with
+// as many decimals as the longest of them.
+func (this *Generator) Float(column *Column) string {
+	minimum, maximum, decimals := math.Inf(1), math.Inf(-1), 0
+	column.EachValue(func(value string) {
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			minimum, maximum = math.Min(minimum, number), math.Max(maximum, number)
+			if point := strings.IndexByte(value, '.'); point >= 0 && len(value)-point-1 > decimals {
+				decimals = len(value) - point - 1
+			}
+		}
+	})
+	if math.IsInf(minimum, 0) {
+		return "0"
+	}
+	return strconv.FormatFloat(minimum+this.random.Float64()*(maximum-minimum), 'f', decimals, 64)
+}
+
+// Reshape replaces every letter and digit of an example by a random one of the
+// same kind, keeping punctuation like the @ of an email address.
+func Reshape(example string, random *rand.Rand) string {
+	runes := []rune(example)
+	for i, r := range runes {
+		switch {
+		case unicode.IsDigit(r):
+			runes[i] = rune('0' + random.Intn(10))
+		case unicode.IsUpper(r):
+			runes[i] = rune('A' + random.Intn(26))
+		case unicode.IsLetter(r):
+			runes[i] = rune('a' + random.Intn(26))
+		}
+	}
+	return string(runes)
+}
+
+// Rows draws the table's rows, using every value of a column's pool before
+// repeating any, so that the cardinalities match the profile.
+func (this *Generator) Rows(table *Table, write func(row []string)) {
+	rows := this.RowCount(table)
+	orders := make([][]int, len(table.columns))
+	for i, column := range table.columns {
+		orders[i] = this.random.Perm(len(this.Pool(column)))
+	}
+	row := make([]string, len(table.columns))
+	for r := 0; r < rows; r++ {
+		for i, column := range table.columns {
+			pool := this.Pool(column)
+			row[i] = ""
+			if column.table.rowCount > 0 && this.random.Float64() < float64(column.nulls)/float64(column.table.rowCount) {
+				continue
+			}
+			if r < len(pool) {
+				row[i] = pool[orders[i][r]]
+			} else {
+				row[i] = pool[this.random.Intn(len(pool))]
+			}
+		}
+		write(row)
+	}
+}
+
+// RunGenerate writes synthetic data matching the profiles of a data
+// directory, or of a discover run's -results, whose foreign keys it keeps.
+func RunGenerate(options *Options) {
+	db := LoadDatabase(options)
+	db.PrintLoaded(options)
+	generator := &Generator{options: options, random: options.NewRandom("generate"), references: make(map[*Column]*Column),
+		pools: make(map[*Column][]string), generating: make(map[*Column]bool)}
+	if graph := LoadResults(options, db); graph != nil {
+		foreignKeys := graph.ForeignKeys()
+		for column, referenced := range foreignKeys {
+			sort.Slice(referenced, func(i, j int) bool { return referenced[i].index < referenced[j].index })
+			generator.references[column] = referenced[0]
+		}
+	} else {
+		fmt.Println("no -results given, foreign keys are not kept")
+	}
+	check(os.MkdirAll(options.outputDir, 0755))
+	var mapping []string
+	for _, table := range db {
+		fileName := table.FileId() + ".tsv"
+		fields := []string{table.QualifiedName(), fileName}
+		for _, column := range table.columns {
+			fields = append(fields, column.name)
+		}
+		mapping = append(mapping, strings.Join(fields, "\t"))
+		WriteOutput(filepath.Join(options.outputDir, fileName), func(w io.Writer) {
+			generator.Rows(table, func(row []string) {
+				fmt.Fprintln(w, strings.Join(row, "\t"))
+			})
+		})
+	}
+	WriteOutput(filepath.Join(options.outputDir, "mapping.tsv"), func(w io.Writer) {
+		fmt.Fprintln(w, strings.Join(mapping, "\n"))
+	})
+	fmt.Println("wrote", len(db), "synthetic tables to", options.outputDir)
+}
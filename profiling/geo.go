@@ -0,0 +1,215 @@
+package profiling
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// share of checked values that must look geospatial for a column to be
+// classified as such
+const geoThreshold = 0.9
+
+var (
+	wktPattern      = regexp.MustCompile(`(?i)^\s*(MULTI)?(POINT|LINESTRING|POLYGON)\s*(Z|M|ZM)?\s*\(`)
+	numberPattern   = regexp.MustCompile(`-?[0-9]+(\.[0-9]+)?`)
+	latLonPattern   = regexp.MustCompile(`^\(?\s*(-?[0-9]+\.[0-9]+)\s*[,; ]\s*(-?[0-9]+\.[0-9]+)\s*\)?$`)
+	geohashPattern  = regexp.MustCompile(`^[0-9b-hjkmnp-z]{5,12}$`)
+	latitudeHint    = regexp.MustCompile(`(?i)^(lat|latitude)$|_lat$|_latitude$|^lat_|^latitude_`)
+	longitudeHint   = regexp.MustCompile(`(?i)^(lon|lng|long|longitude)$|_(lon|lng|longitude)$|^(lon|lng|longitude)_`)
+	geohashHint     = regexp.MustCompile(`(?i)geo`)
+	geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+// GeoProfile describes a column of coordinates. Latitude and longitude
+// columns only have the bounds of their own dimension.
+type GeoProfile struct {
+	// wkt, geohash, latlon (both in one value), latitude or longitude
+	kind           string
+	minLat, maxLat float64
+	minLon, maxLon float64
+	hasLat, hasLon bool
+	invalid        int
+}
+
+func (this *GeoProfile) AddLatitude(lat float64) {
+	if lat < -90 || lat > 90 {
+		this.invalid++
+		return
+	}
+	if !this.hasLat || lat < this.minLat {
+		this.minLat = lat
+	}
+	if !this.hasLat || lat > this.maxLat {
+		this.maxLat = lat
+	}
+	this.hasLat = true
+}
+
+func (this *GeoProfile) AddLongitude(lon float64) {
+	if lon < -180 || lon > 180 {
+		this.invalid++
+		return
+	}
+	if !this.hasLon || lon < this.minLon {
+		this.minLon = lon
+	}
+	if !this.hasLon || lon > this.maxLon {
+		this.maxLon = lon
+	}
+	this.hasLon = true
+}
+
+// Add records a value of the profile's kind and reports whether it parsed.
+func (this *GeoProfile) Add(value string) bool {
+	switch this.kind {
+	case "wkt":
+		if !wktPattern.MatchString(value) {
+			return false
+		}
+		// coordinates are x y, that is longitude before latitude
+		numbers := numberPattern.FindAllString(value[strings.Index(value, "("):], -1)
+		for i := 0; i+1 < len(numbers); i += 2 {
+			lon, _ := strconv.ParseFloat(numbers[i], 64)
+			lat, _ := strconv.ParseFloat(numbers[i+1], 64)
+			this.AddLongitude(lon)
+			this.AddLatitude(lat)
+		}
+	case "latlon":
+		parts := latLonPattern.FindStringSubmatch(value)
+		if parts == nil {
+			return false
+		}
+		lat, _ := strconv.ParseFloat(parts[1], 64)
+		lon, _ := strconv.ParseFloat(parts[2], 64)
+		this.AddLatitude(lat)
+		this.AddLongitude(lon)
+	case "geohash":
+		lat, lon, ok := DecodeGeohash(value)
+		if !ok {
+			return false
+		}
+		this.AddLatitude(lat)
+		this.AddLongitude(lon)
+	case "latitude", "longitude":
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		if this.kind == "latitude" {
+			this.AddLatitude(number)
+		} else {
+			this.AddLongitude(number)
+		}
+	}
+	return true
+}
+
+// DecodeGeohash returns the center of a geohash cell.
+func DecodeGeohash(hash string) (lat float64, lon float64, ok bool) {
+	if !geohashPattern.MatchString(hash) {
+		return 0, 0, false
+	}
+	minLat, maxLat, minLon, maxLon := -90.0, 90.0, -180.0, 180.0
+	even := true
+	for _, r := range hash {
+		bits := strings.IndexRune(geohashAlphabet, r)
+		for bit := 4; bit >= 0; bit-- {
+			set := bits&(1<<bit) != 0
+			if even {
+				middle := (minLon + maxLon) / 2
+				if set {
+					minLon = middle
+				} else {
+					maxLon = middle
+				}
+			} else {
+				middle := (minLat + maxLat) / 2
+				if set {
+					minLat = middle
+				} else {
+					maxLat = middle
+				}
+			}
+			even = !even
+		}
+	}
+	return (minLat + maxLat) / 2, (minLon + maxLon) / 2, true
+}
+
+// GeoKinds returns the kinds of coordinates the column may hold. Plain
+// numbers and short lower case codes are only taken for coordinates if the
+// column name says so.
+func (this *Column) GeoKinds() (kinds []string) {
+	kinds = []string{"wkt", "latlon"}
+	if this.dataType == "float" && latitudeHint.MatchString(this.name) {
+		kinds = append(kinds, "latitude")
+	}
+	if this.dataType == "float" && longitudeHint.MatchString(this.name) {
+		kinds = append(kinds, "longitude")
+	}
+	if this.dataType == "string" && geohashHint.MatchString(this.name) {
+		kinds = append(kinds, "geohash")
+	}
+	return kinds
+}
+
+// DetectGeo classifies the column as geospatial if nearly all of its values
+// parse as one kind of coordinates, and profiles them.
+func (this *Column) DetectGeo() {
+	for _, kind := range this.GeoKinds() {
+		profile := &GeoProfile{kind: kind}
+		parsed, checked := 0, 0
+		this.EachValue(func(value string) {
+			if value == "" {
+				return
+			}
+			checked++
+			if profile.Add(value) {
+				parsed++
+			}
+		})
+		if checked > 0 && float64(parsed)/float64(checked) >= geoThreshold {
+			// values not parsing at all are invalid as well
+			profile.invalid += checked - parsed
+			this.geo = profile
+			return
+		}
+	}
+}
+
+func (this *GeoProfile) String() string {
+	bounds := func(has bool, min float64, max float64) string {
+		if !has {
+			return "none"
+		}
+		return fmt.Sprintf("%.6g..%.6g", min, max)
+	}
+	var box []string
+	if this.kind != "longitude" {
+		box = append(box, "lat "+bounds(this.hasLat, this.minLat, this.maxLat))
+	}
+	if this.kind != "latitude" {
+		box = append(box, "lon "+bounds(this.hasLon, this.minLon, this.maxLon))
+	}
+	return fmt.Sprintf("%v [%v], %v invalid", this.kind, strings.Join(box, ", "), this.invalid)
+}
+
+// Document describes the profile for JSON exports.
+func (this *GeoProfile) Document() (document map[string]interface{}) {
+	document = map[string]interface{}{"kind": this.kind, "invalid": this.invalid}
+	if this.hasLat {
+		document["latitude"] = []float64{this.minLat, this.maxLat}
+	}
+	if this.hasLon {
+		document["longitude"] = []float64{this.minLon, this.maxLon}
+	}
+	return document
+}
+
+func (db Database) DetectGeo() {
+	for _, column := range db.AllColumns() {
+		column.DetectGeo()
+	}
+}
@@ -0,0 +1,42 @@
+package profiling
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ExportGephi writes the inclusions as a node and an edge list in the CSV
+// format of Gephi's spreadsheet import, which network analysis tools like
+// networkx or igraph read as well. Nodes are columns, edges point from the
+// dependent to the referenced column and are weighted by how much of the
+// referenced column's distinct values the dependent one covers.
+func (this *InclusionGraph) ExportGephi(dir string) {
+	check(os.MkdirAll(dir, 0755))
+	WriteOutput(filepath.Join(dir, "nodes.csv"), func(w io.Writer) {
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"Id", "Label", "table", "type", "cardinality", "unique", "pii"})
+		for _, column := range this.nodes {
+			writer.Write([]string{column.String(), column.Label(), column.table.QualifiedName(), column.dataType,
+				strconv.Itoa(column.DistinctValues()), strconv.FormatBool(column.IsUnique()), column.pii})
+		}
+		writer.Flush()
+		check(writer.Error())
+	})
+	WriteOutput(filepath.Join(dir, "edges.csv"), func(w io.Writer) {
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"Source", "Target", "Type", "Weight"})
+		for _, column := range this.nodes {
+			for _, referenced := range this.nodes {
+				if column != referenced && this.adjacencyMatrix[column.index][referenced.index] {
+					coverage := float64(column.DistinctValues()) / float64(referenced.DistinctValues())
+					writer.Write([]string{column.String(), referenced.String(), "Directed", strconv.FormatFloat(coverage, 'g', 4, 64)})
+				}
+			}
+		}
+		writer.Flush()
+		check(writer.Error())
+	})
+}
@@ -0,0 +1,142 @@
+package profiling
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// share of non-empty distinct values that must match a glossary entry's
+// value pattern for a column to be tagged with its concept
+const conceptThreshold = 0.9
+
+// GlossaryEntry tags the columns whose names match name with a business
+// concept. With a value pattern, nearly all of their values must match too.
+type GlossaryEntry struct {
+	concept string
+	name    *regexp.Regexp
+	value   *regexp.Regexp
+}
+
+// ReadGlossary reads a file of concept<TAB>name pattern[<TAB>value pattern]
+// lines. Name patterns ignore case and an empty one matches every column.
+func ReadGlossary(fileName string) (glossary []*GlossaryEntry) {
+	lineReader := NewLineReader(fileName)
+	for {
+		fields := ReadRow(lineReader)
+		if len(fields) == 0 {
+			break
+		}
+		if len(fields) < 2 || len(fields) > 3 || fields[0] == "" {
+			panic("glossary entries need a concept, a name pattern and an optional value pattern per line")
+		}
+		entry := &GlossaryEntry{concept: fields[0], name: regexp.MustCompile("(?i)" + fields[1])}
+		if len(fields) == 3 && fields[2] != "" {
+			entry.value = regexp.MustCompile(fields[2])
+		}
+		glossary = append(glossary, entry)
+	}
+	return glossary
+}
+
+func (this *GlossaryEntry) Matches(column *Column) bool {
+	if !this.name.MatchString(column.name) {
+		return false
+	}
+	if this.value == nil {
+		return true
+	}
+	matched, checked := 0, 0
+	column.EachValue(func(value string) {
+		if value == "" {
+			return
+		}
+		checked++
+		if this.value.MatchString(value) {
+			matched++
+		}
+	})
+	return checked > 0 && float64(matched)/float64(checked) >= conceptThreshold
+}
+
+// TagConcepts tags every column with the concepts of the glossary entries
+// matching it.
+func (db Database) TagConcepts(glossary []*GlossaryEntry) {
+	for _, column := range db.AllColumns() {
+		column.concepts = nil
+		for _, entry := range glossary {
+			if entry.Matches(column) && !column.HasConcept(entry.concept) {
+				column.concepts = append(column.concepts, entry.concept)
+			}
+		}
+	}
+}
+
+func (this *Column) HasConcept(concept string) bool {
+	for _, tagged := range this.concepts {
+		if tagged == concept {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintConcepts lists the columns of each concept with their inclusions in
+// columns of the same concept, followed by the number of inclusions between
+// columns of different concepts.
+func (this *InclusionGraph) PrintConcepts() {
+	members := make(map[string][]*Column)
+	for _, column := range this.nodes {
+		for _, concept := range column.concepts {
+			members[concept] = append(members[concept], column)
+		}
+	}
+	var concepts []string
+	for concept := range members {
+		concepts = append(concepts, concept)
+	}
+	sort.Strings(concepts)
+	w := NewOutput(terminalOutput)
+	links := make(map[[2]string]int)
+	for _, concept := range concepts {
+		fmt.Fprintf(w, "%v\t%v columns\n", Colorize(concept, yellow), len(members[concept]))
+		for _, column := range members[concept] {
+			var included []string
+			for _, other := range members[concept] {
+				if column != other && this.adjacencyMatrix[column.index][other.index] {
+					included = append(included, other.Label())
+				}
+			}
+			fmt.Fprintf(w, "  %v\t%v\n", Colorize(column.Label(), cyan), strings.Join(included, ", "))
+		}
+	}
+	for _, a := range this.nodes {
+		for _, b := range this.nodes {
+			if a == b || !this.adjacencyMatrix[a.index][b.index] {
+				continue
+			}
+			for _, conceptA := range a.concepts {
+				for _, conceptB := range b.concepts {
+					if conceptA != conceptB {
+						links[[2]string{conceptA, conceptB}]++
+					}
+				}
+			}
+		}
+	}
+	var pairs [][2]string
+	for pair := range links {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	for _, pair := range pairs {
+		fmt.Fprintf(w, "%v ⊆ %v\t%v inclusions\n", Colorize(pair[0], yellow), Colorize(pair[1], yellow), links[pair])
+	}
+	w.Flush()
+}
@@ -0,0 +1,235 @@
+package profiling
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ExportHTML writes a browsable report to a directory: an index of the tables
+// and a page per table describing each column by its statistics, example
+// values and histogram, and listing the inclusions it takes part in on
+// either side, linked to the page of the other column. Values appear only as
+// Redact lets them.
+
+// number of example values shown per column
+const htmlExamples = 5
+
+type htmlLink struct {
+	Href  string
+	Label string
+	// coverage of approximate inclusions, empty for exact ones
+	Coverage string
+}
+
+type htmlBar struct {
+	X, Y, Width, Height float64
+	Title               string
+}
+
+type htmlColumn struct {
+	Anchor       string
+	Result       columnResult
+	Examples     []string
+	Quartiles    string
+	Bars         []htmlBar
+	References   []htmlLink
+	ReferencedBy []htmlLink
+}
+
+type htmlTable struct {
+	Name              string
+	Page              string
+	Rows              int
+	SampledFrom       int
+	Columns           []*htmlColumn
+	Keys              []string
+	Dependencies      []string
+	OrderDependencies []string
+	References        int
+	ReferencedBy      int
+}
+
+func (this *Column) HTMLPage() string {
+	return this.table.id + ".html"
+}
+
+// Examples returns the first values of the column in order.
+func (this *Column) Examples(count int) (examples []string) {
+	this.EachSortedValue(func(value string) bool {
+		if value != "" {
+			examples = append(examples, Redact(value))
+		}
+		return len(examples) < count
+	})
+	return examples
+}
+
+// HTMLBars draws the histogram as bars of a 100 by 30 pixel chart.
+func (this *Histogram) HTMLBars() (bars []htmlBar) {
+	largest := 1
+	for _, bin := range this.Bins {
+		if bin.Count > largest {
+			largest = bin.Count
+		}
+	}
+	width := 100 / float64(len(this.Bins))
+	for i, bin := range this.Bins {
+		height := 30 * float64(bin.Count) / float64(largest)
+		title := fmt.Sprintf("%.6g..%.6g: %v", bin.Lower, bin.Upper, bin.Count)
+		if bin.From != "" {
+			title = fmt.Sprintf("%v..%v: %v", bin.From, bin.To, bin.Count)
+		}
+		bars = append(bars, htmlBar{float64(i) * width, 30 - height, 0.9 * width, height, title})
+	}
+	return bars
+}
+
+// HTMLLink links to the other column of the inclusion a <= b.
+func (this *InclusionGraph) HTMLLink(other *Column, a *Column, b *Column) htmlLink {
+	link := htmlLink{Href: other.HTMLPage() + "#" + other.id, Label: other.Label()}
+	if this.coverage != nil {
+		link.Coverage = strconv.FormatFloat(100*this.coverage[[2]int{a.index, b.index}], 'f', 1, 64) + "%"
+	}
+	return link
+}
+
+// HTMLTables describes the tables for the report's templates.
+func (this *InclusionGraph) HTMLTables() (tables []*htmlTable) {
+	names := func(columns []*Column) (result string) {
+		for i, column := range columns {
+			if i > 0 {
+				result += ", "
+			}
+			result += column.name
+		}
+		return result
+	}
+	for _, table := range this.Tables() {
+		page := &htmlTable{Name: table.QualifiedName(), Page: table.id + ".html", Rows: table.rowCount}
+		if table.sampling != nil {
+			page.SampledFrom = table.sampledFrom
+		}
+		for _, column := range table.columns {
+			result := &htmlColumn{Anchor: column.id, Result: column.Result(true), Examples: column.Examples(htmlExamples)}
+			if histogram := result.Result.Histogram; histogram != nil {
+				result.Bars = histogram.HTMLBars()
+				result.Quartiles = strings.Join(histogram.Quartiles(), " / ")
+			}
+			for _, other := range this.nodes {
+				if other == column {
+					continue
+				}
+				if this.adjacencyMatrix[column.index][other.index] {
+					result.References = append(result.References, this.HTMLLink(other, column, other))
+				}
+				if this.adjacencyMatrix[other.index][column.index] {
+					result.ReferencedBy = append(result.ReferencedBy, this.HTMLLink(other, other, column))
+				}
+			}
+			page.References += len(result.References)
+			page.ReferencedBy += len(result.ReferencedBy)
+			page.Columns = append(page.Columns, result)
+		}
+		for _, key := range table.keys {
+			page.Keys = append(page.Keys, names(key))
+		}
+		for _, dependency := range table.dependencies {
+			page.Dependencies = append(page.Dependencies, names(dependency.determinant)+" → "+dependency.dependent.name)
+		}
+		for _, order := range table.orders {
+			page.OrderDependencies = append(page.OrderDependencies, order.String())
+		}
+		tables = append(tables, page)
+	}
+	return tables
+}
+
+func (this *InclusionGraph) ExportHTML(dir string) {
+	check(os.MkdirAll(dir, 0755))
+	tables := this.HTMLTables()
+	WriteOutput(filepath.Join(dir, "index.html"), func(w io.Writer) {
+		check(htmlTemplates.ExecuteTemplate(w, "index", tables))
+	})
+	for _, table := range tables {
+		WriteOutput(filepath.Join(dir, table.Page), func(w io.Writer) {
+			check(htmlTemplates.ExecuteTemplate(w, "table", table))
+		})
+	}
+}
+
+var htmlTemplates = template.Must(template.New("report").Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font: 14px sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 2px 8px; text-align: left; border-bottom: 1px solid #ddd; }
+td.number { text-align: right; }
+section { margin-top: 2em; }
+dt { float: left; clear: left; width: 11em; color: #666; }
+dd { margin-left: 12em; }
+rect { fill: #4a7ab5; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "index"}}{{template "head" "dataprofiling report"}}
+<h1>Tables</h1>
+<table>
+<tr><th>table</th><th>rows</th><th>columns</th><th>references</th><th>referenced by</th></tr>
+{{range .}}<tr><td><a href="{{.Page}}">{{.Name}}</a></td><td class="number">{{.Rows}}</td><td class="number">{{len .Columns}}</td><td class="number">{{.References}}</td><td class="number">{{.ReferencedBy}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+{{end}}
+
+{{define "table"}}{{template "head" .Name}}
+<p><a href="index.html">all tables</a></p>
+<h1>{{.Name}}</h1>
+<p>{{.Rows}} rows{{with .SampledFrom}}, sampled from {{.}}: statistics and dependencies are approximate{{end}}</p>
+<table>
+<tr><th>column</th><th>type</th><th>distinct</th><th>nulls</th><th>minimum</th><th>maximum</th></tr>
+{{range .Columns}}<tr><td><a href="#{{.Anchor}}">{{.Result.Name}}</a></td><td>{{.Result.DataType}}</td><td class="number">{{.Result.DistinctValues}}</td><td class="number">{{.Result.Nulls}}</td><td>{{with .Result.Minimum}}{{.}}{{end}}</td><td>{{with .Result.Maximum}}{{.}}{{end}}</td></tr>
+{{end}}</table>
+{{if .Keys}}<h2>Keys</h2>
+<ul>{{range .Keys}}<li>{{.}}</li>{{end}}</ul>
+{{end}}{{if .Dependencies}}<h2>Functional dependencies</h2>
+<ul>{{range .Dependencies}}<li>{{.}}</li>{{end}}</ul>
+{{end}}{{if .OrderDependencies}}<h2>Order dependencies</h2>
+<ul>{{range .OrderDependencies}}<li>{{.}}</li>{{end}}</ul>
+{{end}}{{range .Columns}}<section id="{{.Anchor}}">
+<h2>{{.Result.Name}}{{with .Result.Alias}} ({{.}}){{end}}</h2>
+<dl>
+<dt>type</dt><dd>{{.Result.DataType}}</dd>
+<dt>distinct values</dt><dd>{{.Result.DistinctValues}} (estimated {{.Result.EstimatedDistinct}})</dd>
+<dt>nulls</dt><dd>{{.Result.Nulls}}</dd>
+{{with .Result.Mistyped}}<dt>values not of the type</dt><dd>{{.}}</dd>{{end}}
+{{with .Result.Minimum}}<dt>minimum</dt><dd>{{.}}</dd>{{end}}
+{{with .Result.Maximum}}<dt>maximum</dt><dd>{{.}}</dd>{{end}}
+{{with .Result.Average}}<dt>average</dt><dd>{{.}}</dd>{{end}}
+{{with .Result.AverageLength}}<dt>average length</dt><dd>{{.}}</dd>{{end}}
+{{with .Quartiles}}<dt>p25 / p50 / p75</dt><dd>{{.}}</dd>{{end}}
+{{with .Result.Patterns}}<dt>patterns</dt><dd>{{range $i, $p := .}}{{if $i}}, {{end}}<code>{{$p.Pattern}}</code> ({{$p.Count}}){{end}}</dd>{{end}}
+{{with .Result.PatternClusters}}<dt>pattern clusters</dt><dd>{{range $i, $p := .}}{{if $i}}, {{end}}<code>{{$p.Pattern}}</code> ({{$p.Count}}){{end}}</dd>{{end}}
+{{with .Result.PII}}<dt>personal data</dt><dd>{{.}}</dd>{{end}}
+{{with .Examples}}<dt>examples</dt><dd>{{range $i, $e := .}}{{if $i}}, {{end}}<code>{{$e}}</code>{{end}}</dd>{{end}}
+</dl>
+{{with .Bars}}<svg width="300" height="90" viewBox="0 0 100 30" preserveAspectRatio="none">{{range .}}<rect x="{{.X}}" y="{{.Y}}" width="{{.Width}}" height="{{.Height}}"><title>{{.Title}}</title></rect>{{end}}</svg>{{end}}
+{{if .References}}<h3>included in</h3>
+<ul>{{range .References}}<li><a href="{{.Href}}">{{.Label}}</a>{{with .Coverage}} ({{.}} covered){{end}}</li>{{end}}</ul>
+{{end}}{{if .ReferencedBy}}<h3>including</h3>
+<ul>{{range .ReferencedBy}}<li><a href="{{.Href}}">{{.Label}}</a>{{with .Coverage}} ({{.}} covered){{end}}</li>{{end}}</ul>
+{{end}}</section>
+{{end}}</body>
+</html>
+{{end}}
+`))
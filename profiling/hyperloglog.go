@@ -0,0 +1,68 @@
+package profiling
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// HyperLogLog registers of the statistics, 2^12 of them estimate distinct
+// counts with a standard error of about 1.6%
+const hyperLogLogPrecision = 12
+
+// hyperLogLog estimates the number of distinct values added to it: each
+// value's hash picks a register by its first bits, which keeps the largest
+// number of leading zeros seen in the remaining bits.
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func (this *hyperLogLog) Add(value string) {
+	if this.registers == nil {
+		this.registers = make([]uint8, 1<<hyperLogLogPrecision)
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	x := SplitMix64(hash.Sum64())
+	register := x >> (64 - hyperLogLogPrecision)
+	if zeros := uint8(bits.LeadingZeros64(x<<hyperLogLogPrecision|1<<(hyperLogLogPrecision-1))) + 1; zeros > this.registers[register] {
+		this.registers[register] = zeros
+	}
+}
+
+// Estimate returns the estimated number of distinct values, counting the
+// empty registers instead for small sets.
+func (this *hyperLogLog) Estimate() int {
+	if this.registers == nil {
+		return 0
+	}
+	m := float64(len(this.registers))
+	sum, empty := 0.0, 0
+	for _, register := range this.registers {
+		sum += math.Ldexp(1, -int(register))
+		if register == 0 {
+			empty++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && empty > 0 {
+		estimate = m * math.Log(m/float64(empty))
+	}
+	return int(math.Round(estimate))
+}
+
+// MayBeIncludedIn tells whether the values added to this sketch may all have
+// been added to the other one. A register of a subset's sketch is never
+// larger than the superset's, so unlike comparing the estimates this never
+// rules out a real inclusion.
+func (this *hyperLogLog) MayBeIncludedIn(other *hyperLogLog) bool {
+	if this.registers == nil || other.registers == nil {
+		return true
+	}
+	for i, register := range this.registers {
+		if register > other.registers[i] {
+			return false
+		}
+	}
+	return true
+}
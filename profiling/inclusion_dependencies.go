@@ -0,0 +1,2382 @@
+package profiling
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/willf/bitset"
+	"hash/crc64"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+func IsCompressedFile(path string) bool {
+	return strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".zst")
+}
+
+// NewLineReader opens a file for reading, decompressing files ending in .gz
+// or .zst on the fly. Bytes read are counted before decompression. A byte
+// order mark at the start is dropped, and files it marks as UTF-16 are
+// transcoded to UTF-8.
+func NewLineReader(fileName string) (reader *bufio.Reader) {
+	file, err := os.Open(fileName)
+	check(err)
+	var input io.Reader = countingReader{file}
+	switch {
+	case strings.HasSuffix(fileName, ".gz"):
+		input, err = gzip.NewReader(input)
+		check(err)
+	case strings.HasSuffix(fileName, ".zst"):
+		// a single decoder decodes in the reading goroutine, starting none
+		// that would outlive the reader
+		decoder, err := zstd.NewReader(input, zstd.WithDecoderConcurrency(1))
+		check(err)
+		input = decoder
+	}
+	reader = bufio.NewReader(input)
+	mark, _ := reader.Peek(3)
+	switch {
+	case bytes.HasPrefix(mark, []byte{0xef, 0xbb, 0xbf}):
+		reader.Discard(3)
+	case bytes.HasPrefix(mark, []byte{0xff, 0xfe}):
+		reader.Discard(2)
+		return bufio.NewReader(&utf16Reader{reader: reader, order: binary.LittleEndian})
+	case bytes.HasPrefix(mark, []byte{0xfe, 0xff}):
+		reader.Discard(2)
+		return bufio.NewReader(&utf16Reader{reader: reader, order: binary.BigEndian})
+	}
+	return reader
+}
+
+func ReadRow(reader *bufio.Reader) (fields []string) {
+	line, err := reader.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return
+	}
+	if err != io.EOF {
+		check(err)
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	return strings.Split(line, "\t")
+}
+
+type Options struct {
+	flags              *flag.FlagSet
+	arguments          []string
+	dataDir            string
+	filterBits         uint
+	filterHashes       uint
+	targetFPP          float64
+	filterPerColumn    bool
+	candidateIndex     string
+	candidatePruning   string
+	minNameSimilarity  float64
+	lshRows            int
+	checkpointDir      string
+	checkpointInterval time.Duration
+	resume             string
+	threads            int
+	workers            int
+	analysisWorkers    int
+	validationWorkers  int
+	columns            string
+	seed               int64
+	profileCache       string
+	quiet              bool
+	verbose            bool
+	typesFile          string
+	typeSample         int
+	typeAgreement      float64
+	sampleRows         int
+	sampleRate         float64
+	incremental        bool
+	raggedRows         string
+	partitions         string
+	catalogFile        string
+	redact             string
+	columnConfigFile   string
+	aliasesFile        string
+	glossaryFile       string
+	header             bool
+	format             string
+	force              bool
+	metanomeInput      string
+	metanomeResults    string
+	dbtFile            string
+	schemaSpyFile      string
+	gephiDir           string
+	htmlDir            string
+	dotFile            string
+	foreignKeys        int
+	featuresFile       string
+	geoInclusions      bool
+	relations          bool
+	prefixInclusions   bool
+	maxArity           int
+	keys               bool
+	maxKeySize         int
+	dependencies       bool
+	maxDeterminant     int
+	orderDependencies  bool
+	minCoverage        float64
+	tokenInclusions    bool
+	tokenDelimiters    string
+	relationTolerance  float64
+	validator          string
+	spillDir           string
+	maxMemory          string
+	budget             *MemoryBudget
+	nullTokens         string
+	nulls              string
+	output             string
+	spillValues        int
+	scheduler          string
+	expectationsDir    string
+	rulesFile          string
+	checkRulesFile     string
+	brokers            string
+	topics             string
+	messages           int
+	schemaRegistry     string
+	kafkaTimeout       time.Duration
+	driver             string
+	dsn                string
+	schemas            string
+	tables             string
+	jsonSchemaDir      string
+	sqlFile            string
+	sqlDialect         string
+	sqlMinScore        float64
+	parquetDir         string
+	started            time.Time
+	statusFile         string
+	serve              string
+	tui                bool
+	failFast           bool
+	include            string
+	exclude            string
+	// the tables skipped because of errors
+	failures *Failures
+	// the exit status of a command whose checks failed
+	exitCode int
+	// the filter of -include and -exclude, nil without them
+	nameFilter *NameFilter
+	// the -tui dashboard, nil without one
+	dashboard          *Dashboard
+	outputDir          string
+	baselineDir        string
+	fingerprintsFile   string
+	edgesDir           string
+	artifactFile       string
+	signKey            string
+	publicKey          string
+	edgesChunk         int
+	edgesTables        string
+	edgesMinScore      float64
+	knownFingerprints  string
+	duplicateColumns   bool
+	duplicateThreshold float64
+	driftThresholds    string
+	rows               int
+	manifestFile       string
+}
+
+func (this *Options) Register(flags *flag.FlagSet) {
+	flags.StringVar(&this.dataDir, "data-dir", "", "data directory holding mapping.tsv, instead of the first argument of commands taking one")
+	flags.UintVar(&this.filterBits, "filter-bits", 1000000, "number of bits in each column's bloom filter")
+	flags.UintVar(&this.filterHashes, "filter-hashes", 4, "number of hash functions used by string bloom filters")
+	flags.Float64Var(&this.targetFPP, "target-fpp", 0, "size bloom filters for this false-positive rate, overriding -filter-bits and -filter-hashes")
+	flags.StringVar(&this.candidatePruning, "candidate-pruning", "exhaustive", "exhaustive validates every candidate the statistics and bloom filters allow, heuristic also drops those whose names are less similar than -min-name-similarity or whose -catalog types are incompatible, which may miss inclusions")
+	flags.Float64Var(&this.minNameSimilarity, "min-name-similarity", 0.5, "name similarity candidates need with -candidate-pruning heuristic, see -foreign-keys; unnamed columns are kept")
+	flags.StringVar(&this.candidateIndex, "candidate-index", "all", "which column pairs are compared for candidates: all, or lsh only those sharing a band of their MinHash signatures, which is much faster with thousands of columns but may miss inclusions in columns of many more values")
+	flags.IntVar(&this.lshRows, "lsh-rows", 1, "hashes per band of -candidate-index lsh, more find fewer candidates and miss more inclusions")
+	flags.BoolVar(&this.filterPerColumn, "filter-per-column", false, "size each column's bloom filter for its own distinct values and -target-fpp (default 0.01) instead of the largest column's")
+	flags.StringVar(&this.metanomeInput, "metanome-input", "", "read the tables from a Metanome file input configuration (JSON) instead of mapping.tsv")
+	flags.StringVar(&this.metanomeResults, "metanome-results", "", "write the inclusions, keys and functional dependencies to this file in Metanome's JSON result format")
+	flags.BoolVar(&this.header, "header", false, "data files start with a row of column names, which is not profiled")
+	flags.StringVar(&this.format, "format", "tsv", "format of data files not naming their own in mapping.tsv (file@format): tsv, csv with RFC 4180 quoting, optionally followed by :<delimiter>[<quote>] as in csv:; or csv:;', where two different characters are always a delimiter and a quote, or :<delimiter>:<quote> for delimiters of several characters as in csv:||:' or csv:<>:\", or fixed:<width>,<width>... for fixed width fields")
+	flags.StringVar(&this.partitions, "partitions", "", "only profile the partitions matching these comma separated key=value pairs, naming a key several times selects each value")
+	flags.StringVar(&this.catalogFile, "catalog", "", "file of declared primary keys, foreign keys, comments and types exported from the source database's information_schema")
+	flags.StringVar(&this.aliasesFile, "aliases", "", "file of table.column<TAB>business name lines, naming columns in reports and exports")
+	flags.StringVar(&this.glossaryFile, "glossary", "", "file of concept<TAB>name regex[<TAB>value regex] lines tagging columns with business concepts")
+	flags.StringVar(&this.include, "include", "", "comma separated patterns like sales.*, orders or orders.customer_id, or regular expressions in slashes like /^crm\\./, naming the tables and columns to profile (default all)")
+	flags.StringVar(&this.exclude, "exclude", "", "comma separated patterns like -include naming tables and columns not to profile")
+	flags.StringVar(&this.columnConfigFile, "column-config", "", "file of table.column<TAB>settings lines overriding null tokens (null=NA,-), trimming (trim), case (lower, upper), type (type=string) or excluding a column (exclude)")
+	flags.StringVar(&this.output, "output", "text", "text prints statistics and inclusions as text, json writes them as one JSON document to stdout and everything else to stderr")
+	flags.StringVar(&this.nullTokens, "null-tokens", "", "comma separated values meaning null in every column, e.g. \\N,NULL, which are profiled as empty values")
+	flags.StringVar(&this.nulls, "nulls", "value", "how empty values take part in inclusions: value requires them in the referenced column like any other value, ignore leaves them out of the dependent column as SQL foreign keys do")
+	flags.StringVar(&this.typesFile, "types", "", "file of table.column<TAB>type lines forcing a column's type (int, float, bool, date, uuid or string), as column:type in mapping.tsv does")
+	flags.IntVar(&this.typeSample, "type-sample", 1000, "number of first rows whose values vote for the type of each column")
+	flags.Float64Var(&this.typeAgreement, "type-agreement", 1, "share of the sampled non-empty values a type must admit for a column to get it, below 1 values of other types are tolerated")
+	flags.StringVar(&this.raggedRows, "ragged-rows", "error", "how rows with fewer or more fields than mapped columns are handled: error stops at the first, skip leaves them out, pad fills missing fields with empty values and drops extra ones; skipped and padded rows are summarized per file")
+	flags.IntVar(&this.sampleRows, "sample-rows", 0, "profile a uniform sample of this many rows of each table, drawn by reservoir sampling and held in memory, for quick approximate statistics and inclusions")
+	flags.Float64Var(&this.sampleRate, "sample-rate", 0, "profile each row of each table with this probability, a Bernoulli sample, for quick approximate statistics and inclusions")
+	flags.StringVar(&this.dbtFile, "dbt", "", "write foreign key like inclusions as dbt relationships tests to this schema.yml file")
+	flags.StringVar(&this.schemaSpyFile, "schemaspy", "", "write foreign key like inclusions to this SchemaSpy meta XML file, for use with schemaspy -meta")
+	flags.IntVar(&this.foreignKeys, "foreign-keys", 0, "print this many inclusions ranked by how likely they are foreign keys, scored by the uniqueness of the referenced column, name similarity, cardinality and coverage")
+	flags.StringVar(&this.dotFile, "dot", "", "write the inclusions as a Graphviz graph, with a cluster per table, to this file")
+	flags.StringVar(&this.gephiDir, "gephi", "", "write the inclusions as Gephi node and edge CSV lists to this directory")
+	flags.StringVar(&this.htmlDir, "html", "", "write a browsable report with a page per table, describing its columns and their inclusions, to this directory")
+	flags.StringVar(&this.edgesDir, "edges", "", "stream the inclusions as chunked edges-<n>.tsv files to this directory, for graphs too large for other exports")
+	flags.IntVar(&this.edgesChunk, "edges-chunk", 1000000, "maximum number of inclusions per -edges file")
+	flags.StringVar(&this.edgesTables, "edges-tables", "", "comma separated tables whose inclusions -edges writes (default all)")
+	flags.Float64Var(&this.edgesMinScore, "edges-min-score", 0, "minimum share of the referenced column's distinct values an inclusion written by -edges covers")
+	flags.StringVar(&this.expectationsDir, "great-expectations", "", "write a Great Expectations suite per table to this directory")
+	flags.StringVar(&this.rulesFile, "rules", "", "write not null, unique, range, value set, foreign key and derived column rules as JSON to this file")
+	flags.StringVar(&this.checkRulesFile, "check-rules", "", "check the data against the not_null, unique, range, in_set, regex and foreign_key rules of this JSON file, in the format of -rules, and report the rows violating them")
+	flags.StringVar(&this.jsonSchemaDir, "json-schema", "", "write a JSON Schema per table to this directory")
+	flags.StringVar(&this.fingerprintsFile, "fingerprints", "", "write a content fingerprint per column to this file, for finding its duplicates in other datasets with -known-fingerprints")
+	flags.BoolVar(&this.duplicateColumns, "duplicate-columns", false, "report columns of different tables with near-identical content, like copied reference data")
+	flags.StringVar(&this.knownFingerprints, "known-fingerprints", "", "comma separated -fingerprints files of other datasets searched for duplicates as well (implies -duplicate-columns)")
+	flags.Float64Var(&this.duplicateThreshold, "duplicate-threshold", 0.9, "estimated share of shared distinct values from which -duplicate-columns reports two columns")
+	flags.StringVar(&this.featuresFile, "features", "", "write a numeric feature vector per column, e.g. for schema matching models, to this CSV file")
+	flags.StringVar(&this.sqlFile, "sql", "", "write suggested PRIMARY KEY, UNIQUE, CHECK and FOREIGN KEY constraints to this SQL file, with composite keys if -keys and -max-arity find them")
+	flags.StringVar(&this.sqlDialect, "sql-dialect", "postgres", "SQL dialect of -sql: postgres, mysql, sqlserver or sqlite")
+	flags.Float64Var(&this.sqlMinScore, "sql-min-score", 0, "leave foreign keys scoring less than this, see -foreign-keys, out of -sql")
+	flags.StringVar(&this.parquetDir, "parquet", "", "write column statistics and inclusions as Parquet files to this directory")
+	flags.StringVar(&this.redact, "redact", "", "keep values out of all outputs: hash replaces them by a hash, mask by their shape (Xxx 99)")
+	flags.StringVar(&this.statusFile, "status-file", "", "periodically write the run's phase and progress as JSON to this file")
+	flags.StringVar(&this.serve, "serve", "", "serve a live view of the inclusions found during validation on this address, e.g. localhost:8080")
+	flags.BoolVar(&this.tui, "tui", false, "show the progress of discover on a terminal dashboard, and browse the tables, column profiles and inclusions once it is done")
+	flags.StringVar(&this.artifactFile, "artifact", "", "write a verifiable description of the run, with checksums of its inputs and outputs, its configuration and results, to this JSON file")
+	flags.StringVar(&this.signKey, "sign-key", "", "sign the -artifact with this Ed25519 private key (PEM), writing the signature to <artifact>.sig")
+	flags.StringVar(&this.manifestFile, "manifest", "", "write the run's final state, results and output files as JSON to this file")
+	flags.StringVar(&this.checkpointDir, "checkpoint-dir", "", "save table profiles and validation progress to this directory")
+	flags.DurationVar(&this.checkpointInterval, "checkpoint-interval", time.Minute, "how often the validation progress is saved to -checkpoint-dir, it is saved on interrupts too")
+	flags.BoolVar(&this.incremental, "incremental", false, "with -profile-cache, update the cached profiles of tables whose files only had rows or partitions appended by reading the new rows alone, and reuse the previous run's validation results for columns that gained no values")
+	flags.StringVar(&this.profileCache, "profile-cache", "", "keep the table profiles in this directory and reuse them while a table's files and settings are unchanged, so discovery reruns with other thresholds without reading the data again")
+	flags.StringVar(&this.resume, "resume", "", "continue an interrupted run from this checkpoint directory")
+	flags.StringVar(&this.baselineDir, "baseline", "", "report how the column profiles drifted from those in this checkpoint directory of an earlier run")
+	flags.StringVar(&this.driftThresholds, "drift-thresholds", "", "comma separated metric=threshold pairs above which -baseline and compare flag a column (default range=0.1,cardinality=0.2,nulls=0.05,distribution=0.3)")
+	flags.BoolVar(&this.geoInclusions, "geo-inclusions", false, "search columns of coordinates for inclusions as well")
+	flags.BoolVar(&this.relations, "relations", false, "find numeric columns derived from others as a sum, product or copy, which takes another pass over the data")
+	flags.Float64Var(&this.relationTolerance, "relation-tolerance", 1e-6, "relative difference up to which values of -relations count as equal")
+	flags.Float64Var(&this.minCoverage, "min-coverage", 1, "accept inclusions whose referenced column contains at least this share of the dependent column's distinct values, reporting their coverage")
+	flags.BoolVar(&this.keys, "keys", false, "find the minimal combinations of columns unique in every row of their table, the candidate keys")
+	flags.IntVar(&this.maxKeySize, "max-key-size", 3, "maximum number of columns of a combination searched by -keys")
+	flags.BoolVar(&this.dependencies, "functional-dependencies", false, "find the minimal functional dependencies X → A between the columns of each table")
+	flags.IntVar(&this.maxDeterminant, "max-determinant", 3, "maximum number of columns on the left side of a dependency found by -functional-dependencies")
+	flags.BoolVar(&this.orderDependencies, "order-dependencies", false, "find the order dependencies A ↦ B between the columns of each table, where sorting by A also sorts by B")
+	flags.IntVar(&this.maxArity, "max-arity", 1, "also search inclusions of combinations of up to this many columns, like composite foreign keys")
+	flags.BoolVar(&this.prefixInclusions, "prefix-inclusions", false, "also report string columns whose values are all prefixes of another column's values, like codes of a hierarchy")
+	flags.BoolVar(&this.tokenInclusions, "token-inclusions", false, "also report tokens of composite columns, like the parts of 123|456, included in other columns")
+	flags.StringVar(&this.tokenDelimiters, "token-delimiters", "|;,/:#", "characters separating the tokens of composite columns for -token-inclusions, tried in order")
+	flags.StringVar(&this.validator, "validator", "memory", "how candidates are validated: memory compares the analyzed value sets, duckdb runs set differences over the files in an embedded DuckDB (needs a build with -tags duckdb), spider merges sorted value files of all columns in one pass")
+	flags.StringVar(&this.spillDir, "spill-dir", "", "directory for the sorted value files of -validator spider and -max-memory (default the system's temporary directory)")
+	flags.StringVar(&this.maxMemory, "max-memory", "", "bound on the estimated memory of the analyzed value sets, e.g. 8GiB, beyond which the largest are spilled to sorted files on disk (default unbounded)")
+	flags.IntVar(&this.spillValues, "spill-values", 1000000, "distinct values of a column -validator spider holds in memory while analyzing before spilling them to disk")
+	flags.StringVar(&this.scheduler, "scheduler", "most-candidates", "order of validation: most-candidates validates the candidates of the columns with the most candidates first, cost the cheapest candidates promising the most pruning by the transitive closure, names those whose names suggest a foreign key most")
+	flags.IntVar(&this.threads, "threads", runtime.NumCPU(), "number of threads executing simultaneously")
+	flags.IntVar(&this.workers, "workers", 0, "number of tables analyzed and of columns compared concurrently, bounding the files open at once (default -threads)")
+	flags.IntVar(&this.analysisWorkers, "analysis-workers", 0, "number of tables analyzed concurrently (default -workers)")
+	flags.IntVar(&this.validationWorkers, "validation-workers", 0, "number of columns compared concurrently while building candidates and candidates validated at once (default -workers)")
+	flags.BoolVar(&this.failFast, "fail-fast", false, "end the run at the first table that cannot be read rather than skipping it and exiting with status 1 after profiling the others")
+	flags.BoolVar(&this.quiet, "quiet", false, "print only results and errors, no messages or progress on stderr")
+	flags.BoolVar(&this.verbose, "verbose", false, "also print what each phase is doing on stderr")
+	flags.Int64Var(&this.seed, "seed", 0, "seed for all random sampling, making approximate runs reproducible (default random)")
+}
+
+// ParseOptions parses the command line of a command, printing the command's
+// usage and exiting if it is wrong.
+func ParseOptions(command *Command, arguments []string) (options *Options) {
+	options, err := parseOptions(command, arguments, flag.ExitOnError)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		command.PrintUsage(command.NewFlagSet(new(Options), flag.ExitOnError))
+		os.Exit(2)
+	}
+	return options
+}
+
+// NewFlagSet registers the flags of the command, storing their values in
+// options.
+func (this *Command) NewFlagSet(options *Options, handling flag.ErrorHandling) (flags *flag.FlagSet) {
+	flags = flag.NewFlagSet("dataprofiling "+this.name, handling)
+	if handling == flag.ContinueOnError {
+		flags.SetOutput(io.Discard)
+	} else {
+		flags.Usage = func() { this.PrintUsage(flags) }
+	}
+	options.Register(flags)
+	if this.flags != nil {
+		this.flags(options, flags)
+	}
+	return flags
+}
+
+// PrintUsage describes the command and its flags and lists all commands.
+func (this *Command) PrintUsage(flags *flag.FlagSet) {
+	w := flags.Output()
+	fmt.Fprintf(w, "usage: %v\n\n%v\n\ncommands:\n", strings.TrimSpace("dataprofiling "+this.name+" [flags] "+this.usage), this.description)
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, command := range commands {
+		fmt.Fprintf(table, "  %v %v\t%v\n", command.name, command.usage, command.description)
+	}
+	table.Flush()
+	fmt.Fprintln(w, "\nflags:")
+	flags.PrintDefaults()
+}
+
+// CheckArguments tells whether the positional arguments match the command's
+// usage, in which optional ones are enclosed in brackets.
+func (this *Command) CheckArguments(arguments []string) error {
+	required, allowed := 0, 0
+	for _, argument := range strings.Fields(this.usage) {
+		if !strings.HasPrefix(argument, "[") {
+			required++
+		}
+		allowed++
+	}
+	if len(arguments) < required || len(arguments) > allowed {
+		if allowed == 0 {
+			return fmt.Errorf("%v takes no arguments", this.name)
+		}
+		return fmt.Errorf("%v expects %v, got %v arguments", this.name, this.usage, len(arguments))
+	}
+	return nil
+}
+
+func parseOptions(command *Command, arguments []string, handling flag.ErrorHandling) (options *Options, err error) {
+	options = &Options{started: time.Now()}
+	options.flags = command.NewFlagSet(options, handling)
+	// allow flags after positional arguments, e.g. "table file.tsv -columns a,b"
+	for len(arguments) > 0 {
+		if err = options.flags.Parse(arguments); err != nil {
+			return nil, err
+		}
+		arguments = options.flags.Args()
+		if len(arguments) > 0 {
+			options.arguments = append(options.arguments, arguments[0])
+			arguments = arguments[1:]
+		}
+	}
+	if options.checkpointDir == "" {
+		options.checkpointDir = options.resume
+	}
+	if options.seed == 0 {
+		options.seed = time.Now().UnixNano()
+	}
+	if options.threads < 1 {
+		options.threads = 1
+	}
+	if options.workers < 1 {
+		options.workers = options.threads
+	}
+	if options.analysisWorkers < 1 {
+		options.analysisWorkers = options.workers
+	}
+	if options.validationWorkers < 1 {
+		options.validationWorkers = options.workers
+	}
+	for _, choice := range []struct {
+		flag    string
+		value   string
+		allowed []string
+	}{
+		{"candidate-index", options.candidateIndex, []string{"all", "lsh"}},
+		{"candidate-pruning", options.candidatePruning, []string{"exhaustive", "heuristic"}},
+		{"nulls", options.nulls, []string{"value", "ignore"}},
+		{"validator", options.validator, []string{"memory", "duckdb", "spider"}},
+		{"scheduler", options.scheduler, []string{"most-candidates", "cost", "names"}},
+		{"output", options.output, []string{"text", "json"}},
+	} {
+		known := false
+		for _, allowed := range choice.allowed {
+			known = known || choice.value == allowed
+		}
+		if !known {
+			last := len(choice.allowed) - 1
+			return nil, fmt.Errorf("unknown -%v %v, use %v or %v", choice.flag, choice.value, strings.Join(choice.allowed[:last], ", "), choice.allowed[last])
+		}
+	}
+	if options.maxMemory != "" {
+		limit, err := ParseByteSize(options.maxMemory)
+		if err != nil {
+			return nil, fmt.Errorf("-max-memory: %v", err)
+		}
+		options.budget = NewMemoryBudget(limit, options.spillDir)
+	}
+	if options.validator == "spider" {
+		if options.spillValues < 1 {
+			return nil, fmt.Errorf("-spill-values needs room for at least one value")
+		}
+		if options.budget == nil {
+			options.budget = &MemoryBudget{limit: math.MaxInt64, parent: options.spillDir}
+		}
+		options.budget.chunkSize = options.spillValues
+	}
+	if options.incremental && options.profileCache == "" {
+		return nil, fmt.Errorf("-incremental needs -profile-cache")
+	}
+	switch options.raggedRows {
+	case "error", "skip", "pad":
+	default:
+		return nil, fmt.Errorf("unknown -ragged-rows %v, use error, skip or pad", options.raggedRows)
+	}
+	if options.sampleRows > 0 && options.sampleRate > 0 {
+		return nil, fmt.Errorf("-sample-rows and -sample-rate exclude each other")
+	}
+	if options.sampleRate < 0 || options.sampleRate > 1 {
+		return nil, fmt.Errorf("-sample-rate must be between 0 and 1")
+	}
+	if (options.sampleRows > 0 || options.sampleRate > 0) && options.validator == "duckdb" {
+		return nil, fmt.Errorf("-validator duckdb reads whole files and cannot validate a sample")
+	}
+	if options.redact != "" && options.redact != "hash" && options.redact != "mask" {
+		return nil, fmt.Errorf("unknown -redact %v, use hash or mask", options.redact)
+	}
+	if options.quiet && options.verbose {
+		return nil, fmt.Errorf("-quiet and -verbose exclude each other")
+	}
+	if options.tui && options.JSONOutput() {
+		return nil, fmt.Errorf("-tui shows text results, not -output %v", options.output)
+	}
+	options.failures = &Failures{failFast: options.failFast}
+	if options.nameFilter, err = ParseNameFilter(options.include, options.exclude); err != nil {
+		return nil, err
+	}
+	if options.dataDir != "" {
+		if !strings.HasPrefix(command.usage, "<data-dir>") {
+			return nil, fmt.Errorf("%v takes no data directory", command.name)
+		}
+		options.arguments = append([]string{options.dataDir}, options.arguments...)
+	}
+	if err = command.CheckArguments(options.arguments); err != nil {
+		return nil, err
+	}
+	if command.name == "generate" && options.outputDir == "" {
+		return nil, fmt.Errorf("generate needs an output directory, use -out")
+	}
+	if strings.HasPrefix(command.usage, "<data-dir>") {
+		options.dataDir = DataDirPath(options.arguments[0])
+	}
+	return options, nil
+}
+
+// Apply sets the logging level and the redaction of values, which the runs in
+// a process share.
+func (this *Options) Apply() {
+	logger.SetLevel(this.quiet, this.verbose)
+	SetRedaction(this.redact)
+}
+
+// DataDirPath returns the data directory given on the command line ending in
+// a slash.
+func DataDirPath(dataDir string) string {
+	if !strings.HasSuffix(dataDir, "/") {
+		dataDir += "/"
+	}
+	return dataDir
+}
+
+// NewRandom returns a generator for one consumer of randomness, e.g. a table.
+// Each stream gets its own generator derived from -seed, so results do not
+// depend on the order in which concurrent workers draw numbers.
+func (this *Options) NewRandom(stream string) *rand.Rand {
+	hash := fnv.New64a()
+	hash.Write([]byte(stream))
+	return rand.New(rand.NewSource(this.seed ^ int64(hash.Sum64())))
+}
+
+// FilterSize returns the bloom filter bits and hash count to use for columns
+// holding at most distinctValues values. All filters share one size, because
+// SimiliarTo compares their bitsets directly.
+func (this *Options) FilterSize(distinctValues int) (bits uint, hashes uint) {
+	if this.targetFPP <= 0 || this.targetFPP >= 1 {
+		return this.filterBits, this.filterHashes
+	}
+	n := math.Max(float64(distinctValues), 1)
+	m := math.Ceil(-n * math.Log(this.targetFPP) / (math.Ln2 * math.Ln2))
+	k := math.Max(math.Round(m/n*math.Ln2), 1)
+	return uint(m), uint(k)
+}
+
+// ColumnFilterSize sizes the bloom filter of a column of distinctValues
+// values for -filter-per-column. The bits are rounded up to a power of two,
+// so that larger filters fold to the size of smaller ones, and all filters
+// use the same number of hashes.
+func (this *Options) ColumnFilterSize(distinctValues int) (bits uint, hashes uint) {
+	fpp := this.targetFPP
+	if fpp <= 0 || fpp >= 1 {
+		fpp = 0.01
+	}
+	n := math.Max(float64(distinctValues), 1)
+	m := -n * math.Log(fpp) / (math.Ln2 * math.Ln2)
+	bits = 64
+	for float64(bits) < m {
+		bits *= 2
+	}
+	return bits, uint(math.Max(math.Round(-math.Log2(fpp)), 1))
+}
+
+type Database []*Table
+
+type Table struct {
+	columns   []*Column
+	path      string
+	schema    string
+	name      string
+	id        string
+	hasHeader bool
+	format    *FileFormat
+	rowCount  int
+	// number of fields per row, including those of excluded columns
+	fields int
+	// arithmetic relations between the numeric columns
+	relations []*Relation
+	// the files of a directory of partitions, nil for a single file table,
+	// and the number of partition keys whose values are added to their rows
+	partitions    []*Partition
+	partitionKeys int
+	// minimal unique column combinations
+	keys [][]*Column
+	// minimal functional dependencies between the columns
+	dependencies []*FunctionalDependency
+	// order dependencies between the columns
+	orders []*OrderDependency
+	// whether the profile was restored by -resume, so the validation progress
+	// of the checkpoint still applies
+	resumed bool
+	// number of first rows whose values vote for the columns' types, and the
+	// share of their votes a type needs
+	typeSample    int
+	typeAgreement float64
+	// bound of -max-memory on the value sets, nil without one
+	budget *MemoryBudget
+	// -ragged-rows, empty when rows are read as they are
+	raggedRows string
+	// stamps of the files of the cached profile the table's profile was
+	// taken from, empty if it was analyzed anew
+	cachedSources string
+	// the rows of -sample-rows or -sample-rate, nil to read all rows, and the
+	// number of rows the sample was drawn from
+	sampling         *Sampling
+	sampledFrom      int
+	reservoirOnce    sync.Once
+	reservoir        [][]string
+	reservoirScanned int
+	// the rules of -check-rules on the table's columns
+	checkers []*ruleChecker
+	// whether reading the table failed, so that it is skipped
+	failed bool
+	// rows added so far and whether the profile is complete, read by the
+	// -tui dashboard while analyzing
+	progress int64
+	analyzed int32
+}
+
+type Column struct {
+	table        *Table
+	id           string
+	index        int
+	field        int
+	name         string
+	dataType     string
+	typeOverride string
+	partition    bool
+	comment      string
+	alias        string
+	config       *ColumnConfig
+	// number of candidates before validation
+	candidateCount int
+	// the kind of personal data the values look like, if any
+	pii           string
+	piiConfidence float64
+	// the dominant natural languages of long text values
+	languages []LanguageShare
+	// coordinates are not searched for inclusions unless asked for
+	geo *GeoProfile
+	// numeric statistics of values with a currency or unit
+	quantity *QuantityProfile
+	// granularity and gaps of date values
+	dates *DateProfile
+	// business concepts of the glossary entries matching the column
+	concepts []string
+	// type declared by the catalog
+	declaredType *DeclaredType
+	primaryKey   bool
+	foreignKeys  map[*Column]bool
+	stats        Statistics
+	filter       BloomFilter
+	// number of bits set in the bloom filter
+	filterBits uint
+	values     map[string]bool
+	// estimated bytes of the values in memory, the sorted runs they were
+	// spilled to during analysis, and the value file merged from them after
+	held    int64
+	runs    []string
+	spilled *valueFile
+	// number of rows with an empty value
+	nulls int
+	// number of non-empty values not of the column's type
+	mistyped   int
+	candidates map[*Column]bool
+	// whether -incremental added values to the cached profile
+	grown bool
+}
+
+type Statistics interface {
+	Print(w io.Writer)
+	Add(s string)
+	FinishAnalysis(rowCount int)
+	// ResumeAnalysis undoes FinishAnalysis, so that more values can be added
+	ResumeAnalysis(rowCount int)
+	SimiliarTo(other Statistics) bool
+	ExampleValues() []string
+	EstimatedDistinct() int
+	DistinctSketch() *hyperLogLog
+	// Histogram describes the distribution of numeric columns' values, nil for
+	// other columns
+	Histogram() *Histogram
+}
+
+type statistics struct {
+	samples     []string
+	initialized bool
+	// sketch of the distinct non-empty values
+	distinct hyperLogLog
+}
+
+func (this *statistics) Sample(s string) {
+	return
+	if !this.initialized {
+		this.samples = make([]string, 0, 10)
+		this.initialized = true
+	}
+	if len(this.samples) < 10 {
+		for _, alreadySampled := range this.samples {
+			if s == alreadySampled {
+				return
+			}
+		}
+		this.samples = append(this.samples, s)
+	}
+}
+
+func (this *statistics) ExampleValues() (result []string) {
+	return this.samples
+}
+
+func (this *statistics) EstimatedDistinct() int {
+	return this.distinct.Estimate()
+}
+
+func (this *statistics) DistinctSketch() *hyperLogLog {
+	return &this.distinct
+}
+
+func (this *statistics) Histogram() *Histogram {
+	return nil
+}
+
+type intStatistics struct {
+	statistics
+	average float64
+	maximum int64
+	minimum int64
+	digest  tDigest
+}
+
+func (this *intStatistics) Print(w io.Writer) {
+	fmt.Fprint(w, "max: ", Redact(fmt.Sprint(this.maximum)), " \t| min: ", Redact(fmt.Sprint(this.minimum)), " \t| avg: ", this.average, " \t| dis: ~", this.EstimatedDistinct())
+	this.Histogram().Print(w)
+}
+
+func (this *intStatistics) Histogram() *Histogram {
+	return this.digest.Histogram()
+}
+
+func (this *intStatistics) Add(s string) {
+	this.Sample(s)
+	this.distinct.Add(s)
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return
+	}
+	if this.minimum > value {
+		this.minimum = value
+	}
+	if this.maximum < value {
+		this.maximum = value
+	}
+	this.average += float64(value)
+	this.digest.Add(float64(value))
+}
+
+func (this *intStatistics) FinishAnalysis(rowCount int) {
+	this.average /= float64(rowCount)
+}
+
+func (this *intStatistics) ResumeAnalysis(rowCount int) {
+	this.average *= float64(rowCount)
+}
+
+func (this *intStatistics) SimiliarTo(s Statistics) bool {
+	other := s.(*intStatistics)
+	return this.minimum >= other.minimum && this.maximum <= other.maximum && this.distinct.MayBeIncludedIn(&other.distinct)
+}
+
+// floatStatistics compares the values as numbers, leaving infinite values
+// and NaN out of the range, the average and the histogram.
+type floatStatistics struct {
+	statistics
+	average float64
+	maximum float64
+	minimum float64
+	digest  tDigest
+}
+
+func (this *floatStatistics) Print(w io.Writer) {
+	fmt.Fprint(w, "max: ", Redact(FormatFloat(this.maximum)), " \t| min: ", Redact(FormatFloat(this.minimum)), " \t| avg: ", this.average, " \t| dis: ~", this.EstimatedDistinct())
+	this.Histogram().Print(w)
+}
+
+func (this *floatStatistics) Histogram() *Histogram {
+	return this.digest.Histogram()
+}
+
+func (this *floatStatistics) Add(s string) {
+	this.Sample(s)
+	this.distinct.Add(s)
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		return
+	}
+	this.minimum = math.Min(this.minimum, value)
+	this.maximum = math.Max(this.maximum, value)
+	this.average += value
+	this.digest.Add(value)
+}
+
+func (this *floatStatistics) FinishAnalysis(rowCount int) {
+	this.average /= float64(rowCount)
+}
+
+func (this *floatStatistics) ResumeAnalysis(rowCount int) {
+	this.average *= float64(rowCount)
+}
+
+func (this *floatStatistics) SimiliarTo(s Statistics) bool {
+	other := s.(*floatStatistics)
+	return this.minimum >= other.minimum && this.maximum <= other.maximum && this.distinct.MayBeIncludedIn(&other.distinct)
+}
+
+type stringStatistics struct {
+	statistics
+	averageLength float64
+	maximum       string
+	minimum       string
+	longest       string
+	shortest      string
+	// number of values per mask, and of those not counted once there are
+	// maxPatterns masks
+	patterns      map[string]int
+	otherPatterns int
+}
+
+func (this *stringStatistics) Print(w io.Writer) {
+	fmt.Fprintln(w, "max:", Redact(this.maximum), "\t| min:", Redact(this.minimum), "\t| lon:", Redact(this.longest), "\t| sho:", Redact(this.shortest), "\t| avg:", this.averageLength, "\t| dis: ~"+fmt.Sprint(this.EstimatedDistinct()),
+		"\t| pat:", this.PatternSummary())
+}
+
+func (this *stringStatistics) Add(value string) {
+	this.Sample(value)
+	this.distinct.Add(value)
+	if this.minimum == "" || this.minimum > value {
+		this.minimum = value
+	}
+	if this.maximum == "" || this.maximum < value {
+		this.maximum = value
+	}
+	if this.longest == "" || len(this.longest) < len(value) {
+		this.longest = value
+	}
+	if this.shortest == "" || len(this.shortest) > len(value) {
+		this.shortest = value
+	}
+	this.averageLength += float64(len(value))
+	this.AddPattern(value)
+}
+
+func (this *stringStatistics) FinishAnalysis(rowCount int) {
+	this.averageLength /= float64(rowCount)
+}
+
+func (this *stringStatistics) ResumeAnalysis(rowCount int) {
+	this.averageLength *= float64(rowCount)
+}
+
+func (this *stringStatistics) SimiliarTo(s Statistics) bool {
+	other := s.(*stringStatistics)
+	return this.minimum >= other.minimum && this.maximum <= other.maximum && len(this.shortest) >= len(other.shortest) && len(this.longest) <= len(other.longest) &&
+		this.distinct.MayBeIncludedIn(&other.distinct)
+}
+
+type BloomFilter interface {
+	Initialize(m uint)
+	Add(s string)
+	Bits() *bitset.BitSet
+	Size() uint
+	Difference(other BloomFilter) *bitset.BitSet
+	SimiliarTo(other BloomFilter) bool
+	Contains(values []string) bool
+}
+
+type bloomFilter struct {
+	bits *bitset.BitSet
+	m    uint
+}
+
+func (this *bloomFilter) Set(index uint) {
+	this.bits = this.bits.Set(index)
+}
+
+func (this *bloomFilter) Initialize(m uint) {
+	this.m = m
+	this.bits = bitset.New(m)
+}
+
+func (this *bloomFilter) Bits() *bitset.BitSet {
+	return this.bits
+}
+
+func (this *bloomFilter) Size() uint {
+	return this.m
+}
+
+// Fold returns the bits of the filter for a size dividing its own, as both
+// filters hash values modulo their size.
+func (this *bloomFilter) Fold(m uint) *bitset.BitSet {
+	if m == this.m {
+		return this.bits
+	}
+	result := bitset.New(m)
+	for i, ok := this.bits.NextSet(0); ok; i, ok = this.bits.NextSet(i + 1) {
+		result.Set(i % m)
+	}
+	return result
+}
+
+// Difference returns the bits set in this filter but not in the other,
+// folding the larger filter of different sizes to the smaller one's.
+func (this *bloomFilter) Difference(other BloomFilter) *bitset.BitSet {
+	if other.Size() == this.m {
+		return this.bits.Difference(other.Bits())
+	}
+	theirs := &bloomFilter{other.Bits(), other.Size()}
+	if this.m < other.Size() {
+		return this.bits.Difference(theirs.Fold(this.m))
+	}
+	return this.Fold(other.Size()).Difference(theirs.bits)
+}
+
+func (this *bloomFilter) SimiliarTo(other BloomFilter) bool {
+	return this.Difference(other).None()
+}
+
+type intBloomFilter struct {
+	bloomFilter
+}
+
+func (this *intBloomFilter) Add(s string) {
+	index := this.Hash(s)
+	this.Set(index)
+}
+
+func (this *intBloomFilter) Contains(values []string) bool {
+	for _, value := range values {
+		index := this.Hash(value)
+		if !this.bits.Test(index) {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *intBloomFilter) Hash(input string) (result uint) {
+	number, err := strconv.ParseInt(input, 10, 64)
+	if err != nil {
+		number = math.MaxInt64
+	}
+	result = uint(number) % this.m
+	return result
+}
+
+// floatBloomFilter hashes the numbers instead of their text, so that values
+// like 1.5 and 1.50 set the same bits.
+type floatBloomFilter struct {
+	bloomFilter
+	k uint
+}
+
+func (this *floatBloomFilter) Add(s string) {
+	for _, index := range this.Hashes(s) {
+		this.Set(index)
+	}
+}
+
+func (this *floatBloomFilter) Contains(values []string) bool {
+	for _, value := range values {
+		for _, index := range this.Hashes(value) {
+			if !this.bits.Test(index) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (this *floatBloomFilter) Hashes(input string) (results []uint) {
+	number, err := strconv.ParseFloat(input, 64)
+	if err != nil {
+		number = math.NaN()
+	}
+	// adding zero turns -0 into 0
+	x := math.Float64bits(number + 0)
+	for i := 0; i < int(this.k); i++ {
+		x = SplitMix64(x)
+		results = append(results, uint(x%uint64(this.m)))
+	}
+	return results
+}
+
+type stringBloomFilter struct {
+	bloomFilter
+	k uint
+}
+
+func (this *stringBloomFilter) Add(s string) {
+	for _, index := range this.Hashes(s) {
+		this.Set(index)
+	}
+}
+
+func (this *stringBloomFilter) Contains(values []string) bool {
+	for _, value := range values {
+		for _, index := range this.Hashes(value) {
+			if !this.bits.Test(index) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// crcTable holds the polynomial of the second hash of string bloom filters.
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// Hashes derives the k indexes of a value by double hashing, h1 + i*h2, from
+// two independent hashes, FNV-1a and CRC-64. h2 is odd, so that a value's
+// indexes differ in filters of a power of two bits.
+func (this *stringBloomFilter) Hashes(input string) (results []uint) {
+	bytes := []byte(input)
+	hash := fnv.New64a()
+	hash.Write(bytes)
+	h1, h2 := hash.Sum64(), crc64.Checksum(bytes, crcTable)|1
+	for i := uint64(0); i < uint64(this.k); i++ {
+		results = append(results, uint((h1+i*h2)%uint64(this.m)))
+	}
+	return results
+}
+
+func ReadTableMapping(dataDir string, format *FileFormat, failures *Failures) (result Database) {
+	mappingFileName := dataDir + "mapping.tsv"
+	lineReader := NewLineReader(mappingFileName)
+	for {
+		fields := ReadRow(lineReader)
+		if len(fields) == 0 {
+			break
+		}
+		failures.Guard(fields[0], "definition", func() {
+			table, err := BuildTable(dataDir, fields, format)
+			check(err)
+			result = append(result, table)
+		})
+	}
+	return result
+}
+
+// BuildTable describes the table of a mapping.tsv line, which is read in the
+// given format unless its file names one, or returns an error if the line
+// names no file.
+func BuildTable(dataDir string, mapping []string, format *FileFormat) (result *Table, err error) {
+	if len(mapping) < 2 {
+		return nil, fmt.Errorf("the mapping of %v names no file", mapping[0])
+	}
+	file, spec := SplitFileFormat(mapping[1])
+	if spec != "" {
+		format, err = ParseFileFormat(spec)
+		check(err)
+	}
+	result = &Table{path: dataDir + file, id: TableId(file), format: format}
+	result.schema, result.name = SplitTableName(mapping[0])
+	if len(mapping) == 2 && IsParquetFile(result.path) {
+		mapping = append(mapping, ParquetColumnNames(result.path)...)
+	}
+	result.BuildColumns(mapping[2:])
+	if IsPartitioned(result.path) {
+		keys, partitions, err := DiscoverPartitions(result.path)
+		check(err)
+		result.partitions, result.partitionKeys = partitions, len(keys)
+		for _, key := range keys {
+			result.columns = append(result.columns, &Column{table: result, name: key, id: fmt.Sprintf("c%03d", result.fields), field: result.fields, partition: true, values: make(map[string]bool)})
+			result.fields++
+		}
+	}
+	return result, nil
+}
+
+// SplitTableName separates the schema from a schema.table name. Tables of
+// different schemas may have the same name.
+func SplitTableName(qualifiedName string) (schema string, name string) {
+	if i := strings.LastIndex(qualifiedName, "."); i >= 0 {
+		return qualifiedName[:i], qualifiedName[i+1:]
+	}
+	return "", qualifiedName
+}
+
+func (this *Table) QualifiedName() string {
+	if this.schema == "" {
+		return this.name
+	}
+	return this.schema + "." + this.name
+}
+
+// TableId derives a table's id from its file name up to the first dot.
+func TableId(file string) string {
+	return strings.Split(strings.TrimSuffix(file, "/"), ".")[0]
+}
+
+func GenerateColumnNames(count int) (result []string) {
+	for i := 0; i < count; i++ {
+		result = append(result, fmt.Sprintf("c%03d", i))
+	}
+	return result
+}
+
+func (this *Table) BuildColumns(columnNames []string) {
+	this.columns = make([]*Column, len(columnNames))
+	for i, name := range columnNames {
+		name, dataType := SplitColumnType(name)
+		this.columns[i] = &Column{table: this, name: name, id: fmt.Sprintf("c%03d", i), field: i, values: make(map[string]bool), typeOverride: dataType}
+	}
+	this.fields = len(columnNames)
+}
+
+// OpenRawRows returns a reader positioned at the table's header row, if it
+// has one, or at its first data row otherwise.
+func (this *Table) OpenRawRows() (rows *RowReader) {
+	if this.partitions != nil {
+		rows = &RowReader{format: this.format, table: this, partition: -1}
+		rows.NextPartition(false)
+		return rows
+	}
+	if IsParquetFile(this.path) {
+		return &RowReader{parquet: OpenParquetRows(this.path)}
+	}
+	rows = &RowReader{reader: NewLineReader(this.path), format: this.format}
+	rows.SkipLines()
+	return rows
+}
+
+// OpenRows returns a reader of the table's data rows, or of its sampled
+// ones with -sample-rows or -sample-rate.
+func (this *Table) OpenRows() (rows *RowReader) {
+	if this.sampling != nil && this.sampling.rows > 0 {
+		sample, scanned := this.Reservoir()
+		return &RowReader{replay: append([][]string{}, sample...), scanned: scanned}
+	}
+	rows = this.OpenAllRows()
+	if this.sampling != nil {
+		rows.random, rows.rate = rand.New(rand.NewSource(this.sampling.seed)), this.sampling.rate
+	}
+	return rows
+}
+
+// OpenAllRows returns a reader positioned at the table's first data row.
+// Parquet files have no header row, nor ragged rows.
+func (this *Table) OpenAllRows() (rows *RowReader) {
+	rows = this.OpenRawRows()
+	if rows.parquet != nil {
+		return rows
+	}
+	if this.hasHeader {
+		rows.Read()
+	}
+	if policy := this.RaggedRows(); policy != "" {
+		path := this.path
+		if this.partitions != nil {
+			path = this.partitions[rows.partition].path
+		}
+		rows.CheckFields(policy, this.DataFields(), path)
+	}
+	return rows
+}
+
+// DataFields returns the number of fields of the rows in the table's files,
+// which lack the values of partition keys.
+func (this *Table) DataFields() int {
+	return this.fields - this.partitionKeys
+}
+
+// RaggedRows returns how rows without a field per mapped column are handled,
+// empty for reading rows as they are.
+func (this *Table) RaggedRows() string {
+	if this.format != nil && this.format.skipDifferingLines {
+		return "skip"
+	}
+	return this.raggedRows
+}
+
+func (this *Table) Analyze() {
+	logger.Debugf("started analyzing %v", this.path)
+	if IsParquetFile(this.path) && this.sampling == nil && this.checkers == nil {
+		this.AnalyzeParquet()
+		this.FinishAnalysis()
+		return
+	}
+	rows := this.OpenRows()
+	this.rowCount = 0
+	for _, column := range this.columns {
+		column.nulls, column.mistyped = 0, 0
+	}
+	for _, checker := range this.checkers {
+		checker.Reset()
+	}
+	// the rows read before the columns' types are inferred from them
+	var sample [][]string
+	sampleSize := 1
+	if this.typeSample > sampleSize {
+		sampleSize = this.typeSample
+	}
+	for {
+		row := rows.Read()
+		if sample != nil || this.rowCount == 0 {
+			if len(row) != 0 {
+				sample = append(sample, append([]string{}, row...))
+			}
+			if len(row) != 0 && len(sample) < sampleSize {
+				continue
+			}
+			this.AnalyzeTypes(sample)
+			for _, sampled := range sample {
+				this.AddRow(sampled)
+			}
+			sample = nil
+			// the row completing the sample was added with it
+			if len(row) != 0 {
+				continue
+			}
+		}
+		if len(row) == 0 {
+			break
+		}
+		this.AddRow(row)
+		if this.rowCount%progressRows == 0 {
+			atomic.StoreInt64(&this.progress, int64(this.rowCount))
+		}
+	}
+	this.sampledFrom = rows.scanned
+	rows.ReportRaggedRows()
+	this.FinishAnalysis()
+}
+
+// FinishAnalysis completes the columns' profiles once all rows are added.
+func (this *Table) FinishAnalysis() {
+	for _, column := range this.columns {
+		column.stats.FinishAnalysis(column.AveragedValues())
+		column.FinishQuantity()
+		column.FinishValues()
+		if column.mistyped > 0 && column.typeOverride != "" {
+			logger.Infof("declared %v as %v although %v of its values are not", column.Name(), column.dataType, column.mistyped)
+		} else if column.mistyped > 0 {
+			logger.Infof("typed %v as %v although %v of its values are not, see -type-sample and -type-agreement", column.Name(), column.dataType, column.mistyped)
+		}
+	}
+	logger.Debugf("finished analyzing %v, %v rows", this.path, this.rowCount)
+}
+
+// AveragedValues returns the number of values the column's averages are
+// taken over, which leave out the empty values.
+func (this *Column) AveragedValues() int {
+	if values := this.table.rowCount - this.nulls; values > 0 {
+		return values
+	}
+	return 1
+}
+
+// AddRow adds a row's values to the columns' profiles.
+func (this *Table) AddRow(row []string) {
+	for _, column := range this.columns {
+		column.Observe(column.Normalize(row[column.field]))
+	}
+	this.rowCount++
+	this.CheckRows(row, this.rowCount)
+}
+
+// Observe adds a normalized value to the column's profile.
+func (this *Column) Observe(value string) {
+	if value != "" {
+		this.stats.Add(value)
+		if this.dataType != "string" && !Admits(this.dataType, value) {
+			this.mistyped++
+		}
+	}
+	if this.quantity != nil {
+		this.quantity.Add(value)
+	}
+	this.AddValue(value)
+	if value == "" {
+		this.nulls++
+	}
+}
+
+// AnalyzeTypes infers the columns' types from the sampled rows.
+func (this *Table) AnalyzeTypes(sample [][]string) {
+	for _, column := range this.columns {
+		var values []string
+		for _, row := range sample {
+			values = append(values, column.Normalize(row[column.field]))
+		}
+		column.StartAnalysis(values, this.typeAgreement)
+	}
+}
+
+// StartAnalysis infers the column's type from its first normalized values.
+func (this *Column) StartAnalysis(values []string, agreement float64) {
+	this.AnalyzeType(values, agreement)
+	if len(values) > 0 {
+		this.StartQuantity(values[0])
+	} else {
+		this.StartQuantity("")
+	}
+}
+
+func IsInt(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+func IsFloat(s string) bool {
+	_, err := strconv.ParseFloat(s, 32)
+	return err == nil
+}
+
+// FormatFloat writes a number with as few digits as parse back to it.
+func FormatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// AnalyzeType infers the column's type from a sample of its values, unless
+// it is overridden.
+func (this *Column) AnalyzeType(sample []string, agreement float64) {
+	if this.typeOverride != "" {
+		this.dataType = this.typeOverride
+	} else {
+		this.dataType = InferType(sample, agreement)
+	}
+	this.stats = NewStatistics(this.dataType)
+}
+
+func NewStatistics(dataType string) Statistics {
+	switch dataType {
+	case "int":
+		return &intStatistics{average: 0.0, maximum: math.MinInt64, minimum: math.MaxInt64}
+	case "float":
+		return &floatStatistics{average: 0.0, maximum: math.Inf(-1), minimum: math.Inf(1)}
+	case "date":
+		return &dateStatistics{}
+	}
+	return &stringStatistics{averageLength: 0.0}
+}
+
+func NewBloomFilter(dataType string, m uint, k uint) (result BloomFilter) {
+	switch dataType {
+	case "int":
+		result = new(intBloomFilter)
+	case "float":
+		result = &floatBloomFilter{k: k}
+	default:
+		result = &stringBloomFilter{k: k}
+	}
+	result.Initialize(m)
+	return result
+}
+
+// BuildFilter adds the column's values to its bloom filter, except the empty
+// one, which would only set a bit hashed from no value at all.
+func (this *Column) BuildFilter(m uint, k uint) {
+	this.filter = NewBloomFilter(this.dataType, m, k)
+	this.EachValue(func(value string) {
+		if value != "" {
+			this.filter.Add(value)
+		}
+	})
+	this.filterBits = this.filter.Bits().Count()
+}
+
+// RunWorkers calls work for every job in 0..jobs-1 from a pool of at most
+// workers goroutines and returns once all jobs are done.
+// A panicking job is re-raised by RunWorkers after the others are done.
+func RunWorkers(workers int, jobs int, work func(job int)) {
+	var wg sync.WaitGroup
+	var once sync.Once
+	var failure interface{}
+	queue := make(chan int)
+	for i := 0; i < workers && i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			for job := range queue {
+				func() {
+					defer func() {
+						if recovered := recover(); recovered != nil {
+							once.Do(func() { failure = recovered })
+						}
+					}()
+					work(job)
+				}()
+				monitor.Advance()
+			}
+			wg.Done()
+		}()
+	}
+	monitor.SetTotal(jobs)
+	for job := 0; job < jobs; job++ {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+	if failure != nil {
+		panic(failure)
+	}
+}
+
+func (db Database) Preprocess(options *Options) {
+	RunWorkers(options.analysisWorkers, len(db), func(i int) {
+		table := db[i]
+		defer func() {
+			atomic.StoreInt64(&table.progress, int64(table.rowCount))
+			atomic.StoreInt32(&table.analyzed, 1)
+		}()
+		table.failed = !options.failures.Guard(table.QualifiedName(), "analysis", func() {
+			// rules are checked on the rows, which profiles do not keep
+			reuse := table.checkers == nil
+			if reuse && options.resume != "" && table.LoadProfile(options.resume) {
+				table.resumed = true
+				return
+			}
+			if reuse && options.profileCache != "" && table.LoadProfile(options.profileCache) {
+				logger.Infof("reusing the cached profile of %v", table.QualifiedName())
+				table.cachedSources = fmt.Sprint(table.Sources())
+				return
+			}
+			if reuse && options.incremental && table.UpdateProfile(options.profileCache) {
+				if options.checkpointDir != "" {
+					table.SaveProfile(options.checkpointDir)
+				}
+				table.SaveProfile(options.profileCache)
+				return
+			}
+			table.Analyze()
+			if options.checkpointDir != "" {
+				table.SaveProfile(options.checkpointDir)
+			}
+			if options.profileCache != "" {
+				table.SaveProfile(options.profileCache)
+			}
+		})
+		if table.failed {
+			table.ReleaseValues()
+		}
+	})
+}
+
+// BuildFilters fills the bloom filters once every column's distinct values
+// are known, so that -target-fpp can size them for the largest column, or
+// each column for itself with -filter-per-column.
+func (db Database) BuildFilters(options *Options) {
+	filterSize := db.FilterSizes(options)
+	RunWorkers(options.analysisWorkers, len(db), func(i int) {
+		for _, column := range db[i].columns {
+			column.BuildFilter(filterSize(column))
+		}
+	})
+}
+
+// FilterSizes returns the bloom filter bits and hashes of each column.
+func (db Database) FilterSizes(options *Options) func(column *Column) (bits uint, hashes uint) {
+	distinctValues := 0
+	for _, column := range db.AllColumns() {
+		if column.DistinctValues() > distinctValues {
+			distinctValues = column.DistinctValues()
+		}
+	}
+	m, k := options.FilterSize(distinctValues)
+	return func(column *Column) (uint, uint) {
+		if options.filterPerColumn {
+			return options.ColumnFilterSize(column.DistinctValues())
+		}
+		return m, k
+	}
+}
+
+// FalsePositiveRate estimates the share of values missing from the column
+// that a bloom filter of m bits and k hashes holding its values lets pass.
+// Integer filters hash only once.
+func (this *Column) FalsePositiveRate(m uint, k uint) float64 {
+	if this.dataType == "int" {
+		k = 1
+	}
+	n := float64(this.DistinctValues())
+	return math.Pow(1-math.Exp(-float64(k)*n/float64(m)), float64(k))
+}
+
+func (db Database) AllColumns() (result []*Column) {
+	for _, table := range db {
+		result = append(result, table.columns...)
+	}
+	return result
+}
+
+func (db Database) BuildCandidates(options *Options) {
+	columns := db.AllColumns()
+	var searched []*Column
+	for _, column := range columns {
+		if column.IsSearched(options) {
+			searched = append(searched, column)
+		}
+	}
+	var index *lshIndex
+	if options.candidateIndex == "lsh" {
+		index = NewLSHIndex(searched, options.lshRows, options.validationWorkers)
+	}
+	compared, pruned, counted := make([]int, len(columns)), make([]int, len(columns)), make([]int, len(columns))
+	RunWorkers(options.validationWorkers, len(columns), func(i int) {
+		if !columns[i].IsSearched(options) {
+			columns[i].candidates = make(map[*Column]bool)
+			return
+		}
+		others := searched
+		if index != nil {
+			others = index.Similar(columns[i], options.minCoverage)
+		}
+		compared[i] = len(others)
+		counted[i] = columns[i].BuildCandidates(others, options.minCoverage)
+		if options.candidatePruning == "heuristic" {
+			for candidate := range columns[i].candidates {
+				if !columns[i].PlausibleReference(candidate, options.minNameSimilarity) {
+					delete(columns[i].candidates, candidate)
+					pruned[i]++
+				}
+			}
+		}
+	})
+	if options.minCoverage >= 1 {
+		count := 0
+		for _, n := range counted {
+			count += n
+		}
+		logger.Infof("rejected %v column pairs by their distinct values and bloom filter bits", count)
+	}
+	if options.candidatePruning == "heuristic" {
+		count := 0
+		for _, n := range pruned {
+			count += n
+		}
+		logger.Infof("pruned %v candidates by their names and declared types", count)
+	}
+	if index != nil {
+		pairs := 0
+		for _, count := range compared {
+			pairs += count
+		}
+		logger.Infof("compared %v of %v column pairs sharing a signature band", pairs, len(searched)*(len(searched)-1))
+	}
+}
+
+// IsSearched reports whether the column takes part in the inclusion search.
+func (this *Column) IsSearched(options *Options) bool {
+	return this.geo == nil || options.geoInclusions
+}
+
+func (db Database) CandidateCount() (result int) {
+	for _, column := range db.AllColumns() {
+		result += len(column.candidates)
+	}
+	return result
+}
+
+func (this *Column) Bits() int {
+	return int(this.filterBits)
+}
+
+// columns with at most this many distinct values are treated as enumerations
+const maxValueSetSize = 10
+
+// HasNulls reports whether the column contains empty values.
+func (this *Column) HasNulls() bool {
+	return this.values[""] || this.spilled != nil && this.nulls > 0
+}
+
+// ValueSet returns the sorted values of a low cardinality column, as numbers
+// for int columns, or nil if the column is no enumeration.
+func (this *Column) ValueSet() (result []interface{}) {
+	// the values would end up in exports, which redaction keeps them out of
+	if redaction != "" || this.DistinctValues() > maxValueSetSize || this.DistinctValues() >= this.table.rowCount {
+		return nil
+	}
+	this.EachSortedValue(func(value string) bool {
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil && this.dataType == "int" {
+			result = append(result, number)
+		} else {
+			result = append(result, value)
+		}
+		return true
+	})
+	return result
+}
+
+// IsUnique reports whether no value occurs twice in the column, making it a
+// key which foreign keys can reference.
+func (this *Column) IsUnique() bool {
+	return this.DistinctValues() == this.table.rowCount
+}
+
+func (this *Column) Name() string {
+	return this.table.QualifiedName() + "." + this.name
+}
+
+func (this *Column) String() string {
+	return fmt.Sprintf("%v[%v]", this.table.id, this.id)
+}
+
+// MayBeIncludedIn rejects the inclusion of the column in another by counts
+// alone, before comparing statistics and bloom filters: the other column
+// needs at least as many distinct values, leaving out the empty one, and, if
+// its bloom filter is not smaller, at least as many bits set.
+func (this *Column) MayBeIncludedIn(other *Column) bool {
+	distinct := this.DistinctValues()
+	if this.HasNulls() {
+		distinct--
+	}
+	return distinct <= other.DistinctValues() && (this.filter.Size() > other.filter.Size() || this.filterBits <= other.filterBits)
+}
+
+func (this *Column) SimiliarTo(other *Column) bool {
+	return (this.dataType == other.dataType) &&
+		this.stats.SimiliarTo(other.stats) &&
+		this.filter.SimiliarTo(other.filter)
+}
+
+func (this *Column) ReadValues() (result map[string]bool) {
+	result = make(map[string]bool)
+	rows := this.table.OpenRows()
+	for {
+		row := rows.Read()
+		if len(row) == 0 {
+			break
+		}
+		result[this.Normalize(row[this.field])] = true
+	}
+	return result
+}
+
+// MayCover is SimiliarTo for approximate inclusions, where neither the
+// ranges nor the bloom filters of the columns need to be included. Only the
+// number of distinct values bounds the coverage.
+func (this *Column) MayCover(other *Column, minCoverage float64) bool {
+	return this.dataType == other.dataType && float64(other.DistinctValues()) >= minCoverage*float64(this.DistinctValues())
+}
+
+// BuildCandidates keeps the other columns the column may be included in,
+// returning the number rejected by MayBeIncludedIn.
+func (this *Column) BuildCandidates(others []*Column, minCoverage float64) (counted int) {
+	this.candidates = make(map[*Column]bool)
+	for _, other := range others {
+		if this == other {
+			continue
+		}
+		if minCoverage >= 1 && !this.MayBeIncludedIn(other) {
+			counted++
+			continue
+		}
+		if minCoverage < 1 && this.MayCover(other, minCoverage) || minCoverage >= 1 && this.SimiliarTo(other) {
+			this.candidates[other] = true
+		}
+	}
+	logger.Debugf("built %v candidates for %v", len(this.candidates), this.Label())
+	return counted
+}
+
+type InclusionGraph struct {
+	nodes           []*Column
+	adjacencyMatrix [][]bool
+	// mark inclusions as declared or new foreign keys when printing
+	catalog bool
+	// found by FindPrefixInclusions, reported apart from the inclusions
+	prefixes []*PrefixInclusion
+	// found by FindTokenInclusions
+	tokens []*TokenInclusion
+	// found by FindNaryInclusions
+	nary []*NaryInclusion
+	// number of calls to Add, telling schedulers when the graph changed
+	changes int
+	// share of the dependent values included for each inclusion, nil unless
+	// searching approximate inclusions
+	coverage map[[2]int]float64
+	// candidates validated not to hold, kept for -incremental
+	refuted []*Candidate
+	// number of candidates to validate
+	candidates int
+}
+
+type Candidate struct {
+	a *Column
+	b *Column
+}
+
+func (this *InclusionGraph) Add(candidate *Candidate) {
+	/*fmt.Println("Found Inclusion", candidate.a.Name(), candidate.a.Bits(), len(candidate.a.candidates), "<=", candidate.b.Name(), candidate.b.Bits(), len(candidate.b.candidates))*/
+	a := candidate.a.index
+	b := candidate.b.index
+	this.changes++
+	// complete transistive closure
+	// A <= B & I <= A -> I <= B
+	// I <= B & B <= C -> I <= C
+	for i, iConnectedTo := range this.adjacencyMatrix {
+		if iConnectedTo[a] {
+			iConnectedTo[b] = true
+			delete(this.nodes[i].candidates, this.nodes[b])
+			for c, bConnectedToC := range this.adjacencyMatrix[b] {
+				if bConnectedToC {
+					iConnectedTo[c] = true
+					delete(this.nodes[i].candidates, this.nodes[c])
+				}
+			}
+		}
+	}
+	/*fmt.Println("total:", this.Count())*/
+}
+
+// AddApproximate adds an inclusion holding for the given share of values.
+// Approximate inclusions are not transitive, so nothing else is inferred.
+func (this *InclusionGraph) AddApproximate(candidate *Candidate, coverage float64) {
+	this.changes++
+	this.adjacencyMatrix[candidate.a.index][candidate.b.index] = true
+	this.coverage[[2]int{candidate.a.index, candidate.b.index}] = coverage
+}
+
+func (this *InclusionGraph) Count() (result int) {
+	result = 0
+	for i, _ := range this.nodes {
+		for j, _ := range this.nodes {
+			if (i != j) && this.adjacencyMatrix[i][j] {
+				result += 1
+			}
+		}
+	}
+	return result
+}
+
+// Print lists the inclusions as tab separated pairs, aligned when written to
+// a terminal, followed by the similarity of their names if they have any.
+// With a catalog, a last column tells declared from new ones. Columns with an
+// alias are shown by it instead of their id.
+func (this *InclusionGraph) Print() {
+	w := NewOutput(terminalOutput)
+	name := func(column *Column) string {
+		if column.alias != "" {
+			return column.alias
+		}
+		return column.String()
+	}
+	for _, column := range this.nodes {
+		for _, candidate := range this.nodes {
+			if (column != candidate) && this.adjacencyMatrix[column.index][candidate.index] {
+				fmt.Fprintf(w, "%v\t%v", Colorize(name(column), cyan), Colorize(name(candidate), cyan))
+				if this.coverage != nil {
+					fmt.Fprintf(w, "\tcoverage %.3f", this.coverage[[2]int{column.index, candidate.index}])
+				}
+				if similarity := NameSimilarity(column, candidate); similarity >= 0 {
+					fmt.Fprintf(w, "\tname %.2f", similarity)
+				}
+				if this.catalog && this.IsDeclared(column, candidate) {
+					fmt.Fprint(w, "\tdeclared")
+				} else if this.catalog {
+					fmt.Fprint(w, "\t"+Colorize("new", yellow))
+				}
+				fmt.Fprintln(w)
+			}
+		}
+	}
+	w.Flush()
+}
+
+func (db Database) ToInclusionGraph() (result *InclusionGraph) {
+	nodes := db.AllColumns()
+	for i, column := range nodes {
+		column.index = i
+	}
+	adjacencyMatrix := make([][]bool, len(nodes))
+	for i := range adjacencyMatrix {
+		adjacencyMatrix[i] = make([]bool, len(nodes))
+		adjacencyMatrix[i][i] = true
+	}
+	result = &InclusionGraph{nodes: nodes, adjacencyMatrix: adjacencyMatrix}
+	return result
+}
+
+// Check reports whether the referenced column contains every value of the
+// dependent one, with ignoreNulls except the empty value.
+func (db Database) Check(candidate *Candidate, ignoreNulls bool) bool {
+	for e := range candidate.a.values {
+		if !candidate.b.values[e] && (e != "" || !ignoreNulls) {
+			return false
+		}
+	}
+	return true
+}
+
+// Coverage returns the share of the dependent column's distinct values the
+// referenced column contains.
+func (db Database) Coverage(candidate *Candidate, ignoreNulls bool) float64 {
+	distinct, included := 0, 0
+	for e := range candidate.a.values {
+		if e == "" && ignoreNulls {
+			continue
+		}
+		distinct++
+		if candidate.b.values[e] {
+			included++
+		}
+	}
+	if distinct == 0 {
+		return 1
+	}
+	return float64(included) / float64(distinct)
+}
+
+// NextCandidate removes the next candidate to validate from the columns'
+// candidates, leaving the order to the scheduler after declared foreign keys.
+func (db Database) NextCandidate(scheduler Scheduler) (result *Candidate) {
+	columns := db.AllColumns()
+	// declared foreign keys are the likeliest inclusions, validating them
+	// first lets the transitive closure prune the most candidates
+	for _, column := range columns {
+		for referenced := range column.foreignKeys {
+			if column.candidates[referenced] {
+				delete(column.candidates, referenced)
+				return &Candidate{column, referenced}
+			}
+		}
+	}
+	return scheduler.Next(columns)
+}
+
+// mostCandidatesScheduler validates the candidates of the columns with the
+// most candidates first.
+type mostCandidatesScheduler struct{}
+
+func (this mostCandidatesScheduler) Next(columns []*Column) *Candidate {
+	sort.Sort(ByMostCandidates(columns))
+	for _, column := range columns {
+		for _, candidate := range columns {
+			if column.candidates[candidate] {
+				delete(column.candidates, candidate)
+				/*fmt.Println("NextCandidate", column.Name(), column.Bits(), len(column.candidates), "->", candidate.Name(), candidate.Bits(), len(candidate.candidates))*/
+				return &Candidate{column, candidate}
+			}
+		}
+	}
+	return nil
+}
+
+type ByMostCandidates []*Column
+
+func (cs ByMostCandidates) Len() int {
+	return len(cs)
+}
+func (cs ByMostCandidates) Swap(i, j int) {
+	cs[i], cs[j] = cs[j], cs[i]
+}
+func (cs ByMostCandidates) Less(i, j int) bool {
+	return len(cs[i].candidates) > len(cs[j].candidates)
+}
+
+func (db Database) PrintStatistics(options *Options) {
+	filterSize := db.FilterSizes(options)
+	w := NewOutput(true)
+	for _, table := range db {
+		for _, column := range table.columns {
+			dataType := column.dataType
+			if column.partition {
+				dataType += " (partition)"
+			}
+			if column.pii != "" {
+				dataType += fmt.Sprintf(" (pii: %v %.2f)", column.pii, column.piiConfidence)
+			}
+			if column.geo != nil {
+				dataType += " (geo: " + column.geo.String() + ")"
+			}
+			if column.quantity != nil {
+				dataType += " (quantity: " + column.quantity.String() + ")"
+			}
+			if column.dates != nil {
+				dataType += " (dates: " + column.dates.String() + ")"
+			}
+			if len(column.languages) > 0 {
+				dataType += " (text: " + column.LanguageSummary() + ")"
+			}
+			if len(column.concepts) > 0 {
+				dataType += " (concepts: " + strings.Join(column.concepts, ", ") + ")"
+			}
+			if column.nulls > 0 {
+				dataType += fmt.Sprintf(" (nulls: %v)", column.nulls)
+			}
+			if column.mistyped > 0 {
+				dataType += fmt.Sprintf(" (mistyped: %v)", column.mistyped)
+			}
+			dataType += fmt.Sprintf(" (fpp: %.2g)", column.FalsePositiveRate(filterSize(column)))
+			fmt.Fprintf(w, "%v\t%v\t", Colorize(column.Label(), cyan), Colorize(dataType, yellow))
+			column.stats.Print(w)
+		}
+	}
+	w.Flush()
+}
+
+type Command struct {
+	name        string
+	usage       string
+	description string
+	run         func(options *Options)
+	flags       func(options *Options, flags *flag.FlagSet)
+}
+
+var commands []*Command
+
+func init() {
+	commands = []*Command{
+		{"discover", "<data-dir>", "find inclusion dependencies (default)", RunDiscover, nil},
+		{"stats", "<data-dir>", "print column statistics without searching for inclusions", RunStats, nil},
+		{"init", "<data-dir>", "write a starter mapping.tsv for the files in a data directory", RunInit, InitFlags},
+		{"validate-config", "<data-dir>", "check mapping.tsv and the files it references", RunValidateConfig, nil},
+		{"kafka", "<data-dir>", "sample kafka topics into a data directory and find inclusions between them", RunKafka, KafkaFlags},
+		{"database", "<data-dir>", "export the tables of a database into a data directory and find inclusions between them", RunDatabase, DatabaseFlags},
+		{"explain", "<data-dir> <column> <column>", "report which stage rejects an inclusion between two columns", RunExplain, nil},
+		{"generate", "<data-dir>", "write synthetic tables matching the profiles and foreign keys of the data", RunGenerate, GenerateFlags},
+		{"query", "<data-dir> <query>", "answer a query like \"stats(orders.*) where nulls > 0.1\" about the profiles and inclusions", RunQuery, QueryFlags},
+		{"table", "<path>", "print column statistics of a single file without a mapping", RunTable, TableFlags},
+		{"compare", "<before.json> <after.json>", "report new and removed tables, columns and inclusions and drifted columns between two runs with -output json", RunCompare, nil},
+		{"verify", "<artifact>", "check an artifact's signature and the checksums of its inputs and outputs", RunVerify, VerifyFlags},
+		{"version", "", "print version, build information and the settings in effect", RunVersion, nil},
+		{"completion", "bash|zsh|fish", "print a shell completion script", RunCompletion, nil},
+		{"help", "[command]", "print the usage of a command", RunHelp, nil},
+	}
+}
+
+// FindCommand picks the command named by the first argument, falling back to
+// discover so that a bare data directory keeps working.
+func FindCommand(arguments []string) (command *Command, rest []string) {
+	if len(arguments) > 0 {
+		for _, command := range commands {
+			if command.name == arguments[0] {
+				return command, arguments[1:]
+			}
+		}
+	}
+	return commands[0], arguments
+}
+
+// IgnoreNulls tells whether -nulls leaves empty values out of inclusions.
+func (this *Options) IgnoreNulls() bool {
+	return this.nulls == "ignore"
+}
+
+func (this *Options) FileFormat() *FileFormat {
+	format, err := ParseFileFormat(this.format)
+	check(err)
+	return format
+}
+
+func LoadDatabase(options *Options) (db Database) {
+	logger.Infof("using %v threads, analyzing %v tables and comparing %v columns at once", options.threads, options.analysisWorkers, options.validationWorkers)
+	logger.Infof("using seed %v", options.seed)
+	logger.Infof("data is in %v", options.dataDir)
+
+	if options.metanomeInput != "" {
+		db = ReadMetanomeInput(options.metanomeInput, options.dataDir)
+	} else if _, err := os.Stat(options.dataDir + "mapping.tsv"); os.IsNotExist(err) {
+		db = DiscoverTables(options.dataDir, options.header, options.FileFormat(), options.failures)
+	} else {
+		db = ReadTableMapping(options.dataDir, options.FileFormat(), options.failures)
+	}
+	status.Result("tables", len(db))
+	status.Result("columns", len(db.AllColumns()))
+	for _, table := range db {
+		table.hasHeader = table.hasHeader || options.header
+		table.typeSample, table.typeAgreement = options.typeSample, options.typeAgreement
+		table.budget = options.budget
+		table.sampling = options.Sampling(table)
+		table.raggedRows = options.raggedRows
+	}
+	if options.partitions != "" {
+		db = db.SelectPartitions(options.partitions)
+	}
+	if options.typesFile != "" {
+		db.OverrideTypes(options.typesFile)
+	}
+	if options.columnConfigFile != "" {
+		db.ApplyColumnConfig(options.columnConfigFile)
+	}
+	if options.nullTokens != "" {
+		db.ApplyNullTokens(strings.Split(options.nullTokens, ","))
+	}
+	if options.catalogFile != "" {
+		db.ImportCatalog(options.catalogFile)
+	}
+	if options.aliasesFile != "" {
+		db.ApplyAliases(options.aliasesFile)
+	}
+	if options.checkRulesFile != "" {
+		db.AttachRules(options.checkRulesFile)
+	}
+	if options.nameFilter != nil {
+		db = db.ApplyNameFilter(options.nameFilter)
+		status.Result("tables", len(db))
+		status.Result("columns", len(db.AllColumns()))
+	}
+	var glossary []*GlossaryEntry
+	if options.glossaryFile != "" {
+		glossary = ReadGlossary(options.glossaryFile)
+	}
+
+	if options.checkpointDir != "" {
+		check(os.MkdirAll(options.checkpointDir, 0755))
+	}
+	if options.profileCache != "" {
+		check(os.MkdirAll(options.profileCache, 0755))
+	}
+	if options.dashboard != nil {
+		options.dashboard.Watch(db)
+	}
+	monitor.Phase("analysis")
+	db.Preprocess(options)
+	db = db.WithoutFailed()
+	if options.checkRulesFile != "" {
+		db.CheckForeignKeyRules(options)
+	}
+	db.DetectPII()
+	db.DetectLanguages()
+	db.DetectGeo()
+	db.DetectDates()
+	db.TagConcepts(glossary)
+	return db
+}
+
+// PrintLoaded reports the tables LoadDatabase profiled, which of them were
+// sampled and the rows violating -check-rules.
+func (db Database) PrintLoaded(options *Options) {
+	fmt.Println("found", len(db), "table definitions")
+	db.PrintSampling()
+	if options.checkRulesFile != "" {
+		db.PrintRuleViolations()
+	}
+}
+
+// ExportProfiles writes the exports which only need column profiles.
+func (db Database) ExportProfiles(options *Options) {
+	if options.jsonSchemaDir != "" {
+		db.ExportJSONSchemas(options.jsonSchemaDir)
+	}
+	if options.parquetDir != "" {
+		db.ExportStatisticsParquet(options)
+	}
+	if options.featuresFile != "" {
+		WriteOutput(options.featuresFile, db.ExportFeatures)
+	}
+	if options.fingerprintsFile != "" {
+		WriteOutput(options.fingerprintsFile, db.ExportFingerprints)
+	}
+}
+
+func RunStats(options *Options) {
+	var stdout *os.File
+	if options.JSONOutput() {
+		stdout = RedirectOutput()
+	}
+	db := LoadDatabase(options)
+	db.PrintLoaded(options)
+	if stdout == nil {
+		db.PrintStatistics(options)
+	}
+	if options.catalogFile != "" {
+		db.PrintTypeMismatches()
+	}
+	if options.baselineDir != "" {
+		db.PrintDrift(options)
+	}
+	if options.duplicateColumns || options.knownFingerprints != "" {
+		db.PrintDuplicates(options)
+	}
+	if options.relations {
+		db.FindRelations(options)
+		db.PrintRelations()
+	}
+	db.ExportProfiles(options)
+	if options.artifactFile != "" {
+		db.WriteArtifact(options, "stats")
+	}
+	if stdout != nil {
+		db.WriteResults(stdout, options, nil)
+	}
+}
+
+func TableFlags(options *Options, flags *flag.FlagSet) {
+	flags.StringVar(&options.columns, "columns", "", "comma separated column names (default c000, c001, ...)")
+}
+
+// BuildSingleTable describes a file that is not listed in any mapping.tsv.
+// Without column names, the columns are named after the header row if there
+// is one, and after their position otherwise.
+func BuildSingleTable(path string, columns string, hasHeader bool, format *FileFormat) (result *Table) {
+	id := strings.Split(filepath.Base(path), ".")[0]
+	result = &Table{name: id, path: path, id: id, hasHeader: hasHeader, format: format}
+	var columnNames []string
+	if columns != "" {
+		columnNames = strings.Split(columns, ",")
+	} else if IsParquetFile(path) {
+		columnNames = ParquetColumnNames(path)
+	} else if hasHeader {
+		columnNames = result.OpenRawRows().Read()
+	} else {
+		columnNames = GenerateColumnNames(len(result.OpenRawRows().Read()))
+	}
+	result.BuildColumns(columnNames)
+	return result
+}
+
+func RunTable(options *Options) {
+	table := BuildSingleTable(options.arguments[0], options.columns, options.header, options.FileFormat())
+	table.typeSample, table.typeAgreement = options.typeSample, options.typeAgreement
+	table.budget = options.budget
+	table.sampling = options.Sampling(table)
+	table.raggedRows = options.raggedRows
+	if options.typesFile != "" {
+		Database{table}.OverrideTypes(options.typesFile)
+	}
+	if options.nullTokens != "" {
+		Database{table}.ApplyNullTokens(strings.Split(options.nullTokens, ","))
+	}
+	monitor.Phase("analysis")
+	table.Analyze()
+	Database{table}.PrintSampling()
+	Database{table}.PrintStatistics(options)
+}
+
+func RunDiscover(options *Options) {
+	var dialect *SQLDialect
+	if options.sqlFile != "" {
+		dialect = NewSQLDialect(options.sqlDialect)
+	}
+	var stdout *os.File
+	if options.JSONOutput() {
+		stdout = RedirectOutput()
+	}
+	if options.tui {
+		if options.dashboard = StartDashboard(); options.dashboard != nil {
+			defer options.dashboard.Close()
+		}
+	}
+	db, graph := DiscoverInclusions(options)
+	db.PrintLoaded(options)
+	if options.baselineDir != "" {
+		db.PrintDrift(options)
+	}
+	fmt.Println("found", graph.candidates, "candidates")
+	fmt.Println("found", graph.Count(), "inclusions")
+	if options.catalogFile != "" {
+		graph.PrintDeclaredViolations()
+		db.PrintTypeMismatches()
+	}
+
+	if stdout == nil {
+		graph.Print()
+	}
+	if options.keys {
+		monitor.Phase("unique column combinations")
+		db.FindKeys(options)
+		db.PrintKeys()
+	}
+	if options.dependencies {
+		monitor.Phase("functional dependencies")
+		db.FindDependencies(options)
+		db.PrintDependencies()
+	}
+	if options.orderDependencies {
+		monitor.Phase("order dependencies")
+		db.FindOrderDependencies(options)
+		db.PrintOrderDependencies()
+	}
+	if options.glossaryFile != "" {
+		graph.PrintConcepts()
+	}
+	if options.foreignKeys > 0 {
+		graph.PrintForeignKeys(options.foreignKeys)
+	}
+	if options.maxArity > 1 {
+		monitor.Phase("n-ary inclusions")
+		graph.FindNaryInclusions(options)
+		graph.PrintNaryInclusions()
+	}
+	if options.prefixInclusions {
+		monitor.Phase("prefix inclusions")
+		graph.FindPrefixInclusions(options)
+		graph.PrintPrefixInclusions()
+	}
+	if options.tokenInclusions {
+		monitor.Phase("token inclusions")
+		graph.FindTokenInclusions(options)
+		graph.PrintTokenInclusions()
+	}
+	if options.relations {
+		monitor.Phase("relations")
+		db.FindRelations(options)
+		db.PrintRelations()
+	}
+	if options.duplicateColumns || options.knownFingerprints != "" {
+		db.PrintDuplicates(options)
+	}
+	db.ExportProfiles(options)
+	if options.dbtFile != "" {
+		WriteOutput(options.dbtFile, graph.ExportDbt)
+	}
+	if options.schemaSpyFile != "" {
+		WriteOutput(options.schemaSpyFile, graph.ExportSchemaSpy)
+	}
+	if options.metanomeResults != "" {
+		WriteOutput(options.metanomeResults, func(w io.Writer) {
+			graph.ExportMetanomeResults(w, db)
+		})
+	}
+	if options.dotFile != "" {
+		WriteOutput(options.dotFile, graph.ExportDot)
+	}
+	if options.gephiDir != "" {
+		graph.ExportGephi(options.gephiDir)
+	}
+	if options.htmlDir != "" {
+		graph.ExportHTML(options.htmlDir)
+	}
+	if options.edgesDir != "" {
+		graph.ExportEdges(options.edgesDir, ParseEdgeFilter(options.edgesTables, options.edgesMinScore), options.edgesChunk)
+	}
+	if options.expectationsDir != "" {
+		graph.ExportExpectations(options.expectationsDir, db)
+	}
+	if options.rulesFile != "" {
+		WriteOutput(options.rulesFile, func(w io.Writer) {
+			graph.ExportRules(w, db)
+		})
+	}
+	if options.parquetDir != "" {
+		graph.ExportParquet(options)
+	}
+	if options.sqlFile != "" {
+		WriteOutput(options.sqlFile, func(w io.Writer) {
+			graph.ExportSQL(w, dialect, db, options.sqlMinScore)
+		})
+	}
+	if options.artifactFile != "" {
+		db.WriteArtifact(options, "discover")
+	}
+	if stdout != nil {
+		db.WriteResults(stdout, options, graph)
+	}
+	if options.dashboard != nil {
+		options.dashboard.Browse(graph)
+	}
+}
+
+// DiscoverInclusions profiles the tables and validates the candidates,
+// returning the graph of valid inclusion dependencies.
+func DiscoverInclusions(options *Options) (db Database, graph *InclusionGraph) {
+	db = LoadDatabase(options)
+	validator := NewValidator(options, db)
+	if options.incremental && options.minCoverage >= 1 {
+		validator = db.IncrementalValidator(validator, options.profileCache, options.IgnoreNulls())
+	}
+	defer validator.Close()
+	monitor.Phase("bloom filters")
+	db.BuildFilters(options)
+	monitor.Phase("candidates")
+	graph = db.ToInclusionGraph()
+	graph.catalog = options.catalogFile != ""
+	if options.minCoverage < 1 {
+		graph.coverage = make(map[[2]int]float64)
+	}
+	if !db.ResumeValidation(options, graph) {
+		db.BuildCandidates(options)
+	}
+	for _, column := range db.AllColumns() {
+		column.candidateCount = len(column.candidates)
+	}
+	candidates := db.CandidateCount()
+	graph.candidates = candidates
+	status.Result("candidates", candidates)
+
+	monitor.Phase("validation")
+	monitor.SetTotal(candidates)
+	validated := 0
+	lastCheckpoint := time.Now()
+	interrupts := make(chan os.Signal, 1)
+	if options.checkpointDir != "" {
+		signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(interrupts)
+	}
+	scheduler := NewScheduler(options, graph)
+	var live *LiveGraph
+	lastUpdate, lastDraw := time.Now(), time.Now()
+	if options.serve != "" {
+		live = ServeLiveGraph(options.serve)
+		live.Update(graph, validated, false)
+	}
+	validated = db.ValidateCandidates(graph, validator, scheduler, options.validationWorkers, options.minCoverage, func(validated int, pending []*Candidate) {
+		if options.checkpointDir != "" && time.Since(lastCheckpoint) > options.checkpointInterval {
+			graph.SaveCheckpoint(options.checkpointDir, pending, options.ValidationSettings())
+			lastCheckpoint = time.Now()
+		}
+		select {
+		case <-interrupts:
+			graph.SaveCheckpoint(options.checkpointDir, pending, options.ValidationSettings())
+			logger.Infof("interrupted after validating %v candidates, continue with -resume %v", validated, options.checkpointDir)
+			panic(errInterrupted)
+		default:
+		}
+		if live != nil && time.Since(lastUpdate) > time.Second {
+			live.Update(graph, validated, false)
+			lastUpdate = time.Now()
+		}
+		if options.dashboard != nil && time.Since(lastDraw) > dashboardInterval {
+			options.dashboard.Validation(validated, db.CandidateCount()+len(pending), graph.Count())
+			lastDraw = time.Now()
+		}
+	})
+	if options.dashboard != nil {
+		options.dashboard.Validation(validated, 0, graph.Count())
+	}
+	if live != nil {
+		live.Update(graph, validated, true)
+	}
+	if options.checkpointDir != "" {
+		graph.SaveCheckpoint(options.checkpointDir, nil, options.ValidationSettings())
+	}
+	if options.profileCache != "" && graph.coverage == nil {
+		graph.SaveResults(db, options.profileCache, options.IgnoreNulls())
+	}
+	status.Result("inclusions", graph.Count())
+	return db, graph
+}
+
+// set at build time, e.g.
+// go build -ldflags "-X github.com/mschneider/dataprofiling/profiling.commit=$(git rev-parse HEAD)
+// -X github.com/mschneider/dataprofiling/profiling.buildDate=$(date -u +%FT%TZ)" ./cmd/dataprofiling
+var (
+	version   = "0.1.0"
+	commit    = ""
+	buildDate = ""
+)
+
+// BuildInfo returns the commit and time the binary was built from, taken
+// from the linker flags or else the Go build information.
+func BuildInfo() (revision string, date string) {
+	revision, date = commit, buildDate
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && revision == "" {
+				revision = setting.Value
+			}
+			if setting.Key == "vcs.time" && date == "" {
+				date = setting.Value
+			}
+		}
+	}
+	if revision == "" {
+		revision = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return revision, date
+}
+
+func RunVersion(options *Options) {
+	revision, date := BuildInfo()
+	fmt.Println("dataprofiling", version)
+	fmt.Println("commit:", revision)
+	fmt.Println("built:", date)
+	fmt.Println("go:", runtime.Version(), runtime.GOOS+"/"+runtime.GOARCH)
+	options.flags.VisitAll(func(f *flag.Flag) {
+		fmt.Printf("%v: %v\n", f.Name, f.Value)
+	})
+}
+
+// Main runs the command line tool with the command and flags in os.Args and
+// returns its exit status: 1 if tables were skipped, 130 if interrupted.
+func Main() (code int) {
+	command, arguments := FindCommand(os.Args[1:])
+	options := ParseOptions(command, arguments)
+	options.Apply()
+	runtime.GOMAXPROCS(options.threads)
+	status.Start(command, options)
+	logger.StartProgress()
+	defer func() {
+		if failure := recover(); failure != nil {
+			logger.StopProgress()
+			status.Finish(failure)
+			if failure == errInterrupted {
+				code = 130
+				return
+			}
+			if _, bug := failure.(runtime.Error); bug {
+				panic(failure)
+			}
+			logger.Errorf("%v", failure)
+			code = 1
+		}
+	}()
+	defer options.budget.Close()
+	command.run(options)
+	logger.StopProgress()
+	options.failures.Report()
+	status.Finish(nil)
+	monitor.Finish()
+	if options.failures.Count() > 0 {
+		return 1
+	}
+	return options.exitCode
+}
@@ -0,0 +1,224 @@
+package profiling
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// With -incremental, a table whose cached profile is out of date only because
+// rows were appended is updated by reading the new rows alone: those after
+// the former end of a file that grew while its end so far stayed the same,
+// and those of new files in a directory of partitions whose former files are
+// unchanged. The statistics and value sets continue from the cached profile,
+// keeping the types it inferred, and bloom filters are rebuilt from the value
+// sets as in every run. Compressed, Parquet and sampled tables are profiled
+// anew.
+//
+// Validation then reuses the results of the previous run, which are saved in
+// the cache along with the file stamps of the profiles they were found with.
+// As appending only adds values, an inclusion a <= b still holds if a gained
+// no values, and b still misses a value of a if b gained none.
+
+// bytes at the end of a file whose hash tells whether it was only appended to
+const tailSize = 4096
+
+type appendedPart struct {
+	path string
+	// offset of the first appended row, 0 for a new file
+	offset    int64
+	partition *Partition
+}
+
+type previousResults struct {
+	IgnoreNulls bool
+	// file stamps of each table's profile
+	Sources    map[string]string
+	Inclusions [][2]string
+	Refuted    [][2]string
+}
+
+// TailHash hashes the last bytes of a file up to size, or returns 0 if they
+// do not end a line, as rows appended then would continue the last one.
+func TailHash(path string, size int64) uint64 {
+	file, err := os.Open(path)
+	check(err)
+	defer file.Close()
+	start := size - tailSize
+	if start < 0 {
+		start = 0
+	}
+	tail := make([]byte, size-start)
+	if _, err := file.ReadAt(tail, start); err != nil || len(tail) == 0 || tail[len(tail)-1] != '\n' {
+		return 0
+	}
+	hash := fnv.New64a()
+	hash.Write(tail)
+	return hash.Sum64()
+}
+
+// AppendedParts returns where the rows appended since the profile was saved
+// start, or false if the table's files changed otherwise. Changed compressed
+// files are never taken as appended to, as their sizes and tails are those of
+// the compressed bytes, so the table is analyzed anew.
+func (this *Table) AppendedParts(profile *tableProfile) (parts []appendedPart, ok bool) {
+	if IsParquetFile(this.path) || this.sampling != nil {
+		return nil, false
+	}
+	previous := make(map[string]sourceStamp)
+	for _, stamp := range profile.Sources {
+		previous[stamp.Path] = stamp
+	}
+	for i, stamp := range this.Sources() {
+		old, found := previous[stamp.Path]
+		delete(previous, stamp.Path)
+		switch {
+		case !found && this.partitions != nil:
+			parts = append(parts, appendedPart{stamp.Path, 0, this.partitions[i]})
+		case old == stamp:
+		case IsCompressedFile(stamp.Path):
+			return nil, false
+		case this.partitions == nil && stamp.Size > old.Size && profile.Tail != 0 && TailHash(stamp.Path, old.Size) == profile.Tail:
+			parts = append(parts, appendedPart{stamp.Path, old.Size, nil})
+		default:
+			return nil, false
+		}
+	}
+	return parts, len(previous) == 0 && len(parts) > 0
+}
+
+// OpenAppendedRows returns a reader of the rows of a part.
+func (this *Table) OpenAppendedRows(part appendedPart) (rows *RowReader) {
+	if part.offset == 0 {
+		rows = &RowReader{reader: NewLineReader(part.path), format: this.format}
+		rows.SkipLines()
+		if this.hasHeader {
+			rows.Read()
+		}
+	} else {
+		file, err := os.Open(part.path)
+		check(err)
+		_, err = file.Seek(part.offset, io.SeekStart)
+		check(err)
+		rows = &RowReader{reader: bufio.NewReader(countingReader{file}), format: this.format}
+	}
+	if policy := this.RaggedRows(); policy != "" {
+		rows.CheckFields(policy, this.DataFields(), part.path)
+	}
+	return rows
+}
+
+// UpdateProfile restores the cached profile of a table that only had rows
+// appended and adds those rows to it.
+func (this *Table) UpdateProfile(cacheDir string) bool {
+	profile := this.ReadProfile(cacheDir)
+	if profile == nil {
+		return false
+	}
+	parts, ok := this.AppendedParts(profile)
+	if !ok {
+		return false
+	}
+	this.RestoreProfile(profile)
+	this.cachedSources = fmt.Sprint(profile.Sources)
+	for _, column := range this.columns {
+		column.stats.ResumeAnalysis(column.AveragedValues())
+	}
+	cached := this.rowCount
+	for _, part := range parts {
+		rows := this.OpenAppendedRows(part)
+		for {
+			row := rows.Read()
+			if len(row) == 0 {
+				break
+			}
+			if part.partition != nil {
+				row = append(row, part.partition.values...)
+			}
+			this.AddRow(row)
+		}
+		rows.ReportRaggedRows()
+	}
+	this.FinishAnalysis()
+	logger.Infof("added %v appended rows to the cached profile of %v", this.rowCount-cached, this.QualifiedName())
+	return true
+}
+
+func PreviousResultsFileName(cacheDir string) string {
+	return filepath.Join(cacheDir, "inclusions.previous")
+}
+
+// SaveResults keeps the validation results for the next -incremental run.
+func (this *InclusionGraph) SaveResults(db Database, cacheDir string, ignoreNulls bool) {
+	results := previousResults{IgnoreNulls: ignoreNulls, Sources: make(map[string]string)}
+	for _, table := range db {
+		results.Sources[table.id] = fmt.Sprint(table.Sources())
+	}
+	for _, column := range this.nodes {
+		for _, other := range this.nodes {
+			if column != other && this.adjacencyMatrix[column.index][other.index] {
+				results.Inclusions = append(results.Inclusions, [2]string{column.String(), other.String()})
+			}
+		}
+	}
+	for _, candidate := range this.refuted {
+		results.Refuted = append(results.Refuted, [2]string{candidate.a.String(), candidate.b.String()})
+	}
+	WriteGob(PreviousResultsFileName(cacheDir), results)
+}
+
+// incrementalValidator answers from the previous run's results what appending
+// could not have changed, and asks the validator otherwise.
+type incrementalValidator struct {
+	Validator
+	// true for inclusions, false for refuted candidates
+	previous map[[2]string]bool
+	// columns whose values are those the previous results were found with
+	unchanged map[*Column]bool
+	reused    *int64
+}
+
+// IncrementalValidator wraps the validator in one reusing the previous
+// results, if there are any for the null handling in use.
+func (db Database) IncrementalValidator(validator Validator, cacheDir string, ignoreNulls bool) Validator {
+	var results previousResults
+	if !ReadGob(PreviousResultsFileName(cacheDir), &results) || results.IgnoreNulls != ignoreNulls {
+		return validator
+	}
+	this := incrementalValidator{validator, make(map[[2]string]bool), make(map[*Column]bool), new(int64)}
+	for _, pair := range results.Inclusions {
+		this.previous[pair] = true
+	}
+	for _, pair := range results.Refuted {
+		this.previous[pair] = false
+	}
+	for _, table := range db {
+		if table.cachedSources == "" || table.cachedSources != results.Sources[table.id] {
+			continue
+		}
+		for _, column := range table.columns {
+			this.unchanged[column] = !column.grown
+		}
+	}
+	return this
+}
+
+func (this incrementalValidator) Check(candidate *Candidate) bool {
+	holds, known := this.previous[[2]string{candidate.a.String(), candidate.b.String()}]
+	if known && holds && this.unchanged[candidate.a] || known && !holds && this.unchanged[candidate.b] {
+		atomic.AddInt64(this.reused, 1)
+		return holds
+	}
+	return this.Validator.Check(candidate)
+}
+
+func (this incrementalValidator) Close() {
+	if *this.reused > 0 {
+		logger.Infof("reused %v validation results of the previous run", *this.reused)
+	}
+	this.Validator.Close()
+}
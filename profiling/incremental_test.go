@@ -0,0 +1,105 @@
+package profiling
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testTable builds the table of users.tsv in dir, typed as by default.
+func testTable(t *testing.T, dir string) *Table {
+	table, err := BuildTable(dir+"/", []string{"users", "users.tsv", "id", "name", "score", "joined"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.typeSample, table.typeAgreement = 1000, 1
+	return table
+}
+
+// describeProfile prints what a profile holds in a comparable form.
+func describeProfile(table *Table) string {
+	var description bytes.Buffer
+	fmt.Fprintf(&description, "%v rows\n", table.rowCount)
+	for _, column := range table.columns {
+		fmt.Fprintf(&description, "%v %v, %v nulls, %v mistyped, values %q\n", column.name, column.dataType, column.nulls, column.mistyped, column.SortedValues())
+		column.stats.Print(&description)
+	}
+	return description.String()
+}
+
+func TestUpdateProfile(t *testing.T) {
+	rows := []string{
+		"1\tada\t3.5\t2020-01-02\n",
+		"2\tbob\t\t2020-02-03\n",
+		"3\t\t4\t2020-03-04\n",
+		"4\tcy\t-1.25\t2021-12-31\n",
+	}
+	appended := []string{
+		"5\tada\t10\t2019-06-07\n",
+		"6\tdee\t\t2022-01-01\n",
+		"7\tbob\t0.5\t\n",
+	}
+	tests := []struct {
+		name     string
+		initial  int
+		appended []string
+	}{
+		{"one row appended", 4, appended[:1]},
+		{"rows appended", 4, appended},
+		{"a new maximum and minimum", 2, append(rows[2:], appended...)},
+		{"repeated values only", 4, rows[:2]},
+	}
+	for _, test := range tests {
+		dir, cache := t.TempDir(), t.TempDir()
+		path := filepath.Join(dir, "users.tsv")
+		if err := os.WriteFile(path, []byte(strings.Join(rows[:test.initial], "")), 0644); err != nil {
+			t.Fatal(err)
+		}
+		table := testTable(t, dir)
+		table.Analyze()
+		table.SaveProfile(cache)
+
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.WriteString(strings.Join(test.appended, "")); err != nil {
+			t.Fatal(err)
+		}
+		if err := file.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		updated := testTable(t, dir)
+		if !updated.UpdateProfile(cache) {
+			t.Errorf("%v: the cached profile was not updated", test.name)
+			continue
+		}
+		full := testTable(t, dir)
+		full.Analyze()
+		if got, want := describeProfile(updated), describeProfile(full); got != want {
+			t.Errorf("%v: the updated profile\n%v\ndiffers from the full one\n%v", test.name, got, want)
+		}
+	}
+}
+
+func TestUpdateProfileChangedFile(t *testing.T) {
+	dir, cache := t.TempDir(), t.TempDir()
+	path := filepath.Join(dir, "users.tsv")
+	if err := os.WriteFile(path, []byte("1\tada\t3.5\t2020-01-02\n2\tbob\t\t2020-02-03\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	table := testTable(t, dir)
+	table.Analyze()
+	table.SaveProfile(cache)
+	// the first row changed, so the file grew other than by appending
+	if err := os.WriteFile(path, []byte("1\tcy\t3.5\t2020-01-02\n2\tbob\t\t2020-02-03\n3\tdee\t1\t2020-04-05\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if testTable(t, dir).UpdateProfile(cache) {
+		t.Errorf("the profile of a rewritten file was updated")
+	}
+}
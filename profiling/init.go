@@ -0,0 +1,181 @@
+package profiling
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var columnNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ .-]*$`)
+
+func InitFlags(options *Options, flags *flag.FlagSet) {
+	flags.BoolVar(&options.force, "force", false, "overwrite an existing mapping.tsv")
+}
+
+// LooksLikeHeader guesses whether a file's first row names its columns: all
+// names are distinct identifiers, and some column holds numbers below them.
+func LooksLikeHeader(first []string, second []string) bool {
+	seen := make(map[string]bool)
+	for _, field := range first {
+		if !columnNamePattern.MatchString(field) || seen[field] {
+			return false
+		}
+		seen[field] = true
+	}
+	if len(second) == 0 {
+		return true
+	}
+	for _, field := range second {
+		if IsFloat(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// DataFiles lists the regular, non hidden files of dataDir except the mapping,
+// and its directories of partitions, with a trailing slash.
+func DataFiles(dataDir string) (result []string) {
+	entries, err := os.ReadDir(dataDir)
+	check(err)
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || name == "mapping.tsv" {
+			continue
+		}
+		if entry.IsDir() {
+			if _, _, err := DiscoverPartitions(filepath.Join(dataDir, name)); err != nil {
+				logger.Infof("skipping directory %v: %v", name, err)
+				continue
+			}
+			result = append(result, name+"/")
+		} else if entry.Type().IsRegular() {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// SniffSeparator guesses the delimiter of a file from its first line: the
+// most frequent of tab, comma, semicolon and bar, a tab if none occurs.
+func SniffSeparator(line string) rune {
+	separator, most := '\t', strings.Count(line, "\t")
+	for _, r := range ",;|" {
+		if count := strings.Count(line, string(r)); count > most {
+			separator, most = r, count
+		}
+	}
+	return separator
+}
+
+// FirstRows reads the first two rows of a data file, or of the first file of
+// a directory of partitions. Without a format, it is guessed from the first
+// line and returned as the file's format in mapping.tsv, if it is not tsv.
+func FirstRows(path string, format *FileFormat) (first []string, second []string, spec string) {
+	if strings.HasSuffix(path, "/") {
+		_, partitions, err := DiscoverPartitions(path)
+		check(err)
+		if len(partitions) == 0 {
+			return nil, nil, ""
+		}
+		path = partitions[0].path
+	}
+	if format == nil {
+		line, _ := NewLineReader(path).ReadString('\n')
+		if separator := SniffSeparator(line); separator != '\t' {
+			format, spec = &FileFormat{separator: separator, quote: '"'}, "csv"
+			if separator != ',' {
+				spec += ":" + string(separator)
+			}
+		}
+	}
+	rows := &RowReader{reader: NewLineReader(path), format: format}
+	rows.SkipLines()
+	return rows.Read(), rows.Read(), spec
+}
+
+// BuildMapping describes every data file and directory of partitions as a
+// table named after it. Column names come from the schema of Parquet files,
+// and for the others from header rows if all of them have one, or if -header
+// is given, and are generated otherwise.
+func BuildMapping(dataDir string, header bool, format *FileFormat) (mapping [][]string, headers bool) {
+	var files, specs []string
+	var firstRows, secondRows [][]string
+	parquet := make(map[string][]string)
+	delimited := 0
+	headers = true
+	for _, file := range DataFiles(dataDir) {
+		if IsParquetFile(file) {
+			parquet[file] = ParquetColumnNames(dataDir + file)
+			files, specs = append(files, file), append(specs, "")
+			firstRows, secondRows = append(firstRows, nil), append(secondRows, nil)
+			continue
+		}
+		first, second, spec := FirstRows(dataDir+file, format)
+		files, specs = append(files, file), append(specs, spec)
+		firstRows, secondRows = append(firstRows, first), append(secondRows, second)
+		delimited++
+		headers = headers && LooksLikeHeader(first, second)
+	}
+	headers = (headers && delimited > 0) || header
+	for i, file := range files {
+		columnNames, ok := parquet[file]
+		if !ok && len(firstRows[i]) == 0 {
+			logger.Infof("skipping empty file %v", file)
+			continue
+		}
+		if !ok {
+			columnNames = GenerateColumnNames(len(firstRows[i]))
+			if headers {
+				columnNames = firstRows[i]
+			}
+		}
+		name := TableId(file)
+		if specs[i] != "" {
+			file += "@" + specs[i]
+		}
+		mapping = append(mapping, append([]string{name, file}, columnNames...))
+	}
+	return mapping, headers
+}
+
+// DiscoverTables builds the tables of a data directory without mapping.tsv,
+// as init would describe them.
+func DiscoverTables(dataDir string, header bool, format *FileFormat, failures *Failures) (db Database) {
+	logger.Infof("no mapping.tsv in %v, taking each data file as a table", dataDir)
+	mapping, headers := BuildMapping(dataDir, header, format)
+	for _, fields := range mapping {
+		failures.Guard(fields[0], "definition", func() {
+			table, err := BuildTable(dataDir, fields, format)
+			check(err)
+			table.hasHeader = headers && !IsParquetFile(table.path)
+			db = append(db, table)
+		})
+	}
+	if headers && !header {
+		logger.Infof("column names were taken from header rows")
+	}
+	return db
+}
+
+func RunInit(options *Options) {
+	mappingFileName := filepath.Join(options.dataDir, "mapping.tsv")
+	if _, err := os.Stat(mappingFileName); err == nil && !options.force {
+		panic(mappingFileName + " already exists, use -force to overwrite it")
+	}
+	mapping, headers := BuildMapping(options.dataDir, options.header, options.FileFormat())
+	file, err := os.Create(mappingFileName)
+	check(err)
+	for _, fields := range mapping {
+		_, err = fmt.Fprintln(file, strings.Join(fields, "\t"))
+		check(err)
+	}
+	check(file.Close())
+	fmt.Println("wrote", len(mapping), "table definitions to", mappingFileName)
+	if headers && !options.header {
+		fmt.Println("column names were taken from header rows, profile with -header to skip them")
+	}
+}
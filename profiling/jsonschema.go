@@ -0,0 +1,111 @@
+package profiling
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// booleans and dates keep the words and layouts of the data, so they are
+// described as strings
+var jsonSchemaTypes = map[string]string{"int": "integer", "float": "number", "bool": "string", "date": "string", "uuid": "string", "string": "string"}
+
+// JSONSchema describes the values observed in the column. Empty values make
+// the column nullable.
+func (this *Column) JSONSchema() (schema map[string]interface{}) {
+	schema = map[string]interface{}{"description": this.Summary()}
+	if this.alias != "" {
+		schema["title"] = this.alias
+	}
+	if len(this.languages) > 0 {
+		languages := make(map[string]float64)
+		for _, language := range this.languages {
+			languages[language.language] = language.share
+		}
+		schema["x-languages"] = languages
+	}
+	if this.geo != nil {
+		schema["x-geo"] = this.geo.Document()
+	}
+	if this.quantity != nil {
+		schema["x-quantity"] = this.quantity.Document()
+	}
+	if this.dates != nil {
+		schema["x-dates"] = this.dates.Document()
+	}
+	if len(this.concepts) > 0 {
+		schema["x-concepts"] = this.concepts
+	}
+	if this.pii != "" {
+		schema["x-pii"] = map[string]interface{}{"kind": this.pii, "confidence": this.piiConfidence}
+	}
+	schemaType := jsonSchemaTypes[this.dataType]
+	if this.HasNulls() {
+		schema["type"] = []string{schemaType, "null"}
+	} else {
+		schema["type"] = schemaType
+	}
+	if this.dataType == "uuid" {
+		schema["format"] = "uuid"
+	}
+	// ranges hold values of the data, which redaction keeps out
+	switch stats := this.stats.(type) {
+	case *intStatistics:
+		if redaction == "" {
+			schema["minimum"] = stats.minimum
+			schema["maximum"] = stats.maximum
+		}
+	case *floatStatistics:
+		if redaction == "" {
+			schema["minimum"] = stats.minimum
+			schema["maximum"] = stats.maximum
+		}
+	case *stringStatistics:
+		if this.dataType == "string" {
+			schema["minLength"] = utf8.RuneCountInString(stats.shortest)
+			schema["maxLength"] = utf8.RuneCountInString(stats.longest)
+		}
+	}
+	if valueSet := this.ValueSet(); valueSet != nil {
+		for i, value := range valueSet {
+			if value == "" {
+				valueSet[i] = nil
+			}
+		}
+		schema["enum"] = valueSet
+	}
+	return schema
+}
+
+func (this *Table) JSONSchema() map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+	for _, column := range this.columns {
+		properties[column.name] = column.JSONSchema()
+		if !column.HasNulls() {
+			required = append(required, column.name)
+		}
+	}
+	return map[string]interface{}{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      this.QualifiedName(),
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// ExportJSONSchemas writes one <table>.schema.json file per table.
+func (db Database) ExportJSONSchemas(dir string) {
+	check(os.MkdirAll(dir, 0755))
+	for _, table := range db {
+		schema := table.JSONSchema()
+		WriteOutput(filepath.Join(dir, table.QualifiedName()+".schema.json"), func(w io.Writer) {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			check(encoder.Encode(schema))
+		})
+	}
+}
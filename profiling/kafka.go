@@ -0,0 +1,200 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/linkedin/goavro/v2"
+	"github.com/segmentio/kafka-go"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func KafkaFlags(options *Options, flags *flag.FlagSet) {
+	flags.StringVar(&options.brokers, "brokers", "localhost:9092", "comma separated kafka brokers")
+	flags.StringVar(&options.topics, "topics", "", "comma separated topics to sample, each becomes a table")
+	flags.IntVar(&options.messages, "messages", 1000, "number of messages sampled per topic")
+	flags.StringVar(&options.schemaRegistry, "schema-registry", "", "schema registry URL for decoding Avro messages")
+	flags.DurationVar(&options.kafkaTimeout, "kafka-timeout", 10*time.Second, "stop reading a partition after waiting this long for a message")
+}
+
+// MessageDecoder turns a message value into a JSON document. Values are
+// either JSON or, with a schema registry, Avro in the Confluent wire format.
+type MessageDecoder struct {
+	registry string
+	codecs   map[uint32]*goavro.Codec
+}
+
+func (this *MessageDecoder) Codec(id uint32) (*goavro.Codec, error) {
+	if codec, ok := this.codecs[id]; ok {
+		return codec, nil
+	}
+	response, err := http.Get(fmt.Sprintf("%v/schemas/ids/%v", strings.TrimSuffix(this.registry, "/"), id))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("schema registry returned %v for schema %v", response.Status, id)
+	}
+	var schema struct {
+		Schema string `json:"schema"`
+	}
+	if err = json.NewDecoder(response.Body).Decode(&schema); err != nil {
+		return nil, err
+	}
+	codec, err := goavro.NewCodecForStandardJSONFull(schema.Schema)
+	if err != nil {
+		return nil, err
+	}
+	this.codecs[id] = codec
+	return codec, nil
+}
+
+func (this *MessageDecoder) Decode(value []byte) (document interface{}, err error) {
+	if this.registry != "" && len(value) > 5 && value[0] == 0 {
+		codec, err := this.Codec(binary.BigEndian.Uint32(value[1:5]))
+		if err != nil {
+			return nil, err
+		}
+		native, _, err := codec.NativeFromBinary(value[5:])
+		if err != nil {
+			return nil, err
+		}
+		if value, err = codec.TextualFromNative(nil, native); err != nil {
+			return nil, err
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	err = decoder.Decode(&document)
+	return document, err
+}
+
+// Flatten stores the scalar fields of a JSON document in row, naming nested
+// fields by their path (a.b.c). Arrays are kept as JSON, nulls become empty.
+func Flatten(path string, value interface{}, row map[string]string) {
+	if object, ok := value.(map[string]interface{}); ok {
+		for key, child := range object {
+			if path != "" {
+				key = path + "." + key
+			}
+			Flatten(key, child, row)
+		}
+		return
+	}
+	if path == "" {
+		path = "value"
+	}
+	switch value := value.(type) {
+	case nil:
+		row[path] = ""
+	case string:
+		row[path] = value
+	case json.Number:
+		row[path] = value.String()
+	case bool:
+		row[path] = strconv.FormatBool(value)
+	default:
+		encoded, _ := json.Marshal(value)
+		row[path] = string(encoded)
+	}
+}
+
+func SampleTopic(options *Options, decoder *MessageDecoder, topic string) (rows []map[string]string) {
+	brokers := strings.Split(options.brokers, ",")
+	connection, err := kafka.Dial("tcp", brokers[0])
+	check(err)
+	partitions, err := connection.ReadPartitions(topic)
+	connection.Close()
+	check(err)
+	for i, partition := range partitions {
+		reader := kafka.NewReader(kafka.ReaderConfig{Brokers: brokers, Topic: topic, Partition: partition.ID, MaxBytes: 10e6})
+		check(reader.SetOffset(kafka.FirstOffset))
+		// split the remaining messages evenly among the remaining partitions
+		remainingPartitions := len(partitions) - i
+		limit := len(rows) + (options.messages-len(rows)+remainingPartitions-1)/remainingPartitions
+		for len(rows) < limit {
+			ctx, cancel := context.WithTimeout(context.Background(), options.kafkaTimeout)
+			message, err := reader.ReadMessage(ctx)
+			cancel()
+			if err != nil {
+				break
+			}
+			document, err := decoder.Decode(message.Value)
+			if err != nil {
+				logger.Infof("skipping undecodable message in %v at offset %v: %v", topic, message.Offset, err)
+				continue
+			}
+			row := make(map[string]string)
+			Flatten("", document, row)
+			rows = append(rows, row)
+		}
+		reader.Close()
+	}
+	return rows
+}
+
+// SanitizeValue keeps sampled values from breaking the tab separated format.
+func SanitizeValue(value string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(value)
+}
+
+// WriteSample stores the rows of one topic as a tab separated file with a
+// header row and returns its mapping.tsv line.
+func WriteSample(dataDir string, topic string, rows []map[string]string) (mapping []string) {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, SanitizeValue(column))
+			}
+		}
+	}
+	sort.Strings(columns)
+	// table ids are taken from the file name up to the first dot
+	fileName := strings.ReplaceAll(topic, ".", "_") + ".tsv"
+	file, err := os.Create(dataDir + fileName)
+	check(err)
+	fmt.Fprintln(file, strings.Join(columns, "\t"))
+	for _, row := range rows {
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = SanitizeValue(row[column])
+		}
+		fmt.Fprintln(file, strings.Join(values, "\t"))
+	}
+	check(file.Close())
+	return append([]string{topic, fileName}, columns...)
+}
+
+// RunKafka samples every topic into the data directory, writes a mapping for
+// the samples and searches them for inclusions like any other data directory.
+func RunKafka(options *Options) {
+	if options.topics == "" {
+		panic("provide the topics to sample with -topics")
+	}
+	check(os.MkdirAll(options.dataDir, 0755))
+	decoder := &MessageDecoder{registry: options.schemaRegistry, codecs: make(map[uint32]*goavro.Codec)}
+	var mapping []string
+	for _, topic := range strings.Split(options.topics, ",") {
+		rows := SampleTopic(options, decoder, topic)
+		logger.Infof("sampled %v messages from %v", len(rows), topic)
+		if len(rows) == 0 {
+			continue
+		}
+		mapping = append(mapping, strings.Join(WriteSample(options.dataDir, topic, rows), "\t"))
+	}
+	check(os.WriteFile(options.dataDir+"mapping.tsv", []byte(strings.Join(mapping, "\n")+"\n"), 0644))
+	options.header = true
+	RunDiscover(options)
+}
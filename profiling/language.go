@@ -0,0 +1,111 @@
+package profiling
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// string columns averaging at least this many characters are checked for
+// natural language
+const minTextLength = 20
+
+// number of distinct values of a column checked for their language
+const languageSampleSize = 200
+
+// languages with a smaller share of the checked values are not reported
+const minLanguageShare = 0.1
+
+// The most frequent words of each language identify it reliably in
+// sentences, while code, identifiers and lists rarely contain several.
+var stopWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "that", "for", "it", "with", "was", "on", "are", "be", "this", "by", "not", "or", "have", "from"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "sich", "auf", "für", "von", "dem", "auch", "es", "im", "wird"},
+	"fr": {"le", "la", "les", "et", "des", "est", "un", "une", "du", "en", "que", "pour", "dans", "pas", "sur", "au", "avec", "ce", "il", "sont"},
+	"es": {"el", "la", "los", "las", "y", "de", "que", "en", "un", "una", "es", "por", "con", "para", "del", "se", "no", "al", "lo", "como"},
+	"it": {"il", "la", "di", "che", "e", "è", "un", "una", "per", "non", "con", "del", "della", "sono", "gli", "le", "si", "da", "nel", "anche"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "met", "voor", "zijn", "er", "ook", "aan", "als", "bij", "maar", "wordt"},
+	"pt": {"o", "a", "os", "as", "e", "de", "que", "do", "da", "em", "um", "uma", "não", "para", "com", "por", "se", "no", "na", "mais"},
+}
+
+var stopWordLanguages = func() (result map[string][]string) {
+	result = make(map[string][]string)
+	for language, words := range stopWords {
+		for _, word := range words {
+			result[word] = append(result[word], language)
+		}
+	}
+	return result
+}()
+
+type LanguageShare struct {
+	language string
+	share    float64
+}
+
+// DetectLanguage returns the language most of the text's words are stop
+// words of, or "" if it contains too few of them to tell.
+func DetectLanguage(text string) string {
+	hits := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		for _, language := range stopWordLanguages[word] {
+			hits[language]++
+		}
+	}
+	best, bestHits := "", 1
+	for language, count := range hits {
+		if count > bestHits || (count == bestHits && best != "" && language < best) {
+			best, bestHits = language, count
+		}
+	}
+	return best
+}
+
+// DetectLanguages finds the dominant languages of long string columns. A
+// long column without any is probably code, identifiers or lists.
+func (this *Column) DetectLanguages() {
+	stats, ok := this.stats.(*stringStatistics)
+	if !ok || this.dataType != "string" || stats.averageLength < minTextLength {
+		return
+	}
+	counts := make(map[string]int)
+	checked := 0
+	this.EachValueUntil(func(value string) bool {
+		if !strings.Contains(value, " ") {
+			return true
+		}
+		if language := DetectLanguage(value); language != "" {
+			counts[language]++
+		}
+		if checked++; checked == languageSampleSize {
+			return false
+		}
+		return true
+	})
+	for language, count := range counts {
+		if share := float64(count) / float64(checked); share >= minLanguageShare {
+			this.languages = append(this.languages, LanguageShare{language, share})
+		}
+	}
+	sort.Slice(this.languages, func(i, j int) bool {
+		return this.languages[i].share > this.languages[j].share
+	})
+}
+
+func (this *Column) LanguageSummary() string {
+	var parts []string
+	for _, language := range this.languages {
+		parts = append(parts, fmt.Sprintf("%v %.2f", language.language, language.share))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (db Database) DetectLanguages() {
+	for _, column := range db.AllColumns() {
+		column.DetectLanguages()
+	}
+}
@@ -0,0 +1,124 @@
+// Package profiling profiles the columns of tabular files and discovers the
+// inclusion dependencies between them. The dataprofiling command in
+// cmd/dataprofiling runs it from the command line; other programs embed it
+// like this:
+//
+//	options, err := profiling.NewOptions("data/", "-threads", "4")
+//	...
+//	db, graph, err := profiling.Discover(options)
+//	...
+//	for _, edge := range graph.Inclusions() {
+//		fmt.Println(edge.Dependent().Name(), "<=", edge.Referenced().Name())
+//	}
+package profiling
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+)
+
+// Runs share the process' logger, redaction of values, resource monitor and
+// status file, which Profile and Discover set from their options, so that
+// they are not safe for concurrent use: calls wait for each other instead.
+var runs sync.Mutex
+
+// NewOptions configures a run over the tables described by dataDir's
+// mapping.tsv with the flags of the discover command, e.g. "-threads", "4".
+func NewOptions(dataDir string, flags ...string) (*Options, error) {
+	command, _ := FindCommand([]string{"discover"})
+	return parseOptions(command, append(append([]string(nil), flags...), dataDir), flag.ContinueOnError)
+}
+
+// recoverError returns the failures the profiling code panics with as err.
+func recoverError(err *error) {
+	if failure := recover(); failure != nil {
+		*err = fmt.Errorf("%v", failure)
+	}
+}
+
+// Profile analyzes the tables without searching for inclusions. Tables that
+// cannot be read are skipped, and named by err along with the profiles of
+// the others. It waits for other calls of Profile and Discover to finish.
+func Profile(options *Options) (db Database, err error) {
+	runs.Lock()
+	defer runs.Unlock()
+	defer recoverError(&err)
+	options.Apply()
+	db = LoadDatabase(options)
+	return db, options.failures.Err()
+}
+
+// Discover analyzes the tables and returns the valid inclusion dependencies.
+// Tables that cannot be read are skipped like by Profile, and calls wait for
+// each other the same way. With -checkpoint-dir, it takes over the process'
+// interrupts while validating, saving the progress and returning an error.
+func Discover(options *Options) (db Database, graph *InclusionGraph, err error) {
+	runs.Lock()
+	defer runs.Unlock()
+	defer recoverError(&err)
+	options.Apply()
+	db, graph = DiscoverInclusions(options)
+	return db, graph, options.failures.Err()
+}
+
+// Inclusions returns every inclusion of the graph, including the transitive
+// ones. Use Edges to stream them from large graphs.
+func (this *InclusionGraph) Inclusions() (edges []Edge) {
+	this.Edges(EdgeFilter{}, 0, func(edge Edge) bool {
+		edges = append(edges, edge)
+		return true
+	})
+	return edges
+}
+
+// NaryInclusions returns the inclusions of several columns found with
+// -max-arity.
+func (this *InclusionGraph) NaryInclusions() []*NaryInclusion {
+	return this.nary
+}
+
+func (this *NaryInclusion) Dependent() []*Column {
+	return this.a
+}
+
+func (this *NaryInclusion) Referenced() []*Column {
+	return this.b
+}
+
+func (this Edge) Dependent() *Column {
+	return this.a
+}
+
+func (this Edge) Referenced() *Column {
+	return this.b
+}
+
+func (this *Table) Columns() []*Column {
+	return this.columns
+}
+
+func (this *Table) RowCount() int {
+	return this.rowCount
+}
+
+func (this *Column) Table() *Table {
+	return this.table
+}
+
+// DataType is int, float, bool, date, uuid or string.
+func (this *Column) DataType() string {
+	return this.dataType
+}
+
+func (this *Column) DistinctValues() int {
+	if this.spilled != nil {
+		return this.spilled.distinct
+	}
+	return len(this.values)
+}
+
+// Nulls counts the column's empty values.
+func (this *Column) Nulls() int {
+	return this.nulls
+}
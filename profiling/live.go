@@ -0,0 +1,157 @@
+package profiling
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// LiveGraph serves the inclusions found so far while the validation runs.
+// The validation loop snapshots the graph now and then, so that requests
+// never read the adjacency matrix while it changes.
+type LiveGraph struct {
+	mutex    sync.Mutex
+	snapshot liveDocument
+}
+
+type liveDocument struct {
+	Validated int        `json:"validated"`
+	Finished  bool       `json:"finished"`
+	Nodes     []liveNode `json:"nodes"`
+	Links     [][2]int   `json:"links"`
+}
+
+type liveNode struct {
+	Id    string `json:"id"`
+	Label string `json:"label"`
+	Table string `json:"table"`
+	// number of columns included in this one, high for hub columns
+	Included int `json:"included"`
+}
+
+// ServeLiveGraph starts serving on the address, failing right away if it
+// cannot listen there.
+func ServeLiveGraph(address string) (result *LiveGraph) {
+	result = &LiveGraph{}
+	listener, err := net.Listen("tcp", address)
+	check(err)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, livePage)
+	})
+	mux.HandleFunc("/graph.json", func(w http.ResponseWriter, r *http.Request) {
+		result.mutex.Lock()
+		defer result.mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result.snapshot)
+	})
+	go http.Serve(listener, mux)
+	logger.Infof("serving the inclusion graph on http://%v/", listener.Addr())
+	return result
+}
+
+// Update snapshots the columns taking part in an inclusion.
+func (this *LiveGraph) Update(graph *InclusionGraph, validated int, finished bool) {
+	document := liveDocument{Validated: validated, Finished: finished, Nodes: []liveNode{}, Links: [][2]int{}}
+	indexes := make(map[*Column]int)
+	node := func(column *Column) int {
+		if index, ok := indexes[column]; ok {
+			return index
+		}
+		indexes[column] = len(document.Nodes)
+		document.Nodes = append(document.Nodes, liveNode{Id: column.String(), Label: column.Label(), Table: column.table.QualifiedName()})
+		return indexes[column]
+	}
+	for _, a := range graph.nodes {
+		for _, b := range graph.nodes {
+			if a != b && graph.adjacencyMatrix[a.index][b.index] {
+				source, target := node(a), node(b)
+				document.Links = append(document.Links, [2]int{source, target})
+				document.Nodes[target].Included++
+			}
+		}
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.snapshot = document
+}
+
+// livePage lays the graph out with a simple force simulation, keeping node
+// positions across refreshes. Nodes grow with the number of columns included
+// in them, and are colored by table.
+const livePage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>dataprofiling inclusions</title>
+<style>
+body { margin: 0; font: 12px sans-serif; }
+#info { position: fixed; top: 8px; left: 8px; background: rgba(255,255,255,0.8); padding: 4px; }
+</style>
+</head>
+<body>
+<div id="info">loading</div>
+<canvas id="graph"></canvas>
+<script>
+const canvas = document.getElementById("graph"), context = canvas.getContext("2d");
+let nodes = [], links = [], positions = {};
+function color(table) {
+  let hash = 0;
+  for (const c of table) hash = (hash * 31 + c.charCodeAt(0)) % 360;
+  return "hsl(" + hash + ", 60%, 50%)";
+}
+function radius(node) { return 4 + 2 * Math.sqrt(node.included); }
+async function refresh() {
+  const data = await (await fetch("graph.json")).json();
+  nodes = data.nodes.map(node => {
+    const position = positions[node.id] || (positions[node.id] = {x: Math.random() * canvas.width, y: Math.random() * canvas.height, vx: 0, vy: 0});
+    return Object.assign(node, {position});
+  });
+  links = data.links;
+  document.getElementById("info").textContent = (data.finished ? "finished: " : "validating: ") +
+    data.validated + " candidates validated, " + links.length + " inclusions between " + nodes.length + " columns";
+  if (!data.finished) setTimeout(refresh, 2000);
+}
+function step() {
+  for (const a of nodes) for (const b of nodes) {
+    if (a === b) continue;
+    const dx = a.position.x - b.position.x, dy = a.position.y - b.position.y, d2 = dx * dx + dy * dy + 0.01;
+    a.position.vx += 200 * dx / d2; a.position.vy += 200 * dy / d2;
+  }
+  for (const [s, t] of links) {
+    const a = nodes[s].position, b = nodes[t].position, dx = b.x - a.x, dy = b.y - a.y;
+    a.vx += 0.01 * dx; a.vy += 0.01 * dy; b.vx -= 0.01 * dx; b.vy -= 0.01 * dy;
+  }
+  for (const node of nodes) {
+    const p = node.position;
+    p.vx += 0.005 * (canvas.width / 2 - p.x); p.vy += 0.005 * (canvas.height / 2 - p.y);
+    p.vx *= 0.6; p.vy *= 0.6; p.x += p.vx; p.y += p.vy;
+  }
+}
+function draw() {
+  canvas.width = window.innerWidth; canvas.height = window.innerHeight;
+  step();
+  context.strokeStyle = "#999";
+  for (const [s, t] of links) {
+    const a = nodes[s].position, b = nodes[t].position, angle = Math.atan2(b.y - a.y, b.x - a.x), r = radius(nodes[t]);
+    const x = b.x - r * Math.cos(angle), y = b.y - r * Math.sin(angle);
+    context.beginPath(); context.moveTo(a.x, a.y); context.lineTo(x, y);
+    context.lineTo(x - 6 * Math.cos(angle - 0.4), y - 6 * Math.sin(angle - 0.4)); context.moveTo(x, y);
+    context.lineTo(x - 6 * Math.cos(angle + 0.4), y - 6 * Math.sin(angle + 0.4)); context.stroke();
+  }
+  for (const node of nodes) {
+    context.fillStyle = color(node.table);
+    context.beginPath(); context.arc(node.position.x, node.position.y, radius(node), 0, 2 * Math.PI); context.fill();
+    context.fillStyle = "#000"; context.fillText(node.label, node.position.x + radius(node) + 2, node.position.y + 4);
+  }
+  requestAnimationFrame(draw);
+}
+refresh();
+draw();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,96 @@
+package profiling
+
+import (
+	"fmt"
+)
+
+// number of hash functions in the signatures of -candidate-index lsh
+const lshSignatureSize = 64
+
+// lshIndex buckets the columns by bands of the MinHash signatures of their
+// non-empty values, so that candidates are only built for columns sharing a
+// bucket instead of for all pairs. If a is included in b, the hashes at a
+// position agree with a probability of |a|/|b|, so with bands of r rows the
+// pair shares a bucket with a probability of 1-(1-(|a|/|b|)^r)^(64/r). The
+// index thus misses inclusions of small columns in much larger ones, the
+// more so the more rows a band has.
+type lshIndex struct {
+	rows       int
+	signatures map[*Column][]uint64
+	buckets    map[string][]*Column
+	// columns without a non-empty value, which are included in any other
+	empty []*Column
+}
+
+func NewLSHIndex(columns []*Column, rows int, workers int) (index *lshIndex) {
+	if rows < 1 || rows > lshSignatureSize {
+		panic(fmt.Sprintf("-lsh-rows must be between 1 and %v", lshSignatureSize))
+	}
+	index = &lshIndex{rows: rows, signatures: make(map[*Column][]uint64), buckets: make(map[string][]*Column)}
+	signatures := make([][]uint64, len(columns))
+	RunWorkers(workers, len(columns), func(i int) {
+		signature, empty := NewMinHashSignature(lshSignatureSize), true
+		columns[i].EachValue(func(value string) {
+			if value != "" {
+				AddMinHash(signature, value)
+				empty = false
+			}
+		})
+		if !empty {
+			signatures[i] = signature
+		}
+	})
+	for i, column := range columns {
+		if signatures[i] == nil {
+			index.empty = append(index.empty, column)
+			continue
+		}
+		index.signatures[column] = signatures[i]
+		for _, key := range index.Keys(signatures[i]) {
+			index.buckets[key] = append(index.buckets[key], column)
+		}
+	}
+	return index
+}
+
+// Keys returns a bucket key per band of the signature.
+func (this *lshIndex) Keys(signature []uint64) (keys []string) {
+	for band := 0; band+this.rows <= len(signature); band += this.rows {
+		keys = append(keys, fmt.Sprint(band, signature[band:band+this.rows]))
+	}
+	return keys
+}
+
+// MayContain tells whether b may contain all non-empty values of a: each of
+// b's hashes is at most a's then, as b's minimum is taken over more values.
+func (this *lshIndex) MayContain(a *Column, b *Column) bool {
+	for i, hash := range this.signatures[a] {
+		if this.signatures[b][i] > hash {
+			return false
+		}
+	}
+	return true
+}
+
+// Similar returns the columns sharing a bucket with the column. With exact
+// inclusions only, those that cannot contain it by their signatures are left
+// out.
+func (this *lshIndex) Similar(column *Column, minCoverage float64) (result []*Column) {
+	signature, ok := this.signatures[column]
+	if !ok {
+		for other := range this.signatures {
+			result = append(result, other)
+		}
+		return append(result, this.empty...)
+	}
+	seen := map[*Column]bool{column: true}
+	for _, key := range this.Keys(signature) {
+		for _, other := range this.buckets[key] {
+			if !seen[other] && (minCoverage < 1 || this.MayContain(column, other)) {
+				result = append(result, other)
+			}
+			seen[other] = true
+		}
+	}
+	return result
+}
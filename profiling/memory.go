@@ -0,0 +1,270 @@
+package profiling
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Analysis keeps the distinct values of every column in memory, so that the
+// memory validator can compare them. With -max-memory, the estimated size of
+// these value sets is bounded: once it exceeds the budget, the table being
+// analyzed writes its largest value set to a sorted run file and starts the
+// set afresh. After the analysis, the runs of a column are merged into a
+// single sorted file, which validation reads instead of the value set, as do
+// the features going through the values, like the language or personal data
+// detection, by EachValue.
+//
+// -validator spider needs no value sets in memory at all: every column spills
+// its values once it holds -spill-values of them, so that all columns end up
+// with a value file, which the validator merges.
+
+// estimated bytes a value takes in a map besides its characters
+const valueOverhead = 48
+
+// value sets smaller than this are not worth spilling
+const minSpilledValues = 1024
+
+// MemoryBudget bounds the estimated bytes held by the value sets of all
+// tables, which may be analyzed concurrently.
+type MemoryBudget struct {
+	limit int64
+	used  int64
+	// directory of the spilled values, created by the first spill
+	parent string
+	dir    string
+	once   sync.Once
+	runs   int64
+	// values of a column held before they are spilled, 0 for no bound
+	chunkSize int
+}
+
+func NewMemoryBudget(limit int64, parent string) *MemoryBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &MemoryBudget{limit: limit, parent: parent}
+}
+
+// Reserve accounts for bytes added to a value set and reports whether the
+// budget is exceeded.
+func (this *MemoryBudget) Reserve(bytes int64) bool {
+	return atomic.AddInt64(&this.used, bytes) > this.limit
+}
+
+func (this *MemoryBudget) Release(bytes int64) {
+	atomic.AddInt64(&this.used, -bytes)
+}
+
+// Path returns the path of a new file in the spill directory.
+func (this *MemoryBudget) Path(column *Column, suffix string) string {
+	this.once.Do(func() {
+		dir, err := os.MkdirTemp(this.parent, "values")
+		check(err)
+		this.dir = dir
+	})
+	return filepath.Join(this.dir, fmt.Sprintf("%v-%v-%v.%v", column.table.FileId(), column.id, atomic.AddInt64(&this.runs, 1), suffix))
+}
+
+// Close removes the spilled values.
+func (this *MemoryBudget) Close() {
+	if this != nil && this.dir != "" {
+		check(os.RemoveAll(this.dir))
+	}
+}
+
+// ParseByteSize parses sizes like 512MiB, 8G or 1000000, with binary units
+// whether they are written KB or KiB.
+func ParseByteSize(s string) (int64, error) {
+	units := []string{"K", "M", "G", "T"}
+	number := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B"), "I")
+	multiplier := int64(1)
+	for i, unit := range units {
+		if strings.HasSuffix(number, unit) {
+			number = strings.TrimSuffix(number, unit)
+			multiplier = int64(1) << (10 * (i + 1))
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q, use e.g. 512MiB or 8GiB", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// AddValue adds a value to the column's value set, spilling the largest set
+// of the table if the memory budget is exceeded.
+func (this *Column) AddValue(value string) {
+	if this.values[value] {
+		return
+	}
+	this.values[value] = true
+	this.grown = true
+	budget := this.table.budget
+	if budget == nil {
+		return
+	}
+	bytes := int64(len(value) + valueOverhead)
+	this.held += bytes
+	if budget.Reserve(bytes) {
+		this.table.SpillLargest()
+	}
+	if budget.chunkSize > 0 && len(this.values) >= budget.chunkSize {
+		this.Spill()
+	}
+}
+
+// SpillLargest writes the largest value set of the table to a run file.
+func (this *Table) SpillLargest() {
+	var largest *Column
+	for _, column := range this.columns {
+		if largest == nil || column.held > largest.held {
+			largest = column
+		}
+	}
+	if largest == nil || len(largest.values) < minSpilledValues {
+		return
+	}
+	largest.Spill()
+}
+
+func (this *Column) Spill() {
+	budget := this.table.budget
+	this.runs = append(this.runs, WriteRun(this.values, budget.Path(this, "run")).path)
+	this.values = make(map[string]bool)
+	budget.Release(this.held)
+	this.held = 0
+}
+
+// ReleaseValues drops the value sets of a table that failed, returning their
+// bytes to the budget.
+func (this *Table) ReleaseValues() {
+	for _, column := range this.columns {
+		if this.budget != nil {
+			this.budget.Release(column.held)
+		}
+		column.held = 0
+		column.values = make(map[string]bool)
+	}
+}
+
+// FinishValues merges the runs of a column whose values were spilled into
+// its value file, which every column gets with a chunk size.
+func (this *Column) FinishValues() {
+	budget := this.table.budget
+	if len(this.runs) == 0 && (budget == nil || budget.chunkSize == 0) {
+		return
+	}
+	if len(this.values) > 0 {
+		this.Spill()
+	}
+	file := MergeRuns(this.runs, budget.Path(this, "values"))
+	this.spilled, this.runs = &file, nil
+	logger.Debugf("spilled the %v distinct values of %v to disk", file.distinct, this.Name())
+}
+
+// EachValue passes the column's distinct values to visit, reading them from
+// disk if they were spilled.
+func (this *Column) EachValue(visit func(value string)) {
+	this.EachValueUntil(func(value string) bool {
+		visit(value)
+		return true
+	})
+}
+
+// EachValueUntil passes the column's distinct values to visit until it
+// returns false, in no particular order.
+func (this *Column) EachValueUntil(visit func(value string) bool) {
+	if this.spilled == nil {
+		for value := range this.values {
+			if !visit(value) {
+				return
+			}
+		}
+		return
+	}
+	cursor, ok := OpenValueFile(this.spilled.path, this)
+	for ok {
+		if !visit(cursor.value) {
+			cursor.Close()
+			return
+		}
+		ok = cursor.Next()
+	}
+}
+
+// EachSortedValue passes the column's distinct values in order to visit until
+// it returns false.
+func (this *Column) EachSortedValue(visit func(value string) bool) {
+	if this.spilled == nil {
+		values := make([]string, 0, len(this.values))
+		for value := range this.values {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			if !visit(value) {
+				return
+			}
+		}
+		return
+	}
+	cursor, ok := OpenValueFile(this.spilled.path, this)
+	for ok {
+		if !visit(cursor.value) {
+			cursor.Close()
+			return
+		}
+		ok = cursor.Next()
+	}
+}
+
+// EachComparedValue passes the column's distinct values in order to visit
+// with whether the other column contains them, until visit returns false.
+// They are merged in order with the other column's value file if its values
+// were spilled, or looked up in its value set.
+func (this *Column) EachComparedValue(other *Column, visit func(value string, contained bool) bool) {
+	var cursor *valueCursor
+	ok := false
+	if other.spilled != nil {
+		if cursor, ok = OpenValueFile(other.spilled.path, other); ok {
+			defer cursor.Close()
+		}
+	}
+	contains := func(value string) bool {
+		if other.spilled == nil {
+			return other.values[value]
+		}
+		for ok && cursor.value < value {
+			ok = cursor.Next()
+		}
+		return ok && cursor.value == value
+	}
+	this.EachSortedValue(func(value string) bool {
+		return visit(value, contains(value))
+	})
+}
+
+// CompareValues counts the distinct values of a and those b contains, when
+// the values of either were spilled. With exact, it stops at the first value
+// b misses.
+func (db Database) CompareValues(candidate *Candidate, ignoreNulls bool, exact bool) (distinct int, included int) {
+	candidate.a.EachComparedValue(candidate.b, func(value string, contained bool) bool {
+		if value == "" && ignoreNulls {
+			return true
+		}
+		distinct++
+		if contained {
+			included++
+			return true
+		}
+		return !exact
+	})
+	return distinct, included
+}
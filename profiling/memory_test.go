@@ -0,0 +1,99 @@
+package profiling
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+)
+
+// testColumn builds a column holding values, spilling them in chunks of
+// chunkSize values, or keeping them in memory for 0.
+func testColumn(t *testing.T, name string, chunkSize int, values ...string) *Column {
+	table := &Table{id: "sales/" + name, name: name}
+	if chunkSize > 0 {
+		table.budget = &MemoryBudget{limit: math.MaxInt64, parent: t.TempDir(), chunkSize: chunkSize}
+	}
+	column := &Column{table: table, id: "c000", name: "value", values: make(map[string]bool)}
+	table.columns = []*Column{column}
+	for _, value := range values {
+		column.AddValue(value)
+	}
+	column.FinishValues()
+	return column
+}
+
+func TestSpilledValues(t *testing.T) {
+	tests := [][]string{
+		nil,
+		{""},
+		{"b", "a", "c"},
+		{"b", "", "a", "b", "a", "c", ""},
+		{"10", "9", "1", "100", "9", "x", "X", "é", "e"},
+		{"with\ttab", "with\nnewline", "with\\backslash", "plain"},
+	}
+	for _, values := range tests {
+		memory := testColumn(t, "memory", 0, values...)
+		var want []string
+		memory.EachSortedValue(func(value string) bool {
+			want = append(want, value)
+			return true
+		})
+		if !sort.StringsAreSorted(want) {
+			t.Errorf("%q: in-memory values %q are not sorted", values, want)
+		}
+		for _, chunkSize := range []int{1, 2, 3, 100} {
+			spilled := testColumn(t, "spilled", chunkSize, values...)
+			if spilled.spilled == nil {
+				t.Errorf("%q in chunks of %v: values were not spilled", values, chunkSize)
+				continue
+			}
+			var got []string
+			spilled.EachSortedValue(func(value string) bool {
+				got = append(got, value)
+				return true
+			})
+			if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+				t.Errorf("%q in chunks of %v: got %q, want %q", values, chunkSize, got, want)
+			}
+			if spilled.DistinctValues() != memory.DistinctValues() {
+				t.Errorf("%q in chunks of %v: got %v distinct values, want %v", values, chunkSize, spilled.DistinctValues(), memory.DistinctValues())
+			}
+		}
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		a           []string
+		b           []string
+		ignoreNulls bool
+		exact       bool
+		distinct    int
+		included    int
+	}{
+		{[]string{"a", "b"}, []string{"a", "b", "c"}, false, false, 2, 2},
+		{[]string{"a", "b"}, []string{"a", "b", "c"}, false, true, 2, 2},
+		{[]string{"a", "d", "b"}, []string{"a", "b", "c"}, false, false, 3, 2},
+		// exact stops at d, the first value b misses in order
+		{[]string{"a", "d", "b", "e"}, []string{"a", "b", "c"}, false, true, 3, 2},
+		{[]string{"d", "a"}, []string{"a", "b", "c"}, false, true, 2, 1},
+		{[]string{"", "a"}, []string{"a"}, false, false, 2, 1},
+		{[]string{"", "a"}, []string{"a"}, true, true, 1, 1},
+		{[]string{"", "a"}, []string{"", "a"}, false, true, 2, 2},
+		// "10" sorts before "9", so b is merged in string order
+		{[]string{"9", "10", "100"}, []string{"1", "10", "9"}, false, false, 3, 2},
+		{[]string{"a"}, nil, false, false, 1, 0},
+		{nil, []string{"a"}, false, true, 0, 0},
+	}
+	for _, test := range tests {
+		for _, chunkSizes := range [][2]int{{0, 0}, {2, 0}, {0, 2}, {1, 3}} {
+			a := testColumn(t, "a", chunkSizes[0], test.a...)
+			b := testColumn(t, "b", chunkSizes[1], test.b...)
+			distinct, included := Database(nil).CompareValues(&Candidate{a, b}, test.ignoreNulls, test.exact)
+			if distinct != test.distinct || included != test.included {
+				t.Errorf("%q <= %q with ignoreNulls %v, exact %v, chunks %v: got %v of %v, want %v of %v", test.a, test.b, test.ignoreNulls, test.exact, chunkSizes, included, distinct, test.included, test.distinct)
+			}
+		}
+	}
+}
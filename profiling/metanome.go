@@ -0,0 +1,167 @@
+package profiling
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// metanomeFileInput mirrors Metanome's ConfigurationSettingFileInput. Unset
+// fields take Metanome's defaults. The null value setting is not supported.
+type metanomeFileInput struct {
+	FileName                string  `json:"fileName"`
+	SeparatorChar           *string `json:"separatorChar"`
+	QuoteChar               *string `json:"quoteChar"`
+	EscapeChar              *string `json:"escapeChar"`
+	StrictQuotes            bool    `json:"strictQuotes"`
+	IgnoreLeadingWhiteSpace *bool   `json:"ignoreLeadingWhiteSpace"`
+	SkipLines               int     `json:"skipLines"`
+	Header                  *bool   `json:"header"`
+	SkipDifferingLines      bool    `json:"skipDifferingLines"`
+}
+
+func MetanomeChar(setting *string, fallback rune) rune {
+	if setting == nil {
+		return fallback
+	}
+	switch *setting {
+	case "":
+		return 0
+	case `\t`:
+		return '\t'
+	}
+	r, _ := utf8.DecodeRuneInString(*setting)
+	return r
+}
+
+func MetanomeBool(setting *bool, fallback bool) bool {
+	if setting == nil {
+		return fallback
+	}
+	return *setting
+}
+
+// ReadMetanomeInput builds the tables from a Metanome relational input
+// configuration: a list of file input settings, an object holding them in
+// "settings", or a single one. Relative file names are resolved against the
+// data directory.
+func ReadMetanomeInput(fileName string, dataDir string) (result Database) {
+	data, err := os.ReadFile(fileName)
+	check(err)
+	var inputs []metanomeFileInput
+	var requirement struct {
+		Settings []metanomeFileInput `json:"settings"`
+	}
+	if err = json.Unmarshal(data, &inputs); err != nil {
+		check(json.Unmarshal(data, &requirement))
+		inputs = requirement.Settings
+		if len(inputs) == 0 {
+			inputs = make([]metanomeFileInput, 1)
+			check(json.Unmarshal(data, &inputs[0]))
+		}
+	}
+	for _, input := range inputs {
+		result = append(result, BuildMetanomeTable(input, dataDir))
+	}
+	return result
+}
+
+func BuildMetanomeTable(input metanomeFileInput, dataDir string) (result *Table) {
+	if input.FileName == "" {
+		panic("metanome input without fileName")
+	}
+	path := input.FileName
+	if !filepath.IsAbs(path) {
+		path = dataDir + path
+	}
+	id := strings.Split(filepath.Base(path), ".")[0]
+	result = &Table{name: id, path: path, id: id, hasHeader: MetanomeBool(input.Header, true)}
+	result.format = &FileFormat{
+		separator:               MetanomeChar(input.SeparatorChar, ','),
+		quote:                   MetanomeChar(input.QuoteChar, '"'),
+		escape:                  MetanomeChar(input.EscapeChar, '\\'),
+		skipLines:               input.SkipLines,
+		strictQuotes:            input.StrictQuotes,
+		ignoreLeadingWhiteSpace: MetanomeBool(input.IgnoreLeadingWhiteSpace, true),
+		skipDifferingLines:      input.SkipDifferingLines,
+	}
+	rows := result.OpenRawRows()
+	firstRow := rows.Read()
+	if result.hasHeader {
+		result.BuildColumns(firstRow)
+	} else {
+		result.BuildColumns(GenerateColumnNames(len(firstRow)))
+	}
+	return result
+}
+
+// Results are written like Metanome's result files: one JSON object per line,
+// naming its result type and the columns by the file of their table.
+type metanomeColumn struct {
+	TableIdentifier  string `json:"tableIdentifier"`
+	ColumnIdentifier string `json:"columnIdentifier"`
+}
+
+type metanomeColumnCombination struct {
+	ColumnIdentifiers []metanomeColumn `json:"columnIdentifiers"`
+}
+
+type metanomeInclusion struct {
+	Type       string                    `json:"type"`
+	Dependant  metanomeColumnCombination `json:"dependant"`
+	Referenced metanomeColumnCombination `json:"referenced"`
+}
+
+type metanomeDependency struct {
+	Type        string                    `json:"type"`
+	Determinant metanomeColumnCombination `json:"determinant"`
+	Dependant   metanomeColumn            `json:"dependant"`
+}
+
+type metanomeUnique struct {
+	Type              string                    `json:"type"`
+	ColumnCombination metanomeColumnCombination `json:"columnCombination"`
+}
+
+func (this *Column) MetanomeColumn() metanomeColumn {
+	return metanomeColumn{filepath.Base(this.table.path), this.name}
+}
+
+func MetanomeColumnCombination(columns []*Column) (result metanomeColumnCombination) {
+	result.ColumnIdentifiers = []metanomeColumn{}
+	for _, column := range columns {
+		result.ColumnIdentifiers = append(result.ColumnIdentifiers, column.MetanomeColumn())
+	}
+	return result
+}
+
+// ExportMetanomeResults writes the inclusions, the n-ary ones included, and
+// the keys and functional dependencies found with -keys and
+// -functional-dependencies.
+func (this *InclusionGraph) ExportMetanomeResults(w io.Writer, db Database) {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, column := range this.nodes {
+		for _, referenced := range this.nodes {
+			if column != referenced && this.adjacencyMatrix[column.index][referenced.index] {
+				check(encoder.Encode(metanomeInclusion{"InclusionDependency", MetanomeColumnCombination([]*Column{column}),
+					MetanomeColumnCombination([]*Column{referenced})}))
+			}
+		}
+	}
+	for _, inclusion := range this.nary {
+		check(encoder.Encode(metanomeInclusion{"InclusionDependency", MetanomeColumnCombination(inclusion.a), MetanomeColumnCombination(inclusion.b)}))
+	}
+	for _, table := range db {
+		for _, key := range table.keys {
+			check(encoder.Encode(metanomeUnique{"UniqueColumnCombination", MetanomeColumnCombination(key)}))
+		}
+		for _, dependency := range table.dependencies {
+			check(encoder.Encode(metanomeDependency{"FunctionalDependency", MetanomeColumnCombination(dependency.determinant),
+				dependency.dependent.MetanomeColumn()}))
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package profiling
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// name tokens marking a column as an identifier, which say nothing about
+// what it identifies
+var idSuffixes = map[string]bool{"id": true, "key": true, "code": true, "no": true, "nr": true, "num": true, "fk": true, "pk": true, "ref": true}
+
+// NameTokens splits a name like customerId or CUSTOMER_ID into lower case
+// words.
+func NameTokens(name string) (tokens []string) {
+	var token []rune
+	previous := ' '
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			r = ' '
+		}
+		if r == ' ' || (unicode.IsUpper(r) && unicode.IsLower(previous)) {
+			if len(token) > 0 {
+				tokens = append(tokens, strings.ToLower(string(token)))
+			}
+			token = nil
+		}
+		if r != ' ' {
+			token = append(token, r)
+		}
+		previous = r
+	}
+	if len(token) > 0 {
+		tokens = append(tokens, strings.ToLower(string(token)))
+	}
+	return tokens
+}
+
+// Subject returns the name's tokens without a trailing id suffix, and a
+// plural s trimmed from the last one, like customer for customers_id.
+func Subject(tokens []string) []string {
+	if len(tokens) > 1 && idSuffixes[tokens[len(tokens)-1]] {
+		tokens = tokens[:len(tokens)-1]
+	}
+	subject := append([]string(nil), tokens...)
+	if last := len(subject) - 1; last >= 0 && len(subject[last]) > 3 {
+		subject[last] = strings.TrimSuffix(subject[last], "s")
+	}
+	return subject
+}
+
+func EditDistance(a string, b string) int {
+	x, y := []rune(a), []rune(b)
+	row := make([]int, len(y)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			next := diagonal + cost
+			if row[j]+1 < next {
+				next = row[j] + 1
+			}
+			if row[j-1]+1 < next {
+				next = row[j-1] + 1
+			}
+			diagonal, row[j] = row[j], next
+		}
+	}
+	return row[len(y)]
+}
+
+// TokenOverlap is the Jaccard similarity of two token lists.
+func TokenOverlap(a []string, b []string) float64 {
+	set := make(map[string]bool)
+	for _, token := range a {
+		set[token] = true
+	}
+	shared, union := 0, len(set)
+	counted := make(map[string]bool)
+	for _, token := range b {
+		if counted[token] {
+			continue
+		}
+		counted[token] = true
+		if set[token] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// PlausibleReference tells whether the heuristic pruning of -candidate-pruning
+// keeps the candidate this <= other: declared types must be compatible, and
+// named columns need a name similarity of at least minSimilarity.
+func (this *Column) PlausibleReference(other *Column, minSimilarity float64) bool {
+	if this.declaredType != nil && other.declaredType != nil && !this.declaredType.CompatibleWith(other.declaredType) {
+		return false
+	}
+	similarity := NameSimilarity(this, other)
+	return similarity < 0 || similarity >= minSimilarity
+}
+
+// NameSimilarity scores from 0 to 1 how much the names of a dependent column
+// and a referenced one suggest a foreign key. A name like customer_id scores
+// 1 for the id of a customers table. Otherwise the names are compared without
+// id suffixes, by their shared tokens or their edit distance. Columns without
+// names, which are only known by their ids, score -1.
+func NameSimilarity(a *Column, b *Column) float64 {
+	if a.name == a.id && b.name == b.id {
+		return -1
+	}
+	subjectA := Subject(NameTokens(a.name))
+	tokensB := NameTokens(b.name)
+	subjectB := Subject(tokensB)
+	// a bare id is named by its table
+	if len(tokensB) == 1 && idSuffixes[tokensB[0]] {
+		subjectB = Subject(NameTokens(b.table.name))
+	}
+	x, y := strings.Join(subjectA, ""), strings.Join(subjectB, "")
+	if x == y {
+		return 1
+	}
+	longest := math.Max(float64(len([]rune(x))), float64(len([]rune(y))))
+	edits := 1 - float64(EditDistance(x, y))/longest
+	return math.Max(TokenOverlap(subjectA, subjectB), edits)
+}
@@ -0,0 +1,211 @@
+package profiling
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NaryInclusion records that every combination of values of the columns a in
+// a row of their table appears in a row of the columns b, in the same order,
+// like a composite foreign key. The columns of a are ordered by index.
+type NaryInclusion struct {
+	a, b []*Column
+}
+
+func (this *NaryInclusion) String() string {
+	names := func(columns []*Column) string {
+		var names []string
+		for _, column := range columns {
+			names = append(names, column.name)
+		}
+		return fmt.Sprintf("%v[%v]", columns[0].table.QualifiedName(), strings.Join(names, ", "))
+	}
+	return names(this.a) + " ⊆ " + names(this.b)
+}
+
+// Key identifies the inclusion by the indexes of its columns.
+func (this *NaryInclusion) Key() string {
+	var key strings.Builder
+	for i := range this.a {
+		fmt.Fprintf(&key, "%v:%v,", this.a[i].index, this.b[i].index)
+	}
+	return key.String()
+}
+
+// Without returns the inclusion of one column less, dropping the i-th.
+func (this *NaryInclusion) Without(i int) *NaryInclusion {
+	result := &NaryInclusion{}
+	result.a = append(append(result.a, this.a[:i]...), this.a[i+1:]...)
+	result.b = append(append(result.b, this.b[:i]...), this.b[i+1:]...)
+	return result
+}
+
+// Combine joins inclusions of n columns agreeing on all but their last
+// column to one of n+1 columns, or returns nil if they cannot be combined.
+func (this *NaryInclusion) Combine(other *NaryInclusion) *NaryInclusion {
+	last := len(this.a) - 1
+	if this.a[0].table != other.a[0].table || this.b[0].table != other.b[0].table || this.a[last].index >= other.a[last].index {
+		return nil
+	}
+	for i := 0; i < last; i++ {
+		if this.a[i] != other.a[i] || this.b[i] != other.b[i] {
+			return nil
+		}
+	}
+	for _, column := range this.b {
+		if column == other.b[last] {
+			return nil
+		}
+	}
+	return &NaryInclusion{append(append([]*Column(nil), this.a...), other.a[last]), append(append([]*Column(nil), this.b...), other.b[last])}
+}
+
+// NextLevel generates the candidates of one more column from the valid
+// inclusions of a level, keeping only those all of whose inclusions of one
+// column less are valid, as in Apriori.
+func NextLevel(level []*NaryInclusion) (candidates []*NaryInclusion) {
+	valid := make(map[string]bool, len(level))
+	for _, inclusion := range level {
+		valid[inclusion.Key()] = true
+	}
+	for _, x := range level {
+		for _, y := range level {
+			candidate := x.Combine(y)
+			if candidate == nil {
+				continue
+			}
+			pruned := false
+			for i := 0; i < len(candidate.a)-2 && !pruned; i++ {
+				pruned = !valid[candidate.Without(i).Key()]
+			}
+			if !pruned {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	return candidates
+}
+
+// ProjectionKey identifies the projection of a table to the columns.
+func ProjectionKey(columns []*Column) string {
+	var key strings.Builder
+	for _, column := range columns {
+		fmt.Fprintf(&key, "%v,", column.index)
+	}
+	return key.String()
+}
+
+// ReadProjections reads the distinct combinations of values of each of the
+// column lists, all of the table, in one pass over the table.
+func (this *Table) ReadProjections(projections [][]*Column) (tuples []map[string]bool) {
+	tuples = make([]map[string]bool, len(projections))
+	for i := range tuples {
+		tuples[i] = make(map[string]bool)
+	}
+	rows := this.OpenRows()
+	values := make([]string, 0, len(this.columns))
+	for {
+		row := rows.Read()
+		if len(row) == 0 {
+			break
+		}
+		for i, columns := range projections {
+			values = values[:0]
+			for _, column := range columns {
+				values = append(values, column.Normalize(row[column.field]))
+			}
+			tuples[i][strings.Join(values, "\x00")] = true
+		}
+	}
+	return tuples
+}
+
+// HasEmptyValue tells whether a combination of values read by
+// ReadProjections has an empty one.
+func HasEmptyValue(tuple string) bool {
+	for _, value := range strings.Split(tuple, "\x00") {
+		if value == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckNary validates the candidates of one level, reading each table once
+// for all projections the candidates need from it.
+//
+// With ignoreNulls, combinations with an empty value need not be included,
+// like SQL's simple match of composite foreign keys.
+func CheckNary(candidates []*NaryInclusion, workers int, ignoreNulls bool) (valid []*NaryInclusion) {
+	projections := make(map[*Table][][]*Column)
+	indexes := make(map[string]int)
+	for _, candidate := range candidates {
+		for _, columns := range [][]*Column{candidate.a, candidate.b} {
+			key := ProjectionKey(columns)
+			if _, ok := indexes[key]; !ok {
+				table := columns[0].table
+				indexes[key] = len(projections[table])
+				projections[table] = append(projections[table], columns)
+			}
+		}
+	}
+	tables := make([]*Table, 0, len(projections))
+	for table := range projections {
+		tables = append(tables, table)
+	}
+	read := make([][]map[string]bool, len(tables))
+	RunWorkers(workers, len(tables), func(i int) {
+		read[i] = tables[i].ReadProjections(projections[tables[i]])
+	})
+	tuples := make(map[string]map[string]bool)
+	for i, table := range tables {
+		for j, columns := range projections[table] {
+			tuples[ProjectionKey(columns)] = read[i][j]
+		}
+	}
+	for _, candidate := range candidates {
+		included := tuples[ProjectionKey(candidate.b)]
+		holds := true
+		for tuple := range tuples[ProjectionKey(candidate.a)] {
+			if ignoreNulls && HasEmptyValue(tuple) {
+				continue
+			}
+			if holds = included[tuple]; !holds {
+				break
+			}
+		}
+		if holds {
+			valid = append(valid, candidate)
+		}
+	}
+	return valid
+}
+
+// FindNaryInclusions extends the graph's inclusions level by level to
+// inclusions of up to -max-arity columns. Only inclusions whose projections
+// to fewer columns all hold are validated.
+func (this *InclusionGraph) FindNaryInclusions(options *Options) {
+	var level []*NaryInclusion
+	for _, a := range this.nodes {
+		for _, b := range this.nodes {
+			if a != b && this.adjacencyMatrix[a.index][b.index] && a.IsSearched(options) && b.IsSearched(options) {
+				level = append(level, &NaryInclusion{[]*Column{a}, []*Column{b}})
+			}
+		}
+	}
+	this.nary = nil
+	for arity := 2; arity <= options.maxArity && len(level) > 1; arity++ {
+		candidates := NextLevel(level)
+		logger.Infof("validating %v candidates of %v columns", len(candidates), arity)
+		level = CheckNary(candidates, options.validationWorkers, options.IgnoreNulls())
+		this.nary = append(this.nary, level...)
+	}
+}
+
+func (this *InclusionGraph) PrintNaryInclusions() {
+	fmt.Println("found", len(this.nary), "n-ary inclusions")
+	status.Result("n-ary inclusions", len(this.nary))
+	for _, inclusion := range this.nary {
+		fmt.Println(inclusion)
+	}
+}
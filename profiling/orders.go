@@ -0,0 +1,193 @@
+package profiling
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// An order dependency A ↦ B holds if sorting a table by A also sorts it by B,
+// ascending or descending, so that a query ordering by B may use an index on
+// A. Rows agreeing on A must then agree on B as well. Values are ordered as
+// numbers, dates or text by their columns' types, and rows with an empty A or
+// B are left out, as databases sort NULL first or last. Dependencies on or of
+// constant columns are not reported, as any order sorts those.
+
+type OrderDependency struct {
+	ordering   *Column
+	ordered    *Column
+	descending bool
+}
+
+func (this *OrderDependency) String() string {
+	direction := "ascending"
+	if this.descending {
+		direction = "descending"
+	}
+	return fmt.Sprintf("%v[%v] ↦ %v %v", this.ordering.table.QualifiedName(), this.ordering.name, this.ordered.name, direction)
+}
+
+// LessValue orders two values of a column's type, with values not of the
+// type after those that are, in text order.
+func LessValue(dataType string, a string, b string) bool {
+	switch dataType {
+	case "int", "float":
+		x, errA := strconv.ParseFloat(a, 64)
+		y, errB := strconv.ParseFloat(b, 64)
+		if errA == nil && errB == nil {
+			return x < y
+		}
+		if (errA == nil) != (errB == nil) {
+			return errA == nil
+		}
+	case "date":
+		x, layoutA := ParseDate(a)
+		y, layoutB := ParseDate(b)
+		if layoutA >= 0 && layoutB >= 0 {
+			return x.Before(y)
+		}
+		if (layoutA >= 0) != (layoutB >= 0) {
+			return layoutA >= 0
+		}
+	}
+	return a < b
+}
+
+// ReadRanks reads each column's values as their ranks in the column's order,
+// equal values having equal ranks and empty ones -1.
+func (this *Table) ReadRanks() (ranks [][]int32) {
+	ids := make([]map[string]int32, len(this.columns))
+	values := make([][]string, len(this.columns))
+	ranks = make([][]int32, len(this.columns))
+	for i := range this.columns {
+		ids[i] = make(map[string]int32)
+	}
+	rows := this.OpenRows()
+	for {
+		fields := rows.Read()
+		if len(fields) == 0 {
+			break
+		}
+		for i, column := range this.columns {
+			value := column.Normalize(fields[column.field])
+			if value == "" {
+				ranks[i] = append(ranks[i], -1)
+				continue
+			}
+			id, ok := ids[i][value]
+			if !ok {
+				id = int32(len(values[i]))
+				ids[i][value] = id
+				values[i] = append(values[i], value)
+			}
+			ranks[i] = append(ranks[i], id)
+		}
+	}
+	for i, column := range this.columns {
+		order := make([]int32, len(values[i]))
+		for id := range order {
+			order[id] = int32(id)
+		}
+		sort.Slice(order, func(x, y int) bool {
+			return LessValue(column.dataType, values[i][order[x]], values[i][order[y]])
+		})
+		rank := make([]int32, len(order))
+		for position, id := range order {
+			rank[id] = int32(position)
+		}
+		for row, id := range ranks[i] {
+			if id >= 0 {
+				ranks[i][row] = rank[id]
+			}
+		}
+	}
+	return ranks
+}
+
+// FindOrderDependencies checks A ↦ B for all pairs of non-constant columns,
+// walking the rows sorted by A once for all B.
+func (this *Table) FindOrderDependencies() {
+	this.orders = nil
+	if this.rowCount == 0 {
+		return
+	}
+	ranks := this.ReadRanks()
+	constant := make([]bool, len(this.columns))
+	for i := range this.columns {
+		first := int32(-1)
+		constant[i] = true
+		for _, rank := range ranks[i] {
+			if rank >= 0 && first >= 0 && rank != first {
+				constant[i] = false
+				break
+			}
+			if rank >= 0 {
+				first = rank
+			}
+		}
+	}
+	for a, ordering := range this.columns {
+		if constant[a] {
+			continue
+		}
+		rows := make([]int32, 0, len(ranks[a]))
+		for row, rank := range ranks[a] {
+			if rank >= 0 {
+				rows = append(rows, int32(row))
+			}
+		}
+		sort.SliceStable(rows, func(x, y int) bool { return ranks[a][rows[x]] < ranks[a][rows[y]] })
+		for b, ordered := range this.columns {
+			if a == b || constant[b] {
+				continue
+			}
+			ascending, descending, changed := true, true, false
+			// B of the previous row with a non-empty B, and whether it was
+			// of the same A
+			previous, previousA := int32(-1), int32(-1)
+			for _, row := range rows {
+				rank := ranks[b][row]
+				if rank < 0 {
+					continue
+				}
+				if previous >= 0 && rank != previous {
+					if ranks[a][row] == previousA {
+						ascending, descending = false, false
+						break
+					}
+					changed = true
+					ascending = ascending && rank > previous
+					descending = descending && rank < previous
+					if !ascending && !descending {
+						break
+					}
+				}
+				previous, previousA = rank, ranks[a][row]
+			}
+			if changed && (ascending || descending) {
+				this.orders = append(this.orders, &OrderDependency{ordering, ordered, !ascending})
+			}
+		}
+	}
+}
+
+// FindOrderDependencies searches the order dependencies of all tables.
+func (db Database) FindOrderDependencies(options *Options) {
+	RunWorkers(options.analysisWorkers, len(db), func(i int) {
+		db[i].FindOrderDependencies()
+	})
+}
+
+func (db Database) PrintOrderDependencies() {
+	count := 0
+	for _, table := range db {
+		count += len(table.orders)
+	}
+	fmt.Println("found", count, "order dependencies")
+	status.Result("order dependencies", count)
+	for _, table := range db {
+		for _, order := range table.orders {
+			fmt.Println(order)
+		}
+	}
+}
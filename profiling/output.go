@@ -0,0 +1,60 @@
+package profiling
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+const (
+	cyan   = "36"
+	yellow = "33"
+)
+
+// whether stdout is a terminal, told at the start since -tui holds back what
+// is printed in a pipe
+var terminalOutput = IsTerminal(os.Stdout)
+
+// colors are only used on terminals, and never when NO_COLOR is set
+// (https://no-color.org)
+var useColor = terminalOutput && os.Getenv("NO_COLOR") == ""
+
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Colorize wraps s in an ANSI color escape. Cells of one column must share a
+// color, so that tabwriter's alignment is not skewed by the escape lengths.
+func Colorize(s string, color string) string {
+	if !useColor {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+type Output interface {
+	Write(p []byte) (int, error)
+	Flush() error
+}
+
+// NewOutput returns a buffered writer to stdout which aligns tab separated
+// cells into columns if align is set and keeps the tabs otherwise.
+func NewOutput(align bool) Output {
+	if align {
+		return tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	}
+	return bufio.NewWriter(os.Stdout)
+}
+
+// WriteOutput creates fileName and fills it using write.
+func WriteOutput(fileName string, write func(w io.Writer)) {
+	file, err := os.Create(fileName)
+	check(err)
+	w := bufio.NewWriter(file)
+	write(w)
+	check(w.Flush())
+	check(file.Close())
+	status.Output(fileName)
+}
@@ -0,0 +1,98 @@
+package profiling
+
+import (
+	"fmt"
+	"github.com/parquet-go/parquet-go"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type columnStatisticsRow struct {
+	ProfiledAt     time.Time `parquet:"profiled_at,timestamp(millisecond)"`
+	DataDir        string    `parquet:"data_dir"`
+	Table          string    `parquet:"table"`
+	Column         string    `parquet:"column"`
+	ColumnID       string    `parquet:"column_id"`
+	Alias          *string   `parquet:"alias,optional"`
+	PII            *string   `parquet:"pii,optional"`
+	PIIConfidence  *float64  `parquet:"pii_confidence,optional"`
+	DataType       string    `parquet:"data_type"`
+	Rows           int64     `parquet:"rows"`
+	DistinctValues int64     `parquet:"distinct_values"`
+	Nulls          int64     `parquet:"nulls"`
+	Minimum        string    `parquet:"minimum"`
+	Maximum        string    `parquet:"maximum"`
+	Average        *float64  `parquet:"average,optional"`
+	AverageLength  *float64  `parquet:"average_length,optional"`
+	Shortest       *string   `parquet:"shortest,optional"`
+	Longest        *string   `parquet:"longest,optional"`
+}
+
+type inclusionRow struct {
+	ProfiledAt       time.Time `parquet:"profiled_at,timestamp(millisecond)"`
+	DataDir          string    `parquet:"data_dir"`
+	DependentTable   string    `parquet:"dependent_table"`
+	DependentColumn  string    `parquet:"dependent_column"`
+	ReferencedTable  string    `parquet:"referenced_table"`
+	ReferencedColumn string    `parquet:"referenced_column"`
+	ReferencedUnique bool      `parquet:"referenced_unique"`
+}
+
+func (this *Column) StatisticsRow(options *Options) (row columnStatisticsRow) {
+	row = columnStatisticsRow{ProfiledAt: options.started, DataDir: options.dataDir, Table: this.table.QualifiedName(), Column: this.name,
+		ColumnID: this.String(), DataType: this.dataType, Rows: int64(this.table.rowCount), DistinctValues: int64(this.DistinctValues()),
+		Nulls: int64(this.nulls)}
+	if this.alias != "" {
+		row.Alias = &this.alias
+	}
+	if this.pii != "" {
+		row.PII, row.PIIConfidence = &this.pii, &this.piiConfidence
+	}
+	switch stats := this.stats.(type) {
+	case *intStatistics:
+		row.Minimum, row.Maximum = Redact(fmt.Sprint(stats.minimum)), Redact(fmt.Sprint(stats.maximum))
+		row.Average = &stats.average
+	case *floatStatistics:
+		row.Minimum, row.Maximum = Redact(FormatFloat(stats.minimum)), Redact(FormatFloat(stats.maximum))
+		row.Average = &stats.average
+	case *dateStatistics:
+		row.Minimum, row.Maximum = Redact(stats.Format(stats.minimum)), Redact(stats.Format(stats.maximum))
+	case *stringStatistics:
+		shortest, longest := Redact(stats.shortest), Redact(stats.longest)
+		row.Minimum, row.Maximum = Redact(stats.minimum), Redact(stats.maximum)
+		row.AverageLength, row.Shortest, row.Longest = &stats.averageLength, &shortest, &longest
+	}
+	return row
+}
+
+// ParquetFileName names the files of one run after its start time, so that
+// a directory collects the history of all runs.
+func ParquetFileName(options *Options, name string) string {
+	return filepath.Join(options.parquetDir, name+"-"+options.started.UTC().Format("20060102T150405Z")+".parquet")
+}
+
+func (db Database) ExportStatisticsParquet(options *Options) {
+	var rows []columnStatisticsRow
+	for _, column := range db.AllColumns() {
+		rows = append(rows, column.StatisticsRow(options))
+	}
+	check(os.MkdirAll(options.parquetDir, 0755))
+	check(parquet.WriteFile(ParquetFileName(options, "column_statistics"), rows))
+	status.Output(ParquetFileName(options, "column_statistics"))
+}
+
+func (this *InclusionGraph) ExportParquet(options *Options) {
+	rows := []inclusionRow{}
+	for _, column := range this.nodes {
+		for _, referenced := range this.nodes {
+			if column != referenced && this.adjacencyMatrix[column.index][referenced.index] {
+				rows = append(rows, inclusionRow{options.started, options.dataDir, column.table.QualifiedName(), column.name,
+					referenced.table.QualifiedName(), referenced.name, referenced.IsUnique()})
+			}
+		}
+	}
+	check(os.MkdirAll(options.parquetDir, 0755))
+	check(parquet.WriteFile(ParquetFileName(options, "inclusions"), rows))
+	status.Output(ParquetFileName(options, "inclusions"))
+}
@@ -0,0 +1,166 @@
+package profiling
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A mapped file may be a directory of Hive style partitions such as
+// sales/year=2024/month=01/part-0.tsv. All files below the directory are read
+// as one table, and every partition key becomes a virtual column holding the
+// partition's value for each of its rows.
+
+// Hive writes this value for partitions of rows whose key is null
+const hiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+type Partition struct {
+	path   string
+	values []string
+}
+
+// DiscoverPartitions lists the data files below root with the values of the
+// key=value directories leading to them. Files starting with "." or "_", like
+// Hadoop's _SUCCESS markers, are skipped.
+func DiscoverPartitions(root string) (keys []string, partitions []*Partition, err error) {
+	first := true
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") || strings.HasPrefix(entry.Name(), "_") {
+			if entry.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !entry.Type().IsRegular() {
+			return nil
+		}
+		relative, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		partition := &Partition{path: path}
+		var partitionKeys []string
+		if relative != "." {
+			for _, directory := range strings.Split(relative, string(filepath.Separator)) {
+				key, value, ok := strings.Cut(directory, "=")
+				if !ok || key == "" {
+					return fmt.Errorf("%v is not a key=value partition directory", filepath.Join(root, relative))
+				}
+				if unescaped, err := url.PathUnescape(value); err == nil {
+					value = unescaped
+				}
+				if value == hiveDefaultPartition {
+					value = ""
+				}
+				partitionKeys = append(partitionKeys, key)
+				partition.values = append(partition.values, value)
+			}
+		}
+		if first {
+			keys, first = partitionKeys, false
+		} else if strings.Join(partitionKeys, "/") != strings.Join(keys, "/") {
+			return fmt.Errorf("%v is partitioned by %v, but %v by %v", path, strings.Join(partitionKeys, ", "), partitions[0].path, strings.Join(keys, ", "))
+		}
+		partitions = append(partitions, partition)
+		return nil
+	})
+	if err == nil && len(partitions) == 0 {
+		err = fmt.Errorf("%v contains no data files", root)
+	}
+	return keys, partitions, err
+}
+
+// IsPartitioned reports whether path is a directory of partitions.
+func IsPartitioned(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// PartitionKeys returns the names of the table's virtual partition columns.
+func (this *Table) PartitionKeys() (keys []string) {
+	for _, column := range this.columns {
+		if column.partition {
+			keys = append(keys, column.name)
+		}
+	}
+	return keys
+}
+
+// PartitionPaths returns the files of the table's selected partitions.
+func (this *Table) PartitionPaths() (paths []string) {
+	for _, partition := range this.partitions {
+		paths = append(paths, partition.path)
+	}
+	return paths
+}
+
+// ParsePartitionFilter parses comma separated key=value pairs. Naming a key
+// several times selects each of its values.
+func ParsePartitionFilter(filter string) (result map[string]map[string]bool) {
+	result = make(map[string]map[string]bool)
+	for _, pair := range strings.Split(filter, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			panic("partition filter " + pair + " is not a key=value pair")
+		}
+		if result[key] == nil {
+			result[key] = make(map[string]bool)
+		}
+		result[key][value] = true
+	}
+	return result
+}
+
+// SelectPartitions drops the partitions not matching the filter. Keys the
+// table is not partitioned by are ignored, so one filter serves all tables.
+func (this *Table) SelectPartitions(filter map[string]map[string]bool) {
+	keys := this.PartitionKeys()
+	selected := []*Partition{}
+	for _, partition := range this.partitions {
+		matches := true
+		for i, key := range keys {
+			if values, ok := filter[key]; ok && !values[partition.values[i]] {
+				matches = false
+			}
+		}
+		if matches {
+			selected = append(selected, partition)
+		}
+	}
+	this.partitions = selected
+}
+
+// SelectPartitions returns the tables with the partitions matching the
+// filter, leaving out partitioned tables none of whose partitions match.
+func (db Database) SelectPartitions(filter string) (result Database) {
+	parsed := ParsePartitionFilter(filter)
+	used := make(map[string]bool)
+	for _, table := range db {
+		if table.partitions == nil {
+			result = append(result, table)
+			continue
+		}
+		for _, key := range table.PartitionKeys() {
+			used[key] = true
+		}
+		table.SelectPartitions(parsed)
+		if len(table.partitions) == 0 {
+			logger.Infof("no partition of %v matches -partitions, leaving the table out", table.QualifiedName())
+			continue
+		}
+		logger.Infof("selected %v partitions of %v", len(table.partitions), table.QualifiedName())
+		result = append(result, table)
+	}
+	for key := range parsed {
+		if !used[key] {
+			panic("no table is partitioned by " + key)
+		}
+	}
+	return result
+}
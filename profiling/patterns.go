@@ -0,0 +1,134 @@
+package profiling
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// String columns count the masks of their values, in which upper case
+// letters become A, other letters a, digits 9, and all other characters stay
+// as they are, so that the formats of identifiers, phone numbers or codes,
+// like AAA-9999, stand out along with the values deviating from them. Masks
+// are clustered by the regular expression they match when runs of a class
+// may have any length, like [A-Z]+-[0-9]+. Masks are cut after
+// maxPatternLength characters, and once a column has maxPatterns different
+// ones, values of new masks are only counted as others.
+
+const maxPatterns = 1000
+
+const maxPatternLength = 64
+
+// number of masks and clusters reported per column
+const reportedPatterns = 5
+
+type patternResult struct {
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+}
+
+// PatternMask returns the mask of a value.
+func PatternMask(value string) string {
+	var mask strings.Builder
+	length := 0
+	for _, r := range value {
+		if length == maxPatternLength {
+			mask.WriteRune('…')
+			break
+		}
+		switch {
+		case unicode.IsUpper(r):
+			mask.WriteRune('A')
+		case unicode.IsLetter(r):
+			mask.WriteRune('a')
+		case unicode.IsDigit(r):
+			mask.WriteRune('9')
+		default:
+			mask.WriteRune(r)
+		}
+		length++
+	}
+	return mask.String()
+}
+
+// PatternRegex returns the regular expression of a mask's cluster.
+func PatternRegex(mask string) string {
+	classes := map[rune]string{'A': "[A-Z]", 'a': "[a-z]", '9': "[0-9]", '…': ".*"}
+	var regex strings.Builder
+	previous := rune(0)
+	for _, r := range mask {
+		class, ok := classes[r]
+		if !ok {
+			regex.WriteString(regexp.QuoteMeta(string(r)))
+		} else if r != previous {
+			regex.WriteString(class)
+			if r != '…' {
+				regex.WriteRune('+')
+			}
+		}
+		previous = r
+	}
+	return regex.String()
+}
+
+func (this *stringStatistics) AddPattern(value string) {
+	if this.patterns == nil {
+		this.patterns = make(map[string]int)
+	}
+	mask := PatternMask(value)
+	if _, ok := this.patterns[mask]; ok || len(this.patterns) < maxPatterns {
+		this.patterns[mask]++
+	} else {
+		this.otherPatterns++
+	}
+}
+
+// TopPatterns returns the most frequent masks and clusters, most frequent
+// first.
+func (this *stringStatistics) TopPatterns() (masks []patternResult, clusters []patternResult) {
+	counts := make(map[string]int)
+	for mask, count := range this.patterns {
+		masks = append(masks, patternResult{mask, count})
+		counts[PatternRegex(mask)] += count
+	}
+	for regex, count := range counts {
+		clusters = append(clusters, patternResult{regex, count})
+	}
+	return TopPatternResults(masks), TopPatternResults(clusters)
+}
+
+func TopPatternResults(results []patternResult) []patternResult {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Count != results[j].Count {
+			return results[i].Count > results[j].Count
+		}
+		return results[i].Pattern < results[j].Pattern
+	})
+	if len(results) > reportedPatterns {
+		results = results[:reportedPatterns]
+	}
+	return results
+}
+
+// PatternSummary describes the most frequent mask and its share of the
+// values, and how many masks there are.
+func (this *stringStatistics) PatternSummary() string {
+	masks, _ := this.TopPatterns()
+	if len(masks) == 0 {
+		return "-"
+	}
+	total := this.otherPatterns
+	for _, count := range this.patterns {
+		total += count
+	}
+	summary := fmt.Sprintf("%v %.0f%%", masks[0].Pattern, 100*float64(masks[0].Count)/float64(total))
+	if others := len(this.patterns) - 1; others > 0 || this.otherPatterns > 0 {
+		summary += fmt.Sprintf(" +%v", others)
+		if this.otherPatterns > 0 {
+			summary += "+"
+		}
+	}
+	return summary
+}
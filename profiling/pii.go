@@ -0,0 +1,146 @@
+package profiling
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// number of distinct values of a column checked for personal data
+const piiSampleSize = 1000
+
+// share of checked values that must match for a column to be tagged
+const piiThreshold = 0.8
+
+type piiDetector struct {
+	kind string
+	// column names hinting at the kind, which some kinds require as values
+	// alone are ambiguous
+	hint        *regexp.Regexp
+	requireHint bool
+	matches     func(value string) bool
+}
+
+var (
+	emailPattern     = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[A-Za-z]{2,}$`)
+	phonePattern     = regexp.MustCompile(`^(\+|00)[1-9][0-9 ()/.-]{6,}$|^\(?[0-9]{3}\)?[ .-][0-9]{3}[ .-][0-9]{4}$`)
+	digitsPattern    = regexp.MustCompile(`^[0-9 ()/.+-]{7,20}$`)
+	ssnPattern       = regexp.MustCompile(`^([0-9]{3})-([0-9]{2})-([0-9]{4})$`)
+	ibanPattern      = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+	personPattern    = regexp.MustCompile(`^\p{Lu}[\p{L}'.-]*( \p{Lu}[\p{L}'.-]*)*$`)
+	separatorRemover = strings.NewReplacer(" ", "", "-", "")
+)
+
+var piiDetectors = []piiDetector{
+	{"email", regexp.MustCompile(`(?i)e-?mail`), false, emailPattern.MatchString},
+	{"credit_card", regexp.MustCompile(`(?i)card|cc_?num|(^|_)pan($|_)`), false, IsCreditCardNumber},
+	{"national_id", regexp.MustCompile(`(?i)ssn|social|national`), false, IsSocialSecurityNumber},
+	{"iban", regexp.MustCompile(`(?i)iban|account`), false, IsIBAN},
+	{"phone", regexp.MustCompile(`(?i)phone|mobile|(^|_)(tel|fax)($|_)`), false, phonePattern.MatchString},
+	{"phone", regexp.MustCompile(`(?i)phone|mobile|(^|_)(tel|fax)($|_)`), true, IsPhoneDigits},
+	{"person_name", regexp.MustCompile(`(?i)(^|_|\b)(first|last|given|sur|full|middle|customer|person|contact)?_?name($|_|\b)`), true, personPattern.MatchString},
+}
+
+// IsCreditCardNumber checks the length and the Luhn checksum of value.
+func IsCreditCardNumber(value string) bool {
+	digits := separatorRemover.Replace(value)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		if (len(digits)-i)%2 == 0 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// IsSocialSecurityNumber checks the format and the unassigned area, group
+// and serial numbers of US social security numbers.
+func IsSocialSecurityNumber(value string) bool {
+	parts := ssnPattern.FindStringSubmatch(value)
+	return parts != nil && parts[1] != "000" && parts[1] != "666" && parts[1][0] != '9' && parts[2] != "00" && parts[3] != "0000"
+}
+
+// IsIBAN checks the format and the mod 97 checksum of value.
+func IsIBAN(value string) bool {
+	value = strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	if !ibanPattern.MatchString(value) {
+		return false
+	}
+	var digits strings.Builder
+	for _, r := range value[4:] + value[:4] {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(big.NewInt(int64(r - 'A' + 10)).String())
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+	number, _ := new(big.Int).SetString(digits.String(), 10)
+	return new(big.Int).Mod(number, big.NewInt(97)).Int64() == 1
+}
+
+// IsPhoneDigits accepts phone numbers without any formatting, which only a
+// column name can tell from other numbers.
+func IsPhoneDigits(value string) bool {
+	digits := 0
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digitsPattern.MatchString(value) && digits >= 7 && digits <= 15
+}
+
+// DetectPII tags the column with the kind of personal data most of its
+// values look like, with the share of matching values as confidence.
+func (this *Column) DetectPII() {
+	var sample []string
+	this.EachValueUntil(func(value string) bool {
+		if value != "" {
+			sample = append(sample, value)
+			if len(sample) == piiSampleSize {
+				return false
+			}
+		}
+		return true
+	})
+	if len(sample) == 0 {
+		return
+	}
+	for _, detector := range piiDetectors {
+		hinted := detector.hint.MatchString(this.name)
+		if detector.requireHint && !hinted {
+			continue
+		}
+		matches := 0
+		for _, value := range sample {
+			if detector.matches(value) {
+				matches++
+			}
+		}
+		confidence := float64(matches) / float64(len(sample))
+		if hinted {
+			// a telling name makes up for some values not matching
+			confidence = (confidence + 1) / 2
+		}
+		if confidence >= piiThreshold && confidence > this.piiConfidence {
+			this.pii, this.piiConfidence = detector.kind, confidence
+		}
+	}
+}
+
+func (db Database) DetectPII() {
+	for _, column := range db.AllColumns() {
+		column.DetectPII()
+	}
+}
@@ -0,0 +1,133 @@
+package profiling
+
+import (
+	"sync"
+)
+
+// Candidates are validated by a pool of workers while the calling goroutine
+// schedules them and adds the results to the graph, so only it touches the
+// graph and the columns' candidates, and the workers only read values. A
+// candidate that an inclusion still being validated may imply by the
+// transitive closure is held back until that validation is done, so that
+// validating concurrently prunes as many candidates as one by one.
+
+type validationResult struct {
+	candidate *Candidate
+	holds     bool
+	coverage  float64
+	// panic of the validator, re-raised by the scheduling goroutine
+	failure interface{}
+}
+
+// MayImply tells whether adding any of the inclusions being validated to the
+// graph may imply the candidate a <= b, as it would for c <= d with a <= c
+// and d <= b.
+func (this *InclusionGraph) MayImply(pending []*Candidate, candidate *Candidate) bool {
+	if this.coverage != nil {
+		return false
+	}
+	a, b := candidate.a.index, candidate.b.index
+	for _, other := range pending {
+		c, d := other.a.index, other.b.index
+		if (a == c || this.adjacencyMatrix[a][c]) && (d == b || this.adjacencyMatrix[d][b]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateCandidates validates the candidates in the scheduler's order with
+// up to workers at once, calling after with the number validated and the
+// candidates taken from the columns but not decided yet once each result is
+// added.
+func (db Database) ValidateCandidates(graph *InclusionGraph, validator Validator, scheduler Scheduler, workers int, minCoverage float64,
+	after func(validated int, pending []*Candidate)) (validated int) {
+	if !validator.Parallel() {
+		workers = 1
+	}
+	jobs := make(chan *Candidate)
+	// room for every result, so workers never wait for the scheduler
+	results := make(chan validationResult, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for candidate := range jobs {
+				results <- Validate(validator, candidate, graph.coverage != nil)
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+	var running []*Candidate
+	var held *Candidate
+	for {
+		for len(running) < workers {
+			candidate := held
+			if held = nil; candidate == nil {
+				candidate = db.NextCandidate(scheduler)
+			}
+			if candidate == nil {
+				break
+			}
+			if graph.MayImply(running, candidate) {
+				held = candidate
+				break
+			}
+			running = append(running, candidate)
+			jobs <- candidate
+		}
+		if len(running) == 0 {
+			return validated
+		}
+		result := <-results
+		if result.failure != nil {
+			panic(result.failure)
+		}
+		for i, candidate := range running {
+			if candidate == result.candidate {
+				running = append(running[:i], running[i+1:]...)
+				break
+			}
+		}
+		validated++
+		monitor.Advance()
+		if graph.coverage != nil {
+			if result.coverage >= minCoverage {
+				graph.AddApproximate(result.candidate, result.coverage)
+			}
+		} else if result.holds {
+			graph.Add(result.candidate)
+			// the transitive closure removes candidates that need no validation
+			monitor.SetTotal(validated + len(running) + db.CandidateCount())
+		} else {
+			graph.refuted = append(graph.refuted, result.candidate)
+		}
+		if held != nil && graph.adjacencyMatrix[held.a.index][held.b.index] {
+			held = nil
+		}
+		pending := append([]*Candidate{}, running...)
+		if held != nil {
+			pending = append(pending, held)
+		}
+		after(validated, pending)
+	}
+}
+
+// Validate checks a candidate, or with -min-coverage measures its coverage,
+// catching the validator's panics.
+func Validate(validator Validator, candidate *Candidate, approximate bool) (result validationResult) {
+	result.candidate = candidate
+	defer func() {
+		result.failure = recover()
+	}()
+	if approximate {
+		result.coverage = validator.Coverage(candidate)
+	} else {
+		result.holds = validator.Check(candidate)
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+package profiling
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PrefixInclusion records that every value of a is a prefix of a value of b,
+// as with code hierarchies like DE and DE-BY, without a being included in b.
+type PrefixInclusion struct {
+	a, b *Column
+}
+
+func (this *PrefixInclusion) String() string {
+	return fmt.Sprintf("%v ⊑ %v", this.a.Label(), this.b.Label())
+}
+
+// SortedValues returns the column's non-empty distinct values in order.
+func (this *Column) SortedValues() (values []string) {
+	this.EachSortedValue(func(value string) bool {
+		if value != "" {
+			values = append(values, value)
+		}
+		return true
+	})
+	return values
+}
+
+// IsPrefixIncluded reports whether every value of a is a prefix of a value of
+// b and at least one of them a proper one. Both must be sorted. A value
+// prefixing any value of b prefixes the smallest one not before it.
+func IsPrefixIncluded(a []string, b []string) bool {
+	proper := false
+	for _, value := range a {
+		i := sort.SearchStrings(b, value)
+		if i == len(b) || !strings.HasPrefix(b[i], value) {
+			return false
+		}
+		if b[i] != value {
+			proper = true
+		}
+	}
+	return proper
+}
+
+// FindPrefixInclusions checks every pair of searched string columns the graph
+// has no inclusion for. Constant columns are left out, as their value
+// prefixes others by chance.
+func (this *InclusionGraph) FindPrefixInclusions(options *Options) {
+	var columns []*Column
+	var values [][]string
+	for _, column := range this.nodes {
+		if column.dataType == "string" && column.IsSearched(options) && column.DistinctValues() > 1 {
+			columns = append(columns, column)
+			values = append(values, column.SortedValues())
+		}
+	}
+	found := make([][]*PrefixInclusion, len(columns))
+	RunWorkers(options.validationWorkers, len(columns), func(i int) {
+		for j, b := range columns {
+			a := columns[i]
+			if a != b && !this.adjacencyMatrix[a.index][b.index] && IsPrefixIncluded(values[i], values[j]) {
+				found[i] = append(found[i], &PrefixInclusion{a, b})
+			}
+		}
+	})
+	this.prefixes = nil
+	for _, inclusions := range found {
+		this.prefixes = append(this.prefixes, inclusions...)
+	}
+}
+
+func (this *InclusionGraph) PrintPrefixInclusions() {
+	fmt.Println("found", len(this.prefixes), "prefix inclusions")
+	for _, inclusion := range this.prefixes {
+		fmt.Println(inclusion)
+	}
+}
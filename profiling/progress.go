@@ -0,0 +1,129 @@
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Logger writes messages about the run, as opposed to its results, and the
+// progress of the current phase to stderr, so stdout stays parseable.
+// -quiet leaves only errors, -verbose adds what each worker is doing.
+type Logger struct {
+	mutex   sync.Mutex
+	out     io.Writer
+	level   int
+	started time.Time
+	// whether the progress line was drawn on a terminal and must be cleared
+	// before the next message
+	drawn bool
+	stop  chan bool
+	done  chan bool
+}
+
+const (
+	quietLevel = iota
+	normalLevel
+	verboseLevel
+)
+
+var logger = &Logger{out: os.Stderr, level: normalLevel, started: time.Now()}
+
+func (this *Logger) SetLevel(quiet bool, verbose bool) {
+	this.level = normalLevel
+	if quiet {
+		this.level = quietLevel
+	} else if verbose {
+		this.level = verboseLevel
+	}
+}
+
+func (this *Logger) Quiet() bool {
+	return this.level == quietLevel
+}
+
+// write prefixes the message with the time since the start.
+func (this *Logger) write(level string, message string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.drawn {
+		fmt.Fprint(this.out, "\r\x1b[K")
+		this.drawn = false
+	}
+	fmt.Fprintf(this.out, "%8.1fs %v %v\n", time.Since(this.started).Seconds(), level, message)
+}
+
+func (this *Logger) Infof(format string, arguments ...interface{}) {
+	if this.level >= normalLevel {
+		this.write("info", fmt.Sprintf(format, arguments...))
+	}
+}
+
+// Errorf reports a failure the run continues after, even with -quiet.
+func (this *Logger) Errorf(format string, arguments ...interface{}) {
+	this.write("error", fmt.Sprintf(format, arguments...))
+}
+
+func (this *Logger) Debugf(format string, arguments ...interface{}) {
+	if this.level >= verboseLevel {
+		this.write("debug", fmt.Sprintf(format, arguments...))
+	}
+}
+
+// StartProgress reports the progress of the monitor's current phase: on a
+// terminal by redrawing a line every second, otherwise by a message every
+// ten seconds.
+func (this *Logger) StartProgress() {
+	if this.level == quietLevel {
+		return
+	}
+	terminal := IsTerminal(os.Stderr)
+	interval := 10 * time.Second
+	if terminal {
+		interval = time.Second
+	}
+	this.stop, this.done = make(chan bool), make(chan bool)
+	go func() {
+		defer close(this.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-this.stop:
+				return
+			}
+			phase, done, total := monitor.Progress()
+			if total == 0 {
+				continue
+			}
+			progress := fmt.Sprintf("%v: %v of %v (%.0f%%)", phase, done, total, 100*float64(done)/float64(total))
+			if !terminal {
+				this.write("info", progress)
+				continue
+			}
+			this.mutex.Lock()
+			fmt.Fprintf(this.out, "\r\x1b[K%8.1fs %v", time.Since(this.started).Seconds(), progress)
+			this.drawn = true
+			this.mutex.Unlock()
+		}
+	}()
+}
+
+// StopProgress stops reporting and clears the progress line.
+func (this *Logger) StopProgress() {
+	if this.stop == nil {
+		return
+	}
+	close(this.stop)
+	<-this.done
+	this.stop = nil
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.drawn {
+		fmt.Fprint(this.out, "\r\x1b[K")
+		this.drawn = false
+	}
+}
@@ -0,0 +1,126 @@
+package profiling
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// share of non-empty values that must parse for a column to keep its
+// quantity profile
+const quantityThreshold = 0.9
+
+// a number with a currency or unit before or after it, like $1,234.50,
+// EUR 12 or 12kg
+var quantityPattern = regexp.MustCompile(`^([^\d\s.,+-]{1,3})?\s*([+-]?\d[\d.,' ]*\d|[+-]?\d)\s*([^\d\s.,]{1,5})?$`)
+
+// QuantityProfile holds numeric statistics of values carrying a currency or
+// unit, which would otherwise only get string statistics.
+type QuantityProfile struct {
+	units            map[string]int
+	count, invalid   int
+	minimum, maximum float64
+	sum              float64
+}
+
+// ParseQuantity splits a value like $1,234.50 into its number and unit.
+func ParseQuantity(value string) (number float64, unit string, ok bool) {
+	parts := quantityPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if parts == nil || (parts[1] == "") == (parts[3] == "") {
+		return 0, "", false
+	}
+	number, err := strconv.ParseFloat(NormalizeNumber(parts[2]), 64)
+	return number, parts[1] + parts[3], err == nil
+}
+
+// NormalizeNumber removes digit grouping from a number and makes its decimal
+// separator a point. The last separator is the decimal separator unless it
+// occurs several times or, being the only one, is followed by three digits.
+func NormalizeNumber(digits string) string {
+	digits = strings.NewReplacer(" ", "", "'", "").Replace(digits)
+	last := strings.LastIndexAny(digits, ".,")
+	if last < 0 {
+		return digits
+	}
+	grouping := strings.NewReplacer(".", "", ",", "")
+	separators := strings.Count(digits, ".") + strings.Count(digits, ",")
+	if strings.Count(digits, digits[last:last+1]) > 1 || (separators == 1 && len(digits)-last-1 == 3) {
+		return grouping.Replace(digits)
+	}
+	return grouping.Replace(digits[:last]) + "." + digits[last+1:]
+}
+
+// StartQuantity profiles the column as quantities if its first value is one,
+// just like its type is taken from the first value.
+func (this *Column) StartQuantity(value string) {
+	if this.dataType == "string" {
+		if _, _, ok := ParseQuantity(value); ok {
+			this.quantity = &QuantityProfile{units: make(map[string]int)}
+		}
+	}
+}
+
+func (this *QuantityProfile) Add(value string) {
+	if value == "" {
+		return
+	}
+	number, unit, ok := ParseQuantity(value)
+	if !ok {
+		this.invalid++
+		return
+	}
+	if this.count == 0 || number < this.minimum {
+		this.minimum = number
+	}
+	if this.count == 0 || number > this.maximum {
+		this.maximum = number
+	}
+	this.sum += number
+	this.count++
+	this.units[unit]++
+}
+
+// FinishQuantity drops the profile if too many values are no quantities.
+func (this *Column) FinishQuantity() {
+	if this.quantity == nil {
+		return
+	}
+	total := this.quantity.count + this.quantity.invalid
+	if total == 0 || float64(this.quantity.count)/float64(total) < quantityThreshold {
+		this.quantity = nil
+	}
+}
+
+// Units returns the units by decreasing frequency.
+func (this *QuantityProfile) Units() (units []string) {
+	for unit := range this.units {
+		units = append(units, unit)
+	}
+	sort.Slice(units, func(i, j int) bool {
+		if this.units[units[i]] != this.units[units[j]] {
+			return this.units[units[i]] > this.units[units[j]]
+		}
+		return units[i] < units[j]
+	})
+	return units
+}
+
+func (this *QuantityProfile) String() string {
+	var units []string
+	for _, unit := range this.Units() {
+		units = append(units, fmt.Sprintf("%v %.0f%%", unit, 100*float64(this.units[unit])/float64(this.count)))
+	}
+	return fmt.Sprintf("%v, max: %v, min: %v, avg: %v", strings.Join(units, " "), Redact(FormatFloat(this.maximum)), Redact(FormatFloat(this.minimum)), this.sum/float64(this.count))
+}
+
+// Document describes the profile for JSON exports.
+func (this *QuantityProfile) Document() map[string]interface{} {
+	document := map[string]interface{}{"units": this.units, "average": this.sum / float64(this.count), "invalid": this.invalid}
+	// the range holds values of the data, which redaction keeps out
+	if redaction == "" {
+		document["minimum"], document["maximum"] = this.minimum, this.maximum
+	}
+	return document
+}
@@ -0,0 +1,226 @@
+package profiling
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A query selects columns by a pattern like orders.* and filters them by
+// their profile:
+//
+//	stats(<pattern>) [where <condition> [and <condition>]...]
+//	includes(<pattern>) [where ...]
+//	included(<pattern>) [where ...]
+//
+// stats lists the matching columns' profiles, includes the inclusions of
+// other columns in them and included their inclusions in other columns. The
+// conditions of the inclusion queries filter those other columns. Conditions
+// compare a field of QueryFields, e.g. nulls > 0.1 or type = string.
+
+var (
+	queryPattern     = regexp.MustCompile(`(?is)^\s*(\w+)\s*\(\s*([^()\s]+)\s*\)\s*(?:where\s+(.+?))?\s*$`)
+	conditionPattern = regexp.MustCompile(`^\s*(\w+)\s*(<=|>=|!=|=|<|>)\s*(.+?)\s*$`)
+	andPattern       = regexp.MustCompile(`(?i)\s+and\s+`)
+)
+
+// QueryFields are the fields conditions can compare, in the order stats
+// queries list them.
+var QueryFields = []string{"column", "type", "rows", "distinct", "nulls", "mistyped", "unique", "min", "max", "avg", "pii", "concepts"}
+
+type Condition struct {
+	field, operator, value string
+}
+
+type Query struct {
+	function   string
+	pattern    string
+	conditions []Condition
+}
+
+func ParseQuery(text string) (query *Query, err error) {
+	parts := queryPattern.FindStringSubmatch(text)
+	if parts == nil {
+		return nil, errors.New("expected stats(<pattern>), includes(<pattern>) or included(<pattern>), optionally followed by where <conditions>")
+	}
+	query = &Query{function: strings.ToLower(parts[1]), pattern: parts[2]}
+	if query.function != "stats" && query.function != "includes" && query.function != "included" {
+		return nil, fmt.Errorf("unknown query function %v, use stats, includes or included", parts[1])
+	}
+	if _, err := path.Match(query.pattern, ""); err != nil {
+		return nil, fmt.Errorf("bad pattern %v: %v", query.pattern, err)
+	}
+	if parts[3] == "" {
+		return query, nil
+	}
+	for _, text := range andPattern.Split(parts[3], -1) {
+		condition := conditionPattern.FindStringSubmatch(text)
+		if condition == nil {
+			return nil, fmt.Errorf("expected a condition like nulls > 0.1, got %v", text)
+		}
+		if !IsQueryField(condition[1]) {
+			return nil, fmt.Errorf("unknown field %v, use one of %v", condition[1], strings.Join(QueryFields, ", "))
+		}
+		value := condition[3]
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		query.conditions = append(query.conditions, Condition{condition[1], condition[2], value})
+	}
+	return query, nil
+}
+
+func IsQueryField(field string) bool {
+	for _, known := range QueryFields {
+		if known == field {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryValues returns the values of the QueryFields for the column. Nulls is
+// the share of rows with an empty value, mistyped the number of values not of
+// the column's type.
+func (this *Column) QueryValues() map[string]string {
+	values := map[string]string{"column": this.Name(), "type": this.dataType, "rows": strconv.Itoa(this.table.rowCount),
+		"distinct": strconv.Itoa(this.DistinctValues()), "nulls": "0", "mistyped": strconv.Itoa(this.mistyped), "unique": strconv.FormatBool(this.IsUnique()),
+		"pii": this.pii, "concepts": strings.Join(this.concepts, ",")}
+	if this.table.rowCount > 0 {
+		values["nulls"] = strconv.FormatFloat(float64(this.nulls)/float64(this.table.rowCount), 'g', 4, 64)
+	}
+	switch stats := this.stats.(type) {
+	case *intStatistics:
+		values["min"], values["max"] = Redact(strconv.FormatInt(stats.minimum, 10)), Redact(strconv.FormatInt(stats.maximum, 10))
+		values["avg"] = strconv.FormatFloat(stats.average, 'g', -1, 64)
+	case *floatStatistics:
+		values["min"], values["max"] = Redact(FormatFloat(stats.minimum)), Redact(FormatFloat(stats.maximum))
+		values["avg"] = strconv.FormatFloat(stats.average, 'g', -1, 64)
+	case *dateStatistics:
+		values["min"], values["max"] = Redact(stats.Format(stats.minimum)), Redact(stats.Format(stats.maximum))
+	case *stringStatistics:
+		values["min"], values["max"] = Redact(stats.minimum), Redact(stats.maximum)
+		values["avg"] = strconv.FormatFloat(stats.averageLength, 'g', -1, 64)
+	}
+	return values
+}
+
+// Holds compares numerically if both sides are numbers and as strings
+// otherwise.
+func (this Condition) Holds(values map[string]string) bool {
+	value := values[this.field]
+	comparison := strings.Compare(value, this.value)
+	x, errX := strconv.ParseFloat(value, 64)
+	y, errY := strconv.ParseFloat(this.value, 64)
+	if errX == nil && errY == nil {
+		comparison = 0
+		if x < y {
+			comparison = -1
+		} else if x > y {
+			comparison = 1
+		}
+	}
+	switch this.operator {
+	case "=":
+		return comparison == 0
+	case "!=":
+		return comparison != 0
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case ">":
+		return comparison > 0
+	}
+	return comparison >= 0
+}
+
+func (this *Query) Matches(column *Column) bool {
+	matched, _ := path.Match(this.pattern, column.Name())
+	return matched
+}
+
+func (this *Query) Filter(column *Column) bool {
+	values := column.QueryValues()
+	for _, condition := range this.conditions {
+		if !condition.Holds(values) {
+			return false
+		}
+	}
+	return true
+}
+
+// Run answers the query from the profiled database and, for inclusion
+// queries, the inclusion graph, which may be nil otherwise.
+func (this *Query) Run(db Database, graph *InclusionGraph) (header []string, rows [][]string, err error) {
+	if this.function == "stats" {
+		for _, column := range db.AllColumns() {
+			if this.Matches(column) && this.Filter(column) {
+				values := column.QueryValues()
+				var row []string
+				for _, field := range QueryFields {
+					row = append(row, values[field])
+				}
+				rows = append(rows, row)
+			}
+		}
+		return QueryFields, rows, nil
+	}
+	if graph == nil {
+		return nil, nil, errors.New(this.function + " needs the inclusions of a finished discover run")
+	}
+	for _, a := range graph.nodes {
+		for _, b := range graph.nodes {
+			if a == b || !graph.adjacencyMatrix[a.index][b.index] {
+				continue
+			}
+			if (this.function == "includes" && this.Matches(b) && this.Filter(a)) || (this.function == "included" && this.Matches(a) && this.Filter(b)) {
+				rows = append(rows, []string{a.Name(), b.Name()})
+			}
+		}
+	}
+	return []string{"column", "included in"}, rows, nil
+}
+
+func QueryFlags(options *Options, flags *flag.FlagSet) {
+	flags.StringVar(&options.resume, "results", "", "checkpoint directory of a discover run with -checkpoint-dir, whose profiles and inclusions are queried")
+}
+
+// LoadResults returns the inclusions stored in the -results directory, or nil
+// if there are none.
+func LoadResults(options *Options, db Database) (graph *InclusionGraph) {
+	if options.resume == "" {
+		return nil
+	}
+	graph = db.ToInclusionGraph()
+	// the results are queried whatever settings they were found with
+	if !graph.LoadCheckpoint(options.resume, nil) {
+		return nil
+	}
+	if db.CandidateCount() > 0 {
+		fmt.Fprintln(os.Stderr, "the stored discover run was interrupted, its inclusions are incomplete")
+	}
+	return graph
+}
+
+// RunQuery answers a query about the profiles and inclusions stored by a
+// discover run. Without stored results, stats queries analyze the data.
+func RunQuery(options *Options) {
+	query, err := ParseQuery(options.arguments[1])
+	check(err)
+	db := LoadDatabase(options)
+	db.PrintLoaded(options)
+	header, rows, err := query.Run(db, LoadResults(options, db))
+	check(err)
+	w := NewOutput(terminalOutput)
+	fmt.Fprintln(w, Colorize(strings.Join(header, "\t"), yellow))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
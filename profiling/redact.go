@@ -0,0 +1,45 @@
+package profiling
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"unicode"
+)
+
+// redaction keeps values out of reports: "hash" replaces them by a short
+// hash, so equal values stay recognizable, and "mask" keeps only their shape,
+// replacing letters by x or X and digits by 9. Empty means no redaction.
+var redaction string
+
+func SetRedaction(mode string) {
+	if mode != "" && mode != "hash" && mode != "mask" {
+		panic("unknown redaction " + mode + ", use hash or mask")
+	}
+	redaction = mode
+}
+
+// Redact returns how a value from the data may appear in a report.
+func Redact(value string) string {
+	if value == "" {
+		return value
+	}
+	switch redaction {
+	case "hash":
+		sum := sha256.Sum256([]byte(value))
+		return "#" + hex.EncodeToString(sum[:6])
+	case "mask":
+		masked := []rune(value)
+		for i, r := range masked {
+			switch {
+			case unicode.IsDigit(r):
+				masked[i] = '9'
+			case unicode.IsUpper(r):
+				masked[i] = 'X'
+			case unicode.IsLetter(r):
+				masked[i] = 'x'
+			}
+		}
+		return string(masked)
+	}
+	return value
+}
@@ -0,0 +1,147 @@
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ResourceMonitor records the resources used by each phase of a run, so
+// users can size machines for future runs.
+type ResourceMonitor struct {
+	mutex          sync.Mutex
+	phases         []*phaseUsage
+	bytesRead      int64
+	peakMemory     uint64
+	peakGoroutines int
+	stop           chan bool
+	done           int64
+	total          int64
+}
+
+type phaseUsage struct {
+	name     string
+	start    time.Time
+	cpuStart time.Duration
+	wall     time.Duration
+	cpu      time.Duration
+}
+
+var monitor = new(ResourceMonitor)
+
+type countingReader struct {
+	reader io.Reader
+}
+
+func (this countingReader) Read(p []byte) (n int, err error) {
+	n, err = this.reader.Read(p)
+	atomic.AddInt64(&monitor.bytesRead, int64(n))
+	return n, err
+}
+
+type countingReaderAt struct {
+	reader io.ReaderAt
+}
+
+func (this countingReaderAt) ReadAt(p []byte, offset int64) (n int, err error) {
+	n, err = this.reader.ReadAt(p, offset)
+	atomic.AddInt64(&monitor.bytesRead, int64(n))
+	return n, err
+}
+
+// Phase ends the current phase, if any, and starts measuring the next one.
+func (this *ResourceMonitor) Phase(name string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.stop == nil {
+		this.stop = make(chan bool)
+		go this.sample()
+	}
+	this.endPhase()
+	logger.Debugf("started %v", name)
+	this.phases = append(this.phases, &phaseUsage{name: name, start: time.Now(), cpuStart: CPUTime()})
+	atomic.StoreInt64(&this.done, 0)
+	atomic.StoreInt64(&this.total, 0)
+}
+
+// SetTotal sets the amount of work in the current phase.
+func (this *ResourceMonitor) SetTotal(total int) {
+	atomic.StoreInt64(&this.total, int64(total))
+}
+
+// Advance marks one more unit of the current phase's work as done.
+func (this *ResourceMonitor) Advance() {
+	atomic.AddInt64(&this.done, 1)
+}
+
+func (this *ResourceMonitor) Progress() (phase string, done int64, total int64) {
+	this.mutex.Lock()
+	if len(this.phases) > 0 {
+		phase = this.phases[len(this.phases)-1].name
+	}
+	this.mutex.Unlock()
+	return phase, atomic.LoadInt64(&this.done), atomic.LoadInt64(&this.total)
+}
+
+func (this *ResourceMonitor) endPhase() {
+	if len(this.phases) > 0 {
+		phase := this.phases[len(this.phases)-1]
+		if phase.wall == 0 {
+			phase.wall = time.Since(phase.start)
+			phase.cpu = CPUTime() - phase.cpuStart
+		}
+	}
+}
+
+func (this *ResourceMonitor) sample() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		this.measure()
+		select {
+		case <-ticker.C:
+		case <-this.stop:
+			return
+		}
+	}
+}
+
+func (this *ResourceMonitor) measure() {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if memory.HeapAlloc > this.peakMemory {
+		this.peakMemory = memory.HeapAlloc
+	}
+	if goroutines := runtime.NumGoroutine(); goroutines > this.peakGoroutines {
+		this.peakGoroutines = goroutines
+	}
+}
+
+// Finish stops measuring and prints the summary to stderr, keeping stdout
+// free for results. Runs without any phase, or with -quiet, print nothing.
+func (this *ResourceMonitor) Finish() {
+	if this.stop == nil {
+		return
+	}
+	close(this.stop)
+	this.measure()
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.endPhase()
+	if logger.Quiet() {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "resource usage:")
+	fmt.Fprintf(os.Stderr, "  peak heap: %.1f MiB\n", float64(this.peakMemory)/(1<<20))
+	fmt.Fprintf(os.Stderr, "  bytes read: %d\n", atomic.LoadInt64(&this.bytesRead))
+	fmt.Fprintln(os.Stderr, "  peak goroutines:", this.peakGoroutines)
+	for _, phase := range this.phases {
+		fmt.Fprintf(os.Stderr, "  %v: %v wall, %v cpu\n", phase.name, phase.wall.Round(time.Millisecond), phase.cpu.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,211 @@
+package profiling
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// resultDocument is written by -output json instead of the text output.
+type resultDocument struct {
+	DataDir        string                `json:"data_dir"`
+	Tables         []tableResult         `json:"tables"`
+	Candidates     *int                  `json:"candidates,omitempty"`
+	Inclusions     []inclusionResult     `json:"inclusions"`
+	NaryInclusions []naryInclusionResult `json:"nary_inclusions,omitempty"`
+	// with -check-rules
+	RuleViolations []ruleViolationResult `json:"rule_violations,omitempty"`
+	Results        map[string]int        `json:"results"`
+}
+
+type tableResult struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+	Rows int    `json:"rows"`
+	// rows the profiled sample was drawn from, with -sample-rows or
+	// -sample-rate
+	SampledFrom *int           `json:"sampled_from_rows,omitempty"`
+	Columns     []columnResult `json:"columns"`
+	Keys        [][]string     `json:"keys,omitempty"`
+	// functional dependencies
+	Dependencies      []dependencyResult      `json:"functional_dependencies,omitempty"`
+	OrderDependencies []orderDependencyResult `json:"order_dependencies,omitempty"`
+}
+
+type orderDependencyResult struct {
+	Ordering   string `json:"ordering"`
+	Ordered    string `json:"ordered"`
+	Descending bool   `json:"descending"`
+}
+
+type dependencyResult struct {
+	Determinant []string `json:"determinant"`
+	Dependent   string   `json:"dependent"`
+}
+
+type columnResult struct {
+	Name           string `json:"name"`
+	Alias          string `json:"alias,omitempty"`
+	DataType       string `json:"data_type"`
+	DistinctValues int    `json:"distinct_values"`
+	// HyperLogLog estimate of the non-empty distinct values
+	EstimatedDistinct int `json:"estimated_distinct_values"`
+	Nulls             int `json:"nulls"`
+	// non-empty values not of the data type
+	Mistyped      int      `json:"mistyped,omitempty"`
+	Minimum       *string  `json:"minimum,omitempty"`
+	Maximum       *string  `json:"maximum,omitempty"`
+	Average       *float64 `json:"average,omitempty"`
+	AverageLength *float64 `json:"average_length,omitempty"`
+	// estimated quantiles and histogram of numeric columns, left out when
+	// redacting
+	Histogram *Histogram `json:"histogram,omitempty"`
+	// most frequent masks of string values, like AAA-9999, and regular
+	// expressions of their clusters
+	Patterns        []patternResult `json:"patterns,omitempty"`
+	PatternClusters []patternResult `json:"pattern_clusters,omitempty"`
+	PII             string          `json:"pii,omitempty"`
+	// expected false-positive rate of the column's bloom filter
+	FalsePositiveRate *float64 `json:"bloom_filter_fpp,omitempty"`
+	Concepts          []string `json:"concepts,omitempty"`
+	// candidates generated for the column, before validation
+	Candidates *int `json:"candidates,omitempty"`
+}
+
+type inclusionResult struct {
+	DependentTable   string   `json:"dependent_table"`
+	DependentColumn  string   `json:"dependent_column"`
+	ReferencedTable  string   `json:"referenced_table"`
+	ReferencedColumn string   `json:"referenced_column"`
+	ReferencedUnique bool     `json:"referenced_unique"`
+	ForeignKeyScore  float64  `json:"foreign_key_score"`
+	Coverage         *float64 `json:"coverage,omitempty"`
+	NameSimilarity   *float64 `json:"name_similarity,omitempty"`
+	Declared         *bool    `json:"declared,omitempty"`
+}
+
+type naryInclusionResult struct {
+	DependentTable    string   `json:"dependent_table"`
+	DependentColumns  []string `json:"dependent_columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+}
+
+// JSONOutput tells whether -output asks for JSON. Everything else printed
+// then goes to stderr, leaving stdout to the JSON document.
+func (this *Options) JSONOutput() bool {
+	return this.output == "json"
+}
+
+// RedirectOutput sends what is printed to stdout to stderr instead and
+// returns the original stdout.
+func RedirectOutput() (stdout *os.File) {
+	stdout, os.Stdout = os.Stdout, os.Stderr
+	return stdout
+}
+
+func (this *Column) Result(candidates bool) (result columnResult) {
+	result = columnResult{Name: this.name, Alias: this.alias, DataType: this.dataType, DistinctValues: this.DistinctValues(), EstimatedDistinct: this.stats.EstimatedDistinct(),
+		Nulls: this.nulls, Mistyped: this.mistyped, PII: this.pii, Concepts: this.concepts}
+	switch stats := this.stats.(type) {
+	case *intStatistics:
+		minimum, maximum, average := Redact(fmt.Sprint(stats.minimum)), Redact(fmt.Sprint(stats.maximum)), stats.average
+		result.Minimum, result.Maximum, result.Average = &minimum, &maximum, &average
+		if redaction == "" {
+			result.Histogram = stats.Histogram()
+		}
+	case *floatStatistics:
+		minimum, maximum, average := Redact(FormatFloat(stats.minimum)), Redact(FormatFloat(stats.maximum)), stats.average
+		result.Minimum, result.Maximum, result.Average = &minimum, &maximum, &average
+		if redaction == "" {
+			result.Histogram = stats.Histogram()
+		}
+	case *dateStatistics:
+		minimum, maximum := Redact(stats.Format(stats.minimum)), Redact(stats.Format(stats.maximum))
+		result.Minimum, result.Maximum = &minimum, &maximum
+		if redaction == "" {
+			result.Histogram = stats.Histogram()
+		}
+	case *stringStatistics:
+		minimum, maximum, averageLength := Redact(stats.minimum), Redact(stats.maximum), stats.averageLength
+		result.Minimum, result.Maximum, result.AverageLength = &minimum, &maximum, &averageLength
+		result.Patterns, result.PatternClusters = stats.TopPatterns()
+	}
+	if candidates {
+		result.Candidates = &this.candidateCount
+	}
+	return result
+}
+
+// Results describes the profiles and, if there is a graph, the inclusions.
+func (db Database) Results(options *Options, graph *InclusionGraph) (document resultDocument) {
+	document = resultDocument{DataDir: options.dataDir, Tables: []tableResult{}, Inclusions: []inclusionResult{}, Results: status.Document().Results}
+	names := func(columns []*Column) (names []string) {
+		for _, column := range columns {
+			names = append(names, column.name)
+		}
+		return names
+	}
+	candidates := 0
+	filterSize := db.FilterSizes(options)
+	document.RuleViolations = db.RuleViolations()
+	for _, table := range db {
+		result := tableResult{Name: table.QualifiedName(), File: table.path, Rows: table.rowCount, Columns: []columnResult{}}
+		if table.sampling != nil {
+			result.SampledFrom = &table.sampledFrom
+		}
+		for _, column := range table.columns {
+			summary := column.Result(graph != nil)
+			falsePositiveRate := column.FalsePositiveRate(filterSize(column))
+			summary.FalsePositiveRate = &falsePositiveRate
+			result.Columns = append(result.Columns, summary)
+			candidates += column.candidateCount
+		}
+		for _, key := range table.keys {
+			result.Keys = append(result.Keys, names(key))
+		}
+		for _, dependency := range table.dependencies {
+			result.Dependencies = append(result.Dependencies, dependencyResult{append([]string{}, names(dependency.determinant)...), dependency.dependent.name})
+		}
+		for _, order := range table.orders {
+			result.OrderDependencies = append(result.OrderDependencies, orderDependencyResult{order.ordering.name, order.ordered.name, order.descending})
+		}
+		document.Tables = append(document.Tables, result)
+	}
+	if graph == nil {
+		return document
+	}
+	document.Candidates = &candidates
+	graph.Edges(EdgeFilter{}, 0, func(edge Edge) bool {
+		a, b := edge.a, edge.b
+		result := inclusionResult{DependentTable: a.table.QualifiedName(), DependentColumn: a.name,
+			ReferencedTable: b.table.QualifiedName(), ReferencedColumn: b.name, ReferencedUnique: b.IsUnique(),
+			ForeignKeyScore: graph.ScoreForeignKey(edge).score}
+		if graph.coverage != nil {
+			coverage := graph.coverage[[2]int{a.index, b.index}]
+			result.Coverage = &coverage
+		}
+		if similarity := NameSimilarity(a, b); similarity >= 0 {
+			result.NameSimilarity = &similarity
+		}
+		if graph.catalog {
+			declared := graph.IsDeclared(a, b)
+			result.Declared = &declared
+		}
+		document.Inclusions = append(document.Inclusions, result)
+		return true
+	})
+	for _, inclusion := range graph.nary {
+		document.NaryInclusions = append(document.NaryInclusions, naryInclusionResult{inclusion.a[0].table.QualifiedName(), names(inclusion.a),
+			inclusion.b[0].table.QualifiedName(), names(inclusion.b)})
+	}
+	return document
+}
+
+func (db Database) WriteResults(w io.Writer, options *Options, graph *InclusionGraph) {
+	data, err := json.MarshalIndent(db.Results(options, graph), "", "  ")
+	check(err)
+	_, err = w.Write(append(data, '\n'))
+	check(err)
+}
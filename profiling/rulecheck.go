@@ -0,0 +1,252 @@
+package profiling
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// With -check-rules, the data is checked against the rules of a RuleSet file,
+// as -rules writes them or written by hand. not_null, unique, range, in_set
+// and regex rules are checked on every row while the tables are analyzed,
+// which is why tables with rules are analyzed anew rather than taken from a
+// cached or resumed profile. foreign_key rules are checked once all tables
+// are analyzed, reading the dependent table again. Except not_null, rules
+// pass empty values. Rows breaking a rule are counted and the first few kept
+// for the violation report, numbered by data row, of the sample when
+// sampling. derived rules are not checked.
+
+// number of violating rows reported per rule
+const ruleSamples = 5
+
+type ruleChecker struct {
+	rule       *Rule
+	column     *Column
+	referenced *Column
+	// type the bounds of range rules compare values as
+	kind             string
+	minimum, maximum string
+	// the values of in_set rules, or of the referenced column
+	values  map[string]bool
+	pattern *regexp.Regexp
+	// values of unique rules seen so far
+	seen       map[string]bool
+	violations int
+	samples    []violationSample
+}
+
+type violationSample struct {
+	Row    int      `json:"row"`
+	Values []string `json:"values"`
+}
+
+type ruleViolationResult struct {
+	Rule       string            `json:"rule"`
+	Violations int               `json:"violations"`
+	Samples    []violationSample `json:"samples,omitempty"`
+}
+
+func ReadRuleSet(fileName string) (rules RuleSet) {
+	data, err := os.ReadFile(fileName)
+	check(err)
+	if err := json.Unmarshal(data, &rules); err != nil {
+		panic(fileName + " holds no rule set: " + err.Error())
+	}
+	return rules
+}
+
+// NewRuleChecker prepares checking a rule, returning nil for rules that are
+// not checked.
+func (db Database) NewRuleChecker(rule *Rule) *ruleChecker {
+	column := db.FindColumn(rule.Table + "." + rule.Column)
+	if column == nil {
+		panic("unknown column " + rule.Table + "." + rule.Column + " in rule " + rule.Id)
+	}
+	if rule.Id == "" {
+		rule.Id = column.Name() + "." + rule.Type
+	}
+	this := &ruleChecker{rule: rule, column: column}
+	switch rule.Type {
+	case "not_null":
+	case "unique":
+		this.seen = make(map[string]bool)
+	case "range":
+		// bounds compare as numbers if one is, as dates if all are, and as
+		// text otherwise
+		this.kind = "date"
+		bound := func(value interface{}) string {
+			if number, ok := value.(float64); ok {
+				this.kind = "float"
+				return FormatFloat(number)
+			}
+			text := fmt.Sprint(value)
+			if _, layout := ParseDate(text); layout < 0 && this.kind == "date" {
+				this.kind = "string"
+			}
+			return text
+		}
+		if rule.Minimum != nil {
+			this.minimum = bound(rule.Minimum)
+		}
+		if rule.Maximum != nil {
+			this.maximum = bound(rule.Maximum)
+		}
+	case "in_set":
+		this.values = make(map[string]bool)
+		for _, value := range rule.Values {
+			if number, ok := value.(float64); ok {
+				this.values[FormatFloat(number)] = true
+			} else {
+				this.values[fmt.Sprint(value)] = true
+			}
+		}
+	case "regex":
+		pattern, err := regexp.Compile("^(?:" + rule.Pattern + ")$")
+		if err != nil {
+			panic("rule " + rule.Id + " has an invalid pattern: " + err.Error())
+		}
+		this.pattern = pattern
+	case "foreign_key":
+		this.referenced = db.FindColumn(rule.ReferencedTable + "." + rule.ReferencedColumn)
+		if this.referenced == nil {
+			panic("unknown column " + rule.ReferencedTable + "." + rule.ReferencedColumn + " referenced by rule " + rule.Id)
+		}
+	case "derived":
+		logger.Infof("not checking derived rule %v", rule.Id)
+		return nil
+	default:
+		panic("unknown type " + rule.Type + " of rule " + rule.Id + ", use not_null, unique, range, in_set, regex, foreign_key or derived")
+	}
+	return this
+}
+
+// AttachRules reads the rules to check and hands them to their tables.
+func (db Database) AttachRules(fileName string) {
+	for _, rule := range ReadRuleSet(fileName).Rules {
+		if checker := db.NewRuleChecker(rule); checker != nil {
+			checker.column.table.checkers = append(checker.column.table.checkers, checker)
+		}
+	}
+}
+
+// Breaks tells whether a normalized value breaks the rule.
+func (this *ruleChecker) Breaks(value string) bool {
+	switch this.rule.Type {
+	case "not_null":
+		return value == ""
+	case "unique":
+		if value == "" {
+			return false
+		}
+		if this.seen[value] {
+			return true
+		}
+		this.seen[value] = true
+		return false
+	}
+	if value == "" {
+		return false
+	}
+	switch this.rule.Type {
+	case "range":
+		return this.rule.Minimum != nil && LessValue(this.kind, value, this.minimum) || this.rule.Maximum != nil && LessValue(this.kind, this.maximum, value)
+	case "regex":
+		return !this.pattern.MatchString(value)
+	}
+	return !this.values[value]
+}
+
+// Check counts the row, the number-th data row, if it breaks the rule.
+func (this *ruleChecker) Check(row []string, number int) {
+	if !this.Breaks(this.column.Normalize(row[this.column.field])) {
+		return
+	}
+	this.violations++
+	if len(this.samples) < ruleSamples {
+		values := make([]string, len(row))
+		for i, value := range row {
+			values[i] = Redact(value)
+		}
+		this.samples = append(this.samples, violationSample{number, values})
+	}
+}
+
+func (this *ruleChecker) Reset() {
+	this.violations, this.samples = 0, nil
+	if this.seen != nil {
+		this.seen = make(map[string]bool)
+	}
+}
+
+// CheckRows checks the rules of the table other than foreign keys on a row
+// while analyzing.
+func (this *Table) CheckRows(row []string, number int) {
+	for _, checker := range this.checkers {
+		if checker.rule.Type != "foreign_key" {
+			checker.Check(row, number)
+		}
+	}
+}
+
+// CheckForeignKeyRules checks the foreign_key rules of all tables, reading
+// each table with some once more.
+func (db Database) CheckForeignKeyRules(options *Options) {
+	RunWorkers(options.analysisWorkers, len(db), func(i int) {
+		var foreignKeys []*ruleChecker
+		for _, checker := range db[i].checkers {
+			if checker.rule.Type == "foreign_key" {
+				checker.Reset()
+				checker.values = checker.referenced.values
+				if checker.referenced.spilled != nil {
+					checker.values = checker.referenced.ReadValues()
+				}
+				foreignKeys = append(foreignKeys, checker)
+			}
+		}
+		if len(foreignKeys) == 0 {
+			return
+		}
+		options.failures.Guard(db[i].QualifiedName(), "foreign key check", func() {
+			rows := db[i].OpenRows()
+			for number := 1; ; number++ {
+				row := rows.Read()
+				if len(row) == 0 {
+					break
+				}
+				for _, checker := range foreignKeys {
+					checker.Check(row, number)
+				}
+			}
+		})
+	})
+}
+
+func (db Database) RuleViolations() (results []ruleViolationResult) {
+	for _, table := range db {
+		for _, checker := range table.checkers {
+			results = append(results, ruleViolationResult{checker.rule.Id, checker.violations, checker.samples})
+		}
+	}
+	return results
+}
+
+// PrintRuleViolations reports the rules broken by some rows, with the first
+// of them.
+func (db Database) PrintRuleViolations() {
+	results := db.RuleViolations()
+	violated := 0
+	for _, result := range results {
+		if result.Violations == 0 {
+			continue
+		}
+		violated++
+		fmt.Println(Colorize(result.Rule, yellow), "is violated by", result.Violations, "rows")
+		for _, sample := range result.Samples {
+			fmt.Printf("\tdata row %v: %v\n", sample.Row, strings.Join(sample.Values, "\t"))
+		}
+	}
+	fmt.Println(violated, "of", len(results), "rules violated")
+	status.Result("violated rules", violated)
+}
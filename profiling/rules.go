@@ -0,0 +1,106 @@
+package profiling
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// RuleSet is a neutral JSON format for data quality rules, which validation
+// jobs can execute without knowing about profiling.
+type RuleSet struct {
+	Version     int     `json:"version"`
+	GeneratedBy string  `json:"generated_by"`
+	Rules       []*Rule `json:"rules"`
+}
+
+// Rule is one check of a column: not_null, unique, range, in_set, regex,
+// whose pattern must match every value, foreign_key or derived, whose
+// expression must hold on every row.
+type Rule struct {
+	Id                string        `json:"id"`
+	Type              string        `json:"type"`
+	Table             string        `json:"table"`
+	Column            string        `json:"column"`
+	Minimum           interface{}   `json:"min,omitempty"`
+	Maximum           interface{}   `json:"max,omitempty"`
+	Values            []interface{} `json:"values,omitempty"`
+	ReferencedTable   string        `json:"referenced_table,omitempty"`
+	ReferencedColumn  string        `json:"referenced_column,omitempty"`
+	Expression        string        `json:"expression,omitempty"`
+	Pattern           string        `json:"pattern,omitempty"`
+	ObservedRows      int           `json:"observed_rows"`
+	ObservedDistincts int           `json:"observed_distinct_values"`
+}
+
+func NewRule(ruleType string, column *Column) *Rule {
+	return &Rule{Id: column.Name() + "." + ruleType, Type: ruleType, Table: column.table.QualifiedName(), Column: column.name,
+		ObservedRows: column.table.rowCount, ObservedDistincts: column.DistinctValues()}
+}
+
+// Rules turns the column's profile and its inclusions in unique columns into
+// rules.
+func (this *Column) Rules(foreignKeys []*Column) (result []*Rule) {
+	if !this.HasNulls() {
+		result = append(result, NewRule("not_null", this))
+	}
+	if this.IsUnique() {
+		result = append(result, NewRule("unique", this))
+	}
+	// ranges hold values of the data, which redaction keeps out
+	if stats, ok := this.stats.(*intStatistics); ok && redaction == "" {
+		between := NewRule("range", this)
+		between.Minimum, between.Maximum = stats.minimum, stats.maximum
+		result = append(result, between)
+	}
+	if stats, ok := this.stats.(*floatStatistics); ok && redaction == "" {
+		between := NewRule("range", this)
+		between.Minimum, between.Maximum = stats.minimum, stats.maximum
+		result = append(result, between)
+	}
+	if stats, ok := this.stats.(*dateStatistics); ok && stats.layouts != 0 && redaction == "" {
+		between := NewRule("range", this)
+		between.Minimum, between.Maximum = stats.Format(stats.minimum), stats.Format(stats.maximum)
+		result = append(result, between)
+	}
+	if valueSet := this.ValueSet(); valueSet != nil {
+		inSet := NewRule("in_set", this)
+		inSet.Values = valueSet
+		result = append(result, inSet)
+	}
+	for _, referenced := range foreignKeys {
+		foreignKey := NewRule("foreign_key", this)
+		foreignKey.Id += "." + referenced.Name()
+		foreignKey.ReferencedTable, foreignKey.ReferencedColumn = referenced.table.QualifiedName(), referenced.name
+		result = append(result, foreignKey)
+	}
+	return result
+}
+
+// Rule describes a derived column found by -relations, naming its operands
+// by their physical names. Values are only equal up to -relation-tolerance.
+func (this *Relation) Rule() *Rule {
+	rule := NewRule("derived", this.c)
+	rule.Expression = this.c.name + " = " + this.a.name
+	if this.b != nil {
+		rule.Expression += " " + this.operator + " " + this.b.name
+	}
+	rule.Id += "." + rule.Expression
+	return rule
+}
+
+// ExportRules writes the rules of all tables as a RuleSet.
+func (this *InclusionGraph) ExportRules(w io.Writer, db Database) {
+	foreignKeys := this.ForeignKeys()
+	rules := RuleSet{Version: 1, GeneratedBy: "dataprofiling " + version, Rules: []*Rule{}}
+	for _, table := range db {
+		for _, column := range table.columns {
+			rules.Rules = append(rules.Rules, column.Rules(foreignKeys[column])...)
+		}
+		for _, relation := range table.relations {
+			rules.Rules = append(rules.Rules, relation.Rule())
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	check(encoder.Encode(rules))
+}
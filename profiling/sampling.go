@@ -0,0 +1,100 @@
+package profiling
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// With -sample-rows or -sample-rate, every phase reads only a sample of each
+// table's rows: -sample-rows keeps a uniform sample of that many rows, drawn
+// by reservoir sampling in one pass and then held in memory, -sample-rate
+// keeps each row with that probability while reading. Both draw from a
+// generator seeded by -seed and the table, so that every pass over a table
+// reads the same rows. Statistics, keys, dependencies and inclusions then
+// describe the sample rather than the table: a key of the sample need not be
+// one of the table, and as the referenced column is sampled as well, an
+// inclusion of the tables only holds between their samples if the sample of
+// the referenced column happens to hold every sampled dependent value. With
+// -min-coverage, a foreign key is then found with about the share of the
+// referenced values that made it into the sample as coverage. Sampled tables
+// are flagged in the output.
+
+type Sampling struct {
+	rows int
+	rate float64
+	seed int64
+}
+
+// Sampling returns how to sample the table, or nil to read all its rows.
+func (this *Options) Sampling(table *Table) *Sampling {
+	if this.sampleRows <= 0 && this.sampleRate <= 0 {
+		return nil
+	}
+	return &Sampling{this.sampleRows, this.sampleRate, this.NewRandom("sample " + table.id).Int63()}
+}
+
+func (this *Sampling) String() string {
+	if this.rows > 0 {
+		return fmt.Sprintf("rows=%v seed=%v", this.rows, this.seed)
+	}
+	return fmt.Sprintf("rate=%v seed=%v", this.rate, this.seed)
+}
+
+// Reservoir draws the sample of -sample-rows on the first call, returning
+// the rows in file order and the number of rows read.
+func (this *Table) Reservoir() (sample [][]string, scanned int) {
+	this.reservoirOnce.Do(func() {
+		random := rand.New(rand.NewSource(this.sampling.seed))
+		var indexes []int
+		rows := this.OpenAllRows()
+		for {
+			row := rows.Read()
+			if len(row) == 0 {
+				break
+			}
+			slot := len(indexes)
+			if slot >= this.sampling.rows {
+				if slot = random.Intn(this.reservoirScanned + 1); slot >= this.sampling.rows {
+					this.reservoirScanned++
+					continue
+				}
+			} else {
+				indexes = append(indexes, 0)
+				this.reservoir = append(this.reservoir, nil)
+			}
+			indexes[slot], this.reservoir[slot] = this.reservoirScanned, append([]string{}, row...)
+			this.reservoirScanned++
+		}
+		rows.ReportRaggedRows()
+		sort.Sort(reservoirOrder{indexes, this.reservoir})
+	})
+	return this.reservoir, this.reservoirScanned
+}
+
+// reservoirOrder sorts the sampled rows by their position in the file.
+type reservoirOrder struct {
+	indexes []int
+	rows    [][]string
+}
+
+func (this reservoirOrder) Len() int           { return len(this.indexes) }
+func (this reservoirOrder) Less(i, j int) bool { return this.indexes[i] < this.indexes[j] }
+func (this reservoirOrder) Swap(i, j int) {
+	this.indexes[i], this.indexes[j] = this.indexes[j], this.indexes[i]
+	this.rows[i], this.rows[j] = this.rows[j], this.rows[i]
+}
+
+// PrintSampling flags the tables whose profiles describe a sample.
+func (db Database) PrintSampling() {
+	sampled := 0
+	for _, table := range db {
+		if table.sampling != nil {
+			fmt.Printf("sampled %v of %v rows of %v, statistics and dependencies are approximate\n", table.rowCount, table.sampledFrom, table.QualifiedName())
+			sampled++
+		}
+	}
+	if sampled > 0 {
+		status.Result("sampled tables", sampled)
+	}
+}
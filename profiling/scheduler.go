@@ -0,0 +1,102 @@
+package profiling
+
+// Scheduler picks the next candidate to validate and removes it from the
+// candidates of its column. It returns nil when none are left.
+type Scheduler interface {
+	Next(columns []*Column) *Candidate
+}
+
+func NewScheduler(options *Options, graph *InclusionGraph) Scheduler {
+	switch options.scheduler {
+	case "most-candidates":
+		return mostCandidatesScheduler{}
+	case "cost":
+		return &costScheduler{graph: graph, onDisk: options.validator != "memory", changes: -1}
+	case "names":
+		return namesScheduler{}
+	}
+	panic("unknown scheduler " + options.scheduler + ", use most-candidates, cost or names")
+}
+
+// namesScheduler validates the candidates whose names suggest a foreign key
+// most first, so that interrupted runs found the likeliest ones.
+type namesScheduler struct{}
+
+func (this namesScheduler) Next(columns []*Column) (result *Candidate) {
+	best := 0.0
+	for _, column := range columns {
+		for referenced := range column.candidates {
+			score := NameSimilarity(column, referenced)
+			if result == nil || score > best || (score == best && (column.index < result.a.index ||
+				(column.index == result.a.index && referenced.index < result.b.index))) {
+				result, best = &Candidate{column, referenced}, score
+			}
+		}
+	}
+	if result != nil {
+		delete(result.a.candidates, result.b)
+	}
+	return result
+}
+
+// costScheduler picks the candidate with the highest expected pruning per
+// cost of validation. Validating a <= b costs a scan of a's values in memory,
+// or a scan of both tables on disk. If it holds, the transitive closure adds
+// i <= c for every i <= a and b <= c, pruning up to that many candidates.
+type costScheduler struct {
+	graph  *InclusionGraph
+	onDisk bool
+	// columns included in each column and columns each column is included in,
+	// counting itself, as of the graph's changes
+	included, includedIn []int
+	changes              int
+}
+
+func (this *costScheduler) Cost(candidate *Candidate) float64 {
+	if this.onDisk {
+		return float64(candidate.a.table.rowCount+candidate.b.table.rowCount) + 1
+	}
+	return float64(candidate.a.DistinctValues()) + 1
+}
+
+func (this *costScheduler) Benefit(candidate *Candidate) float64 {
+	return float64(this.included[candidate.a.index] * this.includedIn[candidate.b.index])
+}
+
+// Count updates the closure sizes once the graph changed.
+func (this *costScheduler) Count() {
+	if this.changes == this.graph.changes {
+		return
+	}
+	this.changes = this.graph.changes
+	size := len(this.graph.nodes)
+	this.included, this.includedIn = make([]int, size), make([]int, size)
+	for i, row := range this.graph.adjacencyMatrix {
+		for j, included := range row {
+			if included {
+				this.includedIn[i]++
+				this.included[j]++
+			}
+		}
+	}
+}
+
+func (this *costScheduler) Next(columns []*Column) (result *Candidate) {
+	this.Count()
+	best := 0.0
+	for _, column := range columns {
+		for referenced := range column.candidates {
+			candidate := &Candidate{column, referenced}
+			score := this.Benefit(candidate) / this.Cost(candidate)
+			// ties go to the first pair by index, keeping runs reproducible
+			if result == nil || score > best || (score == best && (column.index < result.a.index ||
+				(column.index == result.a.index && referenced.index < result.b.index))) {
+				result, best = candidate, score
+			}
+		}
+	}
+	if result != nil {
+		delete(result.a.candidates, result.b)
+	}
+	return result
+}
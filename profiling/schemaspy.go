@@ -0,0 +1,66 @@
+package profiling
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// SchemaSpy reads additional metadata from a -meta XML file, which lets its
+// HTML schema browser draw relationships the database does not declare.
+
+type schemaSpyMeta struct {
+	XMLName  xml.Name         `xml:"schemaMeta"`
+	Comments string           `xml:"comments"`
+	Tables   []schemaSpyTable `xml:"tables>table"`
+}
+
+type schemaSpyTable struct {
+	Name         string            `xml:"name,attr"`
+	RemoteSchema string            `xml:"remoteSchema,attr,omitempty"`
+	Columns      []schemaSpyColumn `xml:"column"`
+}
+
+type schemaSpyColumn struct {
+	Name        string                `xml:"name,attr"`
+	Comments    string                `xml:"comments,attr,omitempty"`
+	ForeignKeys []schemaSpyForeignKey `xml:"foreignKey"`
+}
+
+type schemaSpyForeignKey struct {
+	Table        string `xml:"table,attr"`
+	Column       string `xml:"column,attr"`
+	RemoteSchema string `xml:"remoteSchema,attr,omitempty"`
+}
+
+// ExportSchemaSpy writes a SchemaSpy meta file adding every foreign key like
+// inclusion that is not declared already. Columns without a comment of their
+// own get their summary as one.
+func (this *InclusionGraph) ExportSchemaSpy(w io.Writer) {
+	meta := schemaSpyMeta{Comments: "relationships inferred by dataprofiling"}
+	foreignKeys := this.ForeignKeys()
+	var table *schemaSpyTable
+	var current *Table
+	for _, column := range this.nodes {
+		if column.table != current {
+			current = column.table
+			meta.Tables = append(meta.Tables, schemaSpyTable{Name: current.name, RemoteSchema: current.schema})
+			table = &meta.Tables[len(meta.Tables)-1]
+		}
+		entry := schemaSpyColumn{Name: column.name}
+		if column.comment == "" {
+			entry.Comments = column.Summary()
+		}
+		for _, referenced := range foreignKeys[column] {
+			if !column.foreignKeys[referenced] {
+				entry.ForeignKeys = append(entry.ForeignKeys, schemaSpyForeignKey{referenced.table.name, referenced.name, referenced.table.schema})
+			}
+		}
+		table.Columns = append(table.Columns, entry)
+	}
+	fmt.Fprint(w, xml.Header)
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	check(encoder.Encode(meta))
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,284 @@
+package profiling
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// spiderValidator validates all candidates at once like SPIDER: the distinct
+// values of every column are sorted into files, which are merged in a single
+// pass, removing each referenced column from a dependent column's candidates
+// when a value of the dependent one is missing from it. The value files are
+// those the analysis spilled in chunks of -spill-values values, see memory.go,
+// so neither analysis nor validation depends on the value sets fitting into
+// memory, and the data files are read only once.
+type spiderValidator struct {
+	db          Database
+	dir         string
+	minCoverage float64
+	ignoreNulls bool
+	// coverage of each candidate left after the merge, nil until the first
+	// candidate is checked
+	results map[Candidate]float64
+}
+
+func NewSpiderValidator(options *Options, db Database) Validator {
+	dir, err := os.MkdirTemp(options.spillDir, "spider")
+	check(err)
+	return &spiderValidator{db: db, dir: dir, minCoverage: options.minCoverage, ignoreNulls: options.IgnoreNulls()}
+}
+
+// WriteValue writes a length prefixed value, so values may hold line breaks.
+func WriteValue(w *bufio.Writer, value string) {
+	var length [binary.MaxVarintLen64]byte
+	w.Write(length[:binary.PutUvarint(length[:], uint64(len(value)))])
+	w.WriteString(value)
+}
+
+func ReadValue(r *bufio.Reader) (value string, ok bool) {
+	length, err := binary.ReadUvarint(r)
+	if err == io.EOF {
+		return "", false
+	}
+	check(err)
+	data := make([]byte, length)
+	_, err = io.ReadFull(r, data)
+	check(err)
+	return string(data), true
+}
+
+// valueFile is a sorted file of distinct values.
+type valueFile struct {
+	path     string
+	distinct int
+}
+
+// WriteRun writes a set of values to a sorted value file.
+func WriteRun(values map[string]bool, path string) valueFile {
+	sorted := make([]string, 0, len(values))
+	for value := range values {
+		sorted = append(sorted, value)
+	}
+	sort.Strings(sorted)
+	file, err := os.Create(path)
+	check(err)
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	for _, value := range sorted {
+		WriteValue(w, value)
+	}
+	check(w.Flush())
+	return valueFile{path, len(sorted)}
+}
+
+// valueCursor walks a sorted value file.
+type valueCursor struct {
+	file   *os.File
+	reader *bufio.Reader
+	value  string
+	column *Column
+}
+
+func OpenValueFile(path string, column *Column) (cursor *valueCursor, ok bool) {
+	file, err := os.Open(path)
+	check(err)
+	cursor = &valueCursor{file: file, reader: bufio.NewReader(file), column: column}
+	if !cursor.Next() {
+		return nil, false
+	}
+	return cursor, true
+}
+
+// Close closes the file of a cursor left before its last value.
+func (this *valueCursor) Close() {
+	this.file.Close()
+}
+
+// Next moves to the next value, closing the file after the last one.
+func (this *valueCursor) Next() bool {
+	var ok bool
+	if this.value, ok = ReadValue(this.reader); !ok {
+		this.file.Close()
+	}
+	return ok
+}
+
+// cursorHeap orders cursors by their current value.
+type cursorHeap []*valueCursor
+
+func (this cursorHeap) Len() int            { return len(this) }
+func (this cursorHeap) Less(i, j int) bool  { return this[i].value < this[j].value }
+func (this cursorHeap) Swap(i, j int)       { this[i], this[j] = this[j], this[i] }
+func (this *cursorHeap) Push(x interface{}) { *this = append(*this, x.(*valueCursor)) }
+func (this *cursorHeap) Pop() interface{} {
+	old := *this
+	cursor := old[len(old)-1]
+	*this = old[:len(old)-1]
+	return cursor
+}
+
+// Merge passes the distinct values of the cursors in order to visit, with the
+// columns having each value.
+func Merge(cursors []*valueCursor, visit func(value string, columns []*Column)) {
+	open := cursorHeap(cursors)
+	heap.Init(&open)
+	var columns []*Column
+	for open.Len() > 0 {
+		value := open[0].value
+		columns = columns[:0]
+		for open.Len() > 0 && open[0].value == value {
+			columns = append(columns, open[0].column)
+			if open[0].Next() {
+				heap.Fix(&open, 0)
+			} else {
+				heap.Pop(&open)
+			}
+		}
+		visit(value, columns)
+	}
+}
+
+// MergeRuns merges sorted value files into one holding each value once and
+// removes them.
+func MergeRuns(runs []string, path string) valueFile {
+	var cursors []*valueCursor
+	for _, run := range runs {
+		if cursor, ok := OpenValueFile(run, nil); ok {
+			cursors = append(cursors, cursor)
+		}
+	}
+	file, err := os.Create(path)
+	check(err)
+	w := bufio.NewWriter(file)
+	distinct := 0
+	Merge(cursors, func(value string, _ []*Column) {
+		WriteValue(w, value)
+		distinct++
+	})
+	check(w.Flush())
+	check(file.Close())
+	for _, run := range runs {
+		check(os.Remove(run))
+	}
+	return valueFile{path, distinct}
+}
+
+// Validate merges the value files of all columns taking part in a candidate,
+// including first, which was already taken from its column's candidates.
+func (this *spiderValidator) Validate(first *Candidate) {
+	referenced := make(map[*Column]map[*Column]bool)
+	needed := make(map[*Table]map[*Column]bool)
+	need := func(column *Column) {
+		if needed[column.table] == nil {
+			needed[column.table] = make(map[*Column]bool)
+		}
+		needed[column.table][column] = true
+	}
+	add := func(a *Column, b *Column) {
+		if referenced[a] == nil {
+			referenced[a] = make(map[*Column]bool)
+		}
+		referenced[a][b] = true
+		need(a)
+		need(b)
+	}
+	add(first.a, first.b)
+	for _, column := range this.db.AllColumns() {
+		for candidate := range column.candidates {
+			add(column, candidate)
+		}
+	}
+	distinct := make(map[*Column]int)
+	var cursors []*valueCursor
+	for _, table := range this.db {
+		for _, column := range table.columns {
+			if !needed[table][column] {
+				continue
+			}
+			file := this.ValueFile(column)
+			distinct[column] = file.distinct
+			// with ignoreNulls the empty value is skipped, so referenced
+			// columns need not contain it either
+			if this.ignoreNulls && column.HasNulls() {
+				distinct[column]--
+			}
+			if cursor, ok := OpenValueFile(file.path, column); ok {
+				cursors = append(cursors, cursor)
+			}
+		}
+	}
+	// values a dependent column may miss in a referenced one
+	allowed := func(column *Column) int {
+		return int((1 - this.minCoverage) * float64(distinct[column]))
+	}
+	misses := make(map[Candidate]int)
+	having := make(map[*Column]bool)
+	Merge(cursors, func(value string, columns []*Column) {
+		if value == "" && this.ignoreNulls {
+			return
+		}
+		for _, column := range columns {
+			having[column] = true
+		}
+		for _, a := range columns {
+			for b := range referenced[a] {
+				if having[b] {
+					continue
+				}
+				candidate := Candidate{a, b}
+				if misses[candidate]++; misses[candidate] > allowed(a) {
+					delete(referenced[a], b)
+				}
+			}
+		}
+		for _, column := range columns {
+			delete(having, column)
+		}
+	})
+	this.results = make(map[Candidate]float64)
+	for a, columns := range referenced {
+		for b := range columns {
+			coverage := 1.0
+			if distinct[a] > 0 {
+				coverage -= float64(misses[Candidate{a, b}]) / float64(distinct[a])
+			}
+			this.results[Candidate{a, b}] = coverage
+		}
+	}
+}
+
+// ValueFile returns the column's spilled value file, or writes one for values
+// held in memory, e.g. as they were restored from a saved profile.
+func (this *spiderValidator) ValueFile(column *Column) valueFile {
+	if column.spilled != nil {
+		return *column.spilled
+	}
+	return WriteRun(column.values, filepath.Join(this.dir, fmt.Sprintf("%v-%v.values", column.table.FileId(), column.id)))
+}
+
+func (this *spiderValidator) Coverage(candidate *Candidate) float64 {
+	if this.results == nil {
+		this.Validate(candidate)
+	}
+	return this.results[*candidate]
+}
+
+func (this *spiderValidator) Check(candidate *Candidate) bool {
+	return this.Coverage(candidate) >= 1
+}
+
+// Parallel is false, as the first check validates all candidates of the
+// columns at once.
+func (this *spiderValidator) Parallel() bool {
+	return false
+}
+
+func (this *spiderValidator) Close() {
+	check(os.RemoveAll(this.dir))
+}
@@ -0,0 +1,264 @@
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type SQLDialect struct {
+	quoteOpen  string
+	quoteClose string
+	length     string
+	// SQLite cannot add constraints to existing tables, so they are only
+	// written as comments to be copied into CREATE TABLE statements
+	alterable bool
+}
+
+var sqlDialects = map[string]*SQLDialect{
+	"postgres":  {`"`, `"`, "char_length", true},
+	"mysql":     {"`", "`", "char_length", true},
+	"sqlserver": {"[", "]", "len", true},
+	"sqlite":    {`"`, `"`, "length", false},
+}
+
+func NewSQLDialect(name string) *SQLDialect {
+	dialect, ok := sqlDialects[name]
+	if !ok {
+		panic("unknown SQL dialect " + name + ", use postgres, mysql, sqlserver or sqlite")
+	}
+	return dialect
+}
+
+func (this *SQLDialect) Identifier(name string) string {
+	return this.quoteOpen + strings.ReplaceAll(name, this.quoteClose, this.quoteClose+this.quoteClose) + this.quoteClose
+}
+
+// TableIdentifier quotes a table's name, qualified by its schema if it has one.
+func (this *SQLDialect) TableIdentifier(table *Table) string {
+	if table.schema == "" {
+		return this.Identifier(table.name)
+	}
+	return this.Identifier(table.schema) + "." + this.Identifier(table.name)
+}
+
+func (this *SQLDialect) Literal(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+	}
+	return fmt.Sprint(value)
+}
+
+var constraintNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+func ConstraintName(parts ...string) string {
+	return constraintNameInvalid.ReplaceAllString(strings.Join(parts, "_"), "_")
+}
+
+// CheckConstraints suggests CHECK conditions and names for them, built from
+// the value range, the maximum length and the values of enumerations.
+func (this *Column) CheckConstraints(dialect *SQLDialect) (names []string, conditions []string) {
+	column := dialect.Identifier(this.name)
+	// ranges hold values of the data, which redaction keeps out
+	switch stats := this.stats.(type) {
+	case *intStatistics:
+		if redaction == "" {
+			names = append(names, ConstraintName("chk", this.table.QualifiedName(), this.name, "range"))
+			conditions = append(conditions, fmt.Sprintf("%v BETWEEN %v AND %v", column, stats.minimum, stats.maximum))
+		}
+	case *floatStatistics:
+		if redaction == "" {
+			names = append(names, ConstraintName("chk", this.table.QualifiedName(), this.name, "range"))
+			conditions = append(conditions, fmt.Sprintf("%v BETWEEN %v AND %v", column, FormatFloat(stats.minimum), FormatFloat(stats.maximum)))
+		}
+	case *dateStatistics:
+		if stats.layouts != 0 && redaction == "" {
+			names = append(names, ConstraintName("chk", this.table.QualifiedName(), this.name, "range"))
+			conditions = append(conditions, fmt.Sprintf("%v BETWEEN %v AND %v", column, dialect.Literal(stats.Format(stats.minimum)), dialect.Literal(stats.Format(stats.maximum))))
+		}
+	case *stringStatistics:
+		if this.dataType == "string" {
+			names = append(names, ConstraintName("chk", this.table.QualifiedName(), this.name, "length"))
+			conditions = append(conditions, fmt.Sprintf("%v(%v) <= %v", dialect.length, column, len([]rune(stats.longest))))
+		}
+	}
+	if valueSet := this.ValueSet(); valueSet != nil {
+		literals := make([]string, len(valueSet))
+		for i, value := range valueSet {
+			literals[i] = dialect.Literal(value)
+		}
+		names = append(names, ConstraintName("chk", this.table.QualifiedName(), this.name, "values"))
+		conditions = append(conditions, fmt.Sprintf("%v IN (%v)", column, strings.Join(literals, ", ")))
+	}
+	return names, conditions
+}
+
+// PrimaryKey suggests the table's primary key: the declared one, or else the
+// smallest key without empty values, preferring keys of columns that foreign
+// keys reference and then the leftmost columns. Keys are those found with
+// -keys, or the unique columns without it.
+func (this *Table) PrimaryKey(referenced map[*Column]bool) (key []*Column, declared bool) {
+	for _, column := range this.columns {
+		if column.primaryKey {
+			key = append(key, column)
+		}
+	}
+	if key != nil || this.rowCount == 0 {
+		return key, true
+	}
+	keys := this.keys
+	if keys == nil {
+		for _, column := range this.columns {
+			if column.IsUnique() {
+				keys = append(keys, []*Column{column})
+			}
+		}
+	}
+	references := func(key []*Column) (count int) {
+		for _, column := range key {
+			if referenced[column] {
+				count++
+			}
+		}
+		return count
+	}
+	for _, candidate := range keys {
+		nullable := false
+		for _, column := range candidate {
+			nullable = nullable || column.HasNulls()
+		}
+		if nullable {
+			continue
+		}
+		if key == nil || len(candidate) < len(key) || len(candidate) == len(key) && references(candidate) > references(key) {
+			key = candidate
+		}
+	}
+	return key, false
+}
+
+// Columns quotes the names of columns for a constraint.
+func (this *SQLDialect) Columns(columns []*Column) string {
+	var names []string
+	for _, column := range columns {
+		names = append(names, this.Identifier(column.name))
+	}
+	return strings.Join(names, ", ")
+}
+
+// ExportSQL writes the constraints suggested by the profiles as statements
+// for the dialect: primary keys, CHECK constraints from the column
+// statistics, and foreign keys from the inclusions referencing keys, the
+// likeliest first and each scored in a comment. Referenced columns that are
+// no primary key get a UNIQUE constraint, which foreign keys require.
+func (this *InclusionGraph) ExportSQL(w io.Writer, dialect *SQLDialect, db Database, minScore float64) {
+	prefix := ""
+	if !dialect.alterable {
+		prefix = "-- "
+		fmt.Fprintln(w, "-- this dialect cannot alter constraints, add them to the CREATE TABLE statements")
+	}
+	type foreignKey struct {
+		a, b []*Column
+		// the score of single column foreign keys, the negated name
+		// similarity of composite ones
+		score float64
+	}
+	var foreignKeys []foreignKey
+	for _, candidate := range this.RankForeignKeys() {
+		a, b := candidate.edge.a, candidate.edge.b
+		// only keys can be referenced, and declared foreign keys exist already
+		if b.IsUnique() && !a.foreignKeys[b] && candidate.score >= minScore {
+			foreignKeys = append(foreignKeys, foreignKey{[]*Column{a}, []*Column{b}, candidate.score})
+		}
+	}
+	// composite foreign keys referencing a key found with -keys, of which
+	// only the best named one is kept among those of the same columns
+	// referencing the same table in a different order
+	composite := make(map[string]int)
+	for _, inclusion := range this.nary {
+		if ColumnSetKey(inclusion.a) == ColumnSetKey(inclusion.b) {
+			continue
+		}
+		for _, key := range inclusion.b[0].table.keys {
+			if ColumnSetKey(key) != ColumnSetKey(inclusion.b) {
+				continue
+			}
+			similarity := 0.0
+			for i := range inclusion.a {
+				similarity += math.Max(0, NameSimilarity(inclusion.a[i], inclusion.b[i]))
+			}
+			id := ColumnSetKey(inclusion.a) + inclusion.b[0].table.QualifiedName()
+			if i, ok := composite[id]; !ok {
+				composite[id] = len(foreignKeys)
+				foreignKeys = append(foreignKeys, foreignKey{inclusion.a, inclusion.b, -similarity})
+			} else if -similarity < foreignKeys[i].score {
+				foreignKeys[i] = foreignKey{inclusion.a, inclusion.b, -similarity}
+			}
+		}
+	}
+	referenced := make(map[*Column]bool)
+	for _, key := range foreignKeys {
+		for _, column := range key.b {
+			referenced[column] = true
+		}
+	}
+	// keys already constrained to be unique, by the sorted column indices
+	unique := make(map[string]bool)
+	for _, table := range db {
+		key, declared := table.PrimaryKey(referenced)
+		if key == nil {
+			continue
+		}
+		unique[ColumnSetKey(key)] = true
+		if !declared {
+			fmt.Fprintf(w, "%vALTER TABLE %v ADD CONSTRAINT %v PRIMARY KEY (%v);\n", prefix, dialect.TableIdentifier(table),
+				dialect.Identifier(ConstraintName("pk", table.QualifiedName())), dialect.Columns(key))
+		}
+	}
+	for _, key := range foreignKeys {
+		if set := ColumnSetKey(key.b); !unique[set] {
+			unique[set] = true
+			table := key.b[0].table
+			var names []string
+			for _, column := range key.b {
+				names = append(names, column.name)
+			}
+			fmt.Fprintf(w, "%vALTER TABLE %v ADD CONSTRAINT %v UNIQUE (%v);\n", prefix, dialect.TableIdentifier(table),
+				dialect.Identifier(ConstraintName(append([]string{"uq", table.QualifiedName()}, names...)...)), dialect.Columns(key.b))
+		}
+	}
+	for _, column := range this.nodes {
+		names, conditions := column.CheckConstraints(dialect)
+		for i := range names {
+			fmt.Fprintf(w, "%vALTER TABLE %v ADD CONSTRAINT %v CHECK (%v);\n", prefix, dialect.TableIdentifier(column.table), dialect.Identifier(names[i]), conditions[i])
+		}
+	}
+	for _, key := range foreignKeys {
+		parts := []string{"fk", key.a[0].table.QualifiedName()}
+		for _, column := range key.a {
+			parts = append(parts, column.name)
+		}
+		parts = append(parts, key.b[0].table.QualifiedName())
+		for _, column := range key.b {
+			parts = append(parts, column.name)
+		}
+		if len(key.a) == 1 {
+			fmt.Fprintf(w, "-- foreign key score %.2f\n", key.score)
+		}
+		fmt.Fprintf(w, "%vALTER TABLE %v ADD CONSTRAINT %v FOREIGN KEY (%v) REFERENCES %v (%v);\n", prefix, dialect.TableIdentifier(key.a[0].table),
+			dialect.Identifier(ConstraintName(parts...)), dialect.Columns(key.a), dialect.TableIdentifier(key.b[0].table), dialect.Columns(key.b))
+	}
+}
+
+// ColumnSetKey identifies a set of columns regardless of their order.
+func ColumnSetKey(columns []*Column) string {
+	var indexes []int
+	for _, column := range columns {
+		indexes = append(indexes, column.index)
+	}
+	sort.Ints(indexes)
+	return fmt.Sprint(indexes)
+}
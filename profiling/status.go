@@ -0,0 +1,135 @@
+package profiling
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// StatusFile lets orchestrators follow a run without parsing its output: the
+// -status-file is rewritten periodically while running, and -manifest lists
+// the results and written files once the run ended.
+type StatusFile struct {
+	mutex    sync.Mutex
+	writing  sync.Mutex
+	options  *Options
+	command  string
+	results  map[string]int
+	outputs  []string
+	errors   []string
+	finished bool
+	stop     chan bool
+}
+
+type statusDocument struct {
+	State         string         `json:"state"`
+	Command       string         `json:"command"`
+	DataDir       string         `json:"data_dir,omitempty"`
+	Phase         string         `json:"phase,omitempty"`
+	PhaseDone     int64          `json:"phase_done"`
+	PhaseTotal    int64          `json:"phase_total"`
+	PhasePercent  float64        `json:"phase_percent"`
+	StartedAt     time.Time      `json:"started_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	Results       map[string]int `json:"results,omitempty"`
+	Outputs       []string       `json:"outputs,omitempty"`
+	Errors        []string       `json:"errors"`
+	StatusVersion int            `json:"status_version"`
+}
+
+var status = &StatusFile{results: make(map[string]int)}
+
+func (this *StatusFile) Start(command *Command, options *Options) {
+	this.options, this.command = options, command.name
+	if options.statusFile == "" {
+		return
+	}
+	this.stop = make(chan bool)
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for {
+			this.Write()
+			select {
+			case <-ticker.C:
+			case <-this.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Output records a file written by the run.
+func (this *StatusFile) Output(fileName string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.outputs = append(this.outputs, fileName)
+}
+
+// Result records one of the run's result counts, e.g. the inclusions found.
+func (this *StatusFile) Result(name string, value int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.results[name] = value
+}
+
+// Error records a failure the run continued after, which marks it as failed.
+func (this *StatusFile) Error(message string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.errors = append(this.errors, message)
+}
+
+func (this *StatusFile) Document() (document statusDocument) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	document = statusDocument{State: "running", Command: this.command, DataDir: this.options.dataDir, StartedAt: this.options.started,
+		UpdatedAt: time.Now(), Results: this.results, Outputs: this.outputs, Errors: append([]string{}, this.errors...), StatusVersion: 1}
+	if this.finished {
+		document.State = "succeeded"
+		if len(this.errors) > 0 {
+			document.State = "failed"
+		}
+	}
+	document.Phase, document.PhaseDone, document.PhaseTotal = monitor.Progress()
+	if document.PhaseTotal > 0 {
+		document.PhasePercent = 100 * float64(document.PhaseDone) / float64(document.PhaseTotal)
+	}
+	return document
+}
+
+func WriteJSON(fileName string, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	check(err)
+	check(os.WriteFile(fileName+".tmp", data, 0644))
+	check(os.Rename(fileName+".tmp", fileName))
+}
+
+func (this *StatusFile) Write() {
+	this.writing.Lock()
+	defer this.writing.Unlock()
+	WriteJSON(this.options.statusFile, this.Document())
+}
+
+// Finish writes the final status and the manifest. A non nil failure marks
+// the run as failed.
+func (this *StatusFile) Finish(failure interface{}) {
+	if this.options == nil {
+		return
+	}
+	this.mutex.Lock()
+	this.finished = true
+	if failure != nil {
+		this.errors = append(this.errors, fmt.Sprint(failure))
+	}
+	this.mutex.Unlock()
+	if this.stop != nil {
+		close(this.stop)
+		this.Write()
+	}
+	if this.options.manifestFile != "" {
+		WriteJSON(this.options.manifestFile, this.Document())
+	}
+}
@@ -0,0 +1,138 @@
+package profiling
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TokenInclusion records that the tokens of a composite column a, like the
+// 456 of 123|456, are included in b. Position is the token's index if all
+// values have the same number of tokens, otherwise -1 for all of them.
+type TokenInclusion struct {
+	a, b      *Column
+	delimiter string
+	position  int
+}
+
+func (this *TokenInclusion) String() string {
+	if this.position < 0 {
+		return fmt.Sprintf("%v tokens ⊆ %v", this.a.Label(), this.b.Label())
+	}
+	return fmt.Sprintf("%v token %v ⊆ %v", this.a.Label(), this.position+1, this.b.Label())
+}
+
+// Delimiter returns the first of the delimiters every non-empty value of the
+// column contains, or "" if it is no composite column.
+func (this *Column) Delimiter(delimiters string) string {
+	if this.dataType != "string" || this.dates != nil || this.geo != nil {
+		return ""
+	}
+	for _, delimiter := range strings.Split(delimiters, "") {
+		composite := false
+		this.EachValueUntil(func(value string) bool {
+			if value == "" {
+				return true
+			}
+			if composite = strings.Contains(value, delimiter); !composite {
+				return false
+			}
+			return true
+		})
+		if composite {
+			return delimiter
+		}
+	}
+	return ""
+}
+
+// Tokens splits the column's values, returning the distinct tokens at each
+// position, or a single set of all tokens if their number varies.
+func (this *Column) Tokens(delimiter string) (tokens []map[string]bool) {
+	width := -1
+	this.EachValue(func(value string) {
+		if value == "" {
+			return
+		}
+		parts := strings.Split(value, delimiter)
+		if width == -1 {
+			width = len(parts)
+			for range parts {
+				tokens = append(tokens, make(map[string]bool))
+			}
+		}
+		if len(parts) != width {
+			width = 1
+			all := make(map[string]bool)
+			for _, positions := range tokens {
+				for token := range positions {
+					all[token] = true
+				}
+			}
+			tokens = []map[string]bool{all}
+		}
+		for i, part := range parts {
+			if part != "" {
+				tokens[i%width][part] = true
+			}
+		}
+	})
+	return tokens
+}
+
+// FindTokenInclusions checks the tokens of every searched composite column
+// against the values of all other searched columns. Tokens with only one
+// distinct value are left out, as they are included in many columns by chance.
+func (this *InclusionGraph) FindTokenInclusions(options *Options) {
+	var composite []*Column
+	var searched []*Column
+	for _, column := range this.nodes {
+		if column.IsSearched(options) {
+			searched = append(searched, column)
+			if column.Delimiter(options.tokenDelimiters) != "" {
+				composite = append(composite, column)
+			}
+		}
+	}
+	found := make([][]*TokenInclusion, len(composite))
+	RunWorkers(options.validationWorkers, len(composite), func(i int) {
+		a := composite[i]
+		delimiter := a.Delimiter(options.tokenDelimiters)
+		tokens := a.Tokens(delimiter)
+		for position, values := range tokens {
+			if len(values) < 2 {
+				continue
+			}
+			if len(tokens) == 1 {
+				position = -1
+			}
+			for _, b := range searched {
+				if a != b && IsTokenIncluded(values, b) {
+					found[i] = append(found[i], &TokenInclusion{a, b, delimiter, position})
+				}
+			}
+		}
+	})
+	this.tokens = nil
+	for _, inclusions := range found {
+		this.tokens = append(this.tokens, inclusions...)
+	}
+}
+
+func IsTokenIncluded(tokens map[string]bool, column *Column) bool {
+	if len(tokens) > column.DistinctValues() {
+		return false
+	}
+	for token := range tokens {
+		if !column.values[token] {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *InclusionGraph) PrintTokenInclusions() {
+	fmt.Println("found", len(this.tokens), "token inclusions")
+	for _, inclusion := range this.tokens {
+		fmt.Println(inclusion)
+	}
+}
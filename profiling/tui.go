@@ -0,0 +1,370 @@
+package profiling
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// With -tui, discover runs behind a dashboard redrawn on the terminal: the
+// phase and its progress, the rows read of each table being analyzed, the
+// candidates left and inclusions found while validating, and the last
+// messages. Messages and results are held back meanwhile and printed once the
+// dashboard closes, so they stay in the terminal's scrollback. After the run,
+// the results can be browsed by commands typed on stdin: the tables, the
+// profiles of a table's columns with the inclusions they take part in, and
+// the inclusions, filtered by a text.
+
+const dashboardInterval = 250 * time.Millisecond
+
+// number of tables and messages the dashboard shows
+const dashboardTables = 15
+
+const dashboardMessages = 8
+
+// rows between updates of a table's progress while analyzing
+const progressRows = 1000
+
+// lines per page of the browser
+const browserPage = 30
+
+type Dashboard struct {
+	mutex sync.Mutex
+	out   *os.File
+	db    Database
+	// set by the validation loop once it starts
+	validating bool
+	validated  int
+	remaining  int
+	found      int
+	messages   []string
+	// the messages and results written while the dashboard is shown
+	logged, printed []byte
+	stdout, pipe    *os.File
+	copied          chan bool
+	stop, done      chan bool
+	closed          bool
+}
+
+// dashboardWriter keeps what is written for printing once the dashboard
+// closes and shows its last lines as messages.
+type dashboardWriter struct {
+	dashboard *Dashboard
+	held      *[]byte
+}
+
+func (this dashboardWriter) Write(p []byte) (int, error) {
+	this.dashboard.mutex.Lock()
+	defer this.dashboard.mutex.Unlock()
+	*this.held = append(*this.held, p...)
+	for _, line := range strings.Split(string(p), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			this.dashboard.messages = append(this.dashboard.messages, line)
+		}
+	}
+	if excess := len(this.dashboard.messages) - dashboardMessages; excess > 0 {
+		this.dashboard.messages = this.dashboard.messages[excess:]
+	}
+	return len(p), nil
+}
+
+// StartDashboard takes over the terminal on stderr, or returns nil if stderr
+// is no terminal.
+func StartDashboard() (this *Dashboard) {
+	if !IsTerminal(os.Stderr) {
+		logger.Infof("-tui needs a terminal on stderr, reporting progress as usual")
+		return nil
+	}
+	logger.StopProgress()
+	this = &Dashboard{out: os.Stderr, copied: make(chan bool), stop: make(chan bool), done: make(chan bool)}
+	reader, writer, err := os.Pipe()
+	check(err)
+	this.stdout, this.pipe, os.Stdout = os.Stdout, writer, writer
+	go func() {
+		defer close(this.copied)
+		io.Copy(dashboardWriter{this, &this.printed}, reader)
+		reader.Close()
+	}()
+	logger.mutex.Lock()
+	logger.out = dashboardWriter{this, &this.logged}
+	logger.mutex.Unlock()
+	// switch to the alternate screen and hide the cursor
+	fmt.Fprint(this.out, "\x1b[?1049h\x1b[?25l")
+	go func() {
+		defer close(this.done)
+		ticker := time.NewTicker(dashboardInterval)
+		defer ticker.Stop()
+		for {
+			this.Draw()
+			select {
+			case <-ticker.C:
+			case <-this.stop:
+				return
+			}
+		}
+	}()
+	return this
+}
+
+// Watch shows the analysis progress of the tables.
+func (this *Dashboard) Watch(db Database) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.db = db
+}
+
+func (this *Dashboard) Validation(validated int, remaining int, found int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.validating = true
+	this.validated, this.remaining, this.found = validated, remaining, found
+}
+
+func (this *Dashboard) Draw() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	phase, done, total := monitor.Progress()
+	fmt.Fprintf(&screen, "%8.1fs %v", time.Since(logger.started).Seconds(), phase)
+	if total > 0 {
+		fmt.Fprintf(&screen, ": %v of %v (%.0f%%)", done, total, 100*float64(done)/float64(total))
+	}
+	screen.WriteString("\n\n")
+	if len(this.db) > 0 {
+		this.DrawTables(&screen)
+	}
+	if this.validating {
+		fmt.Fprintf(&screen, "\nvalidated %v candidates, %v left, %v inclusions found\n", this.validated, this.remaining, this.found)
+	}
+	if len(this.messages) > 0 {
+		screen.WriteString("\n")
+		for _, message := range this.messages {
+			screen.WriteString(message + "\n")
+		}
+	}
+	this.out.WriteString(screen.String())
+}
+
+// DrawTables lists the tables not analyzed yet first.
+func (this *Dashboard) DrawTables(w io.Writer) {
+	var tables, analyzed []*Table
+	for _, table := range this.db {
+		if atomic.LoadInt32(&table.analyzed) != 0 {
+			analyzed = append(analyzed, table)
+		} else {
+			tables = append(tables, table)
+		}
+	}
+	fmt.Fprintf(w, "%v of %v tables analyzed\n", len(analyzed), len(this.db))
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for i, current := range append(tables, analyzed...) {
+		if i == dashboardTables {
+			fmt.Fprintf(table, "  and %v more\n", len(this.db)-i)
+			break
+		}
+		rows := atomic.LoadInt64(&current.progress)
+		state := "waiting"
+		if atomic.LoadInt32(&current.analyzed) != 0 {
+			state = "analyzed"
+		} else if rows > 0 {
+			state = "analyzing"
+		}
+		fmt.Fprintf(table, "  %v\t%v rows\t%v\n", current.QualifiedName(), rows, state)
+	}
+	table.Flush()
+}
+
+// StopDrawing stops redrawing the dashboard, leaving it on the screen.
+func (this *Dashboard) StopDrawing() {
+	if this.stop == nil {
+		return
+	}
+	close(this.stop)
+	<-this.done
+	this.stop = nil
+}
+
+// Close restores the terminal and prints the messages and results held back.
+func (this *Dashboard) Close() {
+	if this.closed {
+		return
+	}
+	this.closed = true
+	this.StopDrawing()
+	os.Stdout = this.stdout
+	this.pipe.Close()
+	<-this.copied
+	logger.mutex.Lock()
+	logger.out = this.out
+	logger.mutex.Unlock()
+	fmt.Fprint(this.out, "\x1b[?25h\x1b[?1049l")
+	this.out.Write(this.logged)
+	this.stdout.Write(this.printed)
+}
+
+// Tabulate returns the lines written to a tabwriter.
+func Tabulate(write func(w io.Writer)) []string {
+	var text strings.Builder
+	w := tabwriter.NewWriter(&text, 0, 4, 2, ' ', 0)
+	write(w)
+	w.Flush()
+	return strings.Split(strings.TrimSuffix(text.String(), "\n"), "\n")
+}
+
+// BrowserTables lists the tables by number.
+func BrowserTables(tables []*htmlTable) []string {
+	return Tabulate(func(w io.Writer) {
+		fmt.Fprintln(w, "#\ttable\trows\tcolumns\treferences\treferenced by")
+		for i, table := range tables {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", i+1, table.Name, table.Rows, len(table.Columns), table.References, table.ReferencedBy)
+		}
+	})
+}
+
+// BrowserTable describes the columns of a table and the inclusions they take
+// part in.
+func BrowserTable(table *htmlTable) (lines []string) {
+	lines = append(lines, fmt.Sprintf("%v rows", table.Rows))
+	if table.SampledFrom > 0 {
+		lines[0] += fmt.Sprintf(", sampled from %v", table.SampledFrom)
+	}
+	lines = append(lines, "")
+	lines = append(lines, Tabulate(func(w io.Writer) {
+		fmt.Fprintln(w, "column\ttype\tdistinct\tnulls\tminimum\tmaximum\tpattern")
+		for _, column := range table.Columns {
+			result := column.Result
+			minimum, maximum, pattern := "-", "-", "-"
+			if result.Minimum != nil {
+				minimum = *result.Minimum
+			}
+			if result.Maximum != nil {
+				maximum = *result.Maximum
+			}
+			if len(result.Patterns) > 0 {
+				pattern = result.Patterns[0].Pattern
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", result.Name, result.DataType, result.DistinctValues, result.Nulls, minimum, maximum, pattern)
+		}
+	})...)
+	for _, list := range []struct {
+		label string
+		items []string
+	}{{"keys", table.Keys}, {"functional dependencies", table.Dependencies}, {"order dependencies", table.OrderDependencies}} {
+		if len(list.items) > 0 {
+			lines = append(lines, "", list.label+": "+strings.Join(list.items, "; "))
+		}
+	}
+	for _, column := range table.Columns {
+		if len(column.References) == 0 && len(column.ReferencedBy) == 0 {
+			continue
+		}
+		lines = append(lines, "", column.Result.Name)
+		for _, link := range column.References {
+			lines = append(lines, "  included in "+BrowserLink(link))
+		}
+		for _, link := range column.ReferencedBy {
+			lines = append(lines, "  including "+BrowserLink(link))
+		}
+	}
+	return lines
+}
+
+func BrowserLink(link htmlLink) string {
+	if link.Coverage != "" {
+		return link.Label + " (" + link.Coverage + " covered)"
+	}
+	return link.Label
+}
+
+// BrowserInclusions lists the inclusions containing the text.
+func BrowserInclusions(tables []*htmlTable, text string) (lines []string) {
+	for _, table := range tables {
+		for _, column := range table.Columns {
+			for _, link := range column.References {
+				line := table.Name + "." + column.Result.Name + " <= " + BrowserLink(link)
+				if strings.Contains(line, text) {
+					lines = append(lines, line)
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// Browse shows the results page by page until the user quits or stdin ends.
+func (this *Dashboard) Browse(graph *InclusionGraph) {
+	this.StopDrawing()
+	tables := graph.HTMLTables()
+	var title, notice string
+	var lines []string
+	offset := 0
+	show := func(heading string, content []string) {
+		title, lines, offset = heading, content, 0
+	}
+	show("tables", BrowserTables(tables))
+	input := bufio.NewScanner(os.Stdin)
+	for {
+		this.DrawPage(title, lines, offset, notice)
+		notice = ""
+		if !input.Scan() {
+			return
+		}
+		command := strings.TrimSpace(input.Text())
+		number, err := strconv.Atoi(command)
+		switch {
+		case command == "q":
+			return
+		case command == "" || command == "n":
+			if offset+browserPage < len(lines) {
+				offset += browserPage
+			}
+		case command == "p":
+			offset -= browserPage
+			if offset < 0 {
+				offset = 0
+			}
+		case command == "t":
+			show("tables", BrowserTables(tables))
+		case command == "i":
+			show("inclusions", BrowserInclusions(tables, ""))
+		case strings.HasPrefix(command, "/"):
+			show("inclusions containing "+command[1:], BrowserInclusions(tables, command[1:]))
+		case err == nil && number >= 1 && number <= len(tables):
+			show(tables[number-1].Name, BrowserTable(tables[number-1]))
+		default:
+			notice = "unknown command " + command
+		}
+	}
+}
+
+func (this *Dashboard) DrawPage(title string, lines []string, offset int, notice string) {
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	screen.WriteString(Colorize(title, cyan) + "\n\n")
+	end := offset + browserPage
+	if end > len(lines) {
+		end = len(lines)
+	}
+	for _, line := range lines[offset:end] {
+		screen.WriteString(line + "\n")
+	}
+	if len(lines) == 0 {
+		screen.WriteString("none\n")
+	}
+	if len(lines) > browserPage {
+		fmt.Fprintf(&screen, "\nlines %v-%v of %v\n", offset+1, end, len(lines))
+	}
+	if notice != "" {
+		screen.WriteString("\n" + Colorize(notice, yellow) + "\n")
+	}
+	screen.WriteString("\nnumber: table, t: tables, i: inclusions, /text: inclusions containing text, n/p: next/previous page, q: quit\n> ")
+	this.out.WriteString(screen.String())
+}
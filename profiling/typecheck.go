@@ -0,0 +1,149 @@
+package profiling
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// number of mismatching values shown per column
+const reportedMismatches = 3
+
+var (
+	sqlTypePattern = regexp.MustCompile(`^\s*([a-z][a-z0-9 ]*?)\s*(\(\s*(\d+)\s*(,\s*(\d+)\s*)?\))?\s*$`)
+	sqlTypeClasses = map[string]string{
+		"int": "integer", "integer": "integer", "bigint": "integer", "smallint": "integer", "tinyint": "integer", "mediumint": "integer",
+		"int2": "integer", "int4": "integer", "int8": "integer", "serial": "integer", "bigserial": "integer", "smallserial": "integer",
+		"decimal": "numeric", "numeric": "numeric", "number": "numeric", "float": "numeric", "float4": "numeric", "float8": "numeric",
+		"double": "numeric", "double precision": "numeric", "real": "numeric", "money": "numeric",
+		"date": "date", "timestamp": "timestamp", "timestamptz": "timestamp", "datetime": "timestamp", "datetime2": "timestamp",
+		"timestamp with time zone": "timestamp", "timestamp without time zone": "timestamp", "time": "time",
+		"bool": "boolean", "boolean": "boolean", "bit": "boolean",
+		"char": "string", "character": "string", "varchar": "string", "character varying": "string", "nchar": "string",
+		"nvarchar": "string", "varchar2": "string", "nvarchar2": "string", "text": "string", "string": "string", "clob": "string",
+	}
+	booleanValues = map[string]bool{"true": true, "false": true, "t": true, "f": true, "1": true, "0": true, "yes": true, "no": true, "y": true, "n": true}
+)
+
+// DeclaredType is a column type declared by the source database, reduced to
+// what its values may look like.
+type DeclaredType struct {
+	name string
+	// integer, numeric, date, timestamp, time, boolean or string
+	class string
+	// maximum number of characters, or 0 for any
+	length int
+}
+
+// ParseDeclaredType classifies SQL types like INT, NUMBER(10,0) or
+// VARCHAR(20). Unknown types yield nil.
+func ParseDeclaredType(name string) *DeclaredType {
+	parts := sqlTypePattern.FindStringSubmatch(strings.ToLower(name))
+	if parts == nil {
+		return nil
+	}
+	class, ok := sqlTypeClasses[parts[1]]
+	if !ok {
+		return nil
+	}
+	result := &DeclaredType{name: name, class: class}
+	// decimals without a scale hold integers
+	if class == "numeric" && parts[3] != "" && (parts[5] == "" || parts[5] == "0") && parts[1] != "float" {
+		result.class = "integer"
+	}
+	if class == "string" && parts[3] != "" {
+		result.length, _ = strconv.Atoi(parts[3])
+	}
+	return result
+}
+
+// Admits reports whether a value can be stored in a column of the type.
+func (this *DeclaredType) Admits(value string) bool {
+	switch this.class {
+	case "integer":
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case "numeric":
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case "date", "timestamp":
+		for _, layout := range dateLayouts {
+			if _, err := time.Parse(layout, value); err == nil {
+				return true
+			}
+		}
+		return false
+	case "time":
+		for _, layout := range []string{"15:04:05.999999999", "15:04"} {
+			if _, err := time.Parse(layout, value); err == nil {
+				return true
+			}
+		}
+		return false
+	case "boolean":
+		return booleanValues[strings.ToLower(value)]
+	}
+	return this.length == 0 || utf8.RuneCountInString(value) <= this.length
+}
+
+// CompatibleWith reports whether values of the type may be stored in a
+// column of the other type without conversion between numbers, dates and
+// text.
+func (this *DeclaredType) CompatibleWith(other *DeclaredType) bool {
+	family := func(class string) string {
+		switch class {
+		case "integer", "numeric":
+			return "number"
+		case "date", "timestamp":
+			return "date"
+		}
+		return class
+	}
+	return family(this.class) == family(other.class)
+}
+
+// TypeMismatches returns the column's distinct non-empty values its declared
+// type does not admit, like alpha values in an INT or 0000-00-00 in a DATE.
+func (this *Column) TypeMismatches() (mismatches []string) {
+	if this.declaredType == nil {
+		return nil
+	}
+	this.EachSortedValue(func(value string) bool {
+		if value != "" && !this.declaredType.Admits(value) {
+			mismatches = append(mismatches, value)
+		}
+		return true
+	})
+	return mismatches
+}
+
+// PrintTypeMismatches compares the types the catalog declares with the
+// values observed.
+func (db Database) PrintTypeMismatches() {
+	declared, mismatching := 0, 0
+	for _, column := range db.AllColumns() {
+		if column.declaredType == nil {
+			continue
+		}
+		declared++
+		mismatches := column.TypeMismatches()
+		if len(mismatches) == 0 {
+			continue
+		}
+		mismatching++
+		var examples []string
+		for i, value := range mismatches {
+			if i == reportedMismatches {
+				examples = append(examples, "...")
+				break
+			}
+			examples = append(examples, strconv.Quote(Redact(value)))
+		}
+		fmt.Printf("declared type %v of %v does not admit %v of %v distinct values, observed %v: %v\n", column.declaredType.name, column.Label(),
+			len(mismatches), column.DistinctValues(), column.dataType, strings.Join(examples, ", "))
+	}
+	fmt.Println(mismatching, "of", declared, "declared types mismatch the data")
+}
@@ -0,0 +1,97 @@
+package profiling
+
+import (
+	"regexp"
+	"strings"
+)
+
+// dataTypes are ordered from the most to the least specific, every value is
+// a string
+var dataTypes = []string{"int", "float", "bool", "date", "uuid", "string"}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Admits reports whether a value is of the data type. Only words count as
+// booleans, 0 and 1 are ints.
+func Admits(dataType string, value string) bool {
+	switch dataType {
+	case "int":
+		return IsInt(value)
+	case "float":
+		return IsFloat(value)
+	case "bool":
+		return booleanValues[strings.ToLower(value)] && !IsInt(value)
+	case "date":
+		_, layout := ParseDate(value)
+		return layout >= 0
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	}
+	return true
+}
+
+// InferType lets the sampled non-empty values vote for every type they are
+// of and returns the most specific type at least the share agreement of
+// them voted for.
+func InferType(sample []string, agreement float64) string {
+	votes := make(map[string]int)
+	values := 0
+	for _, value := range sample {
+		if value == "" {
+			continue
+		}
+		values++
+		for _, candidate := range dataTypes {
+			if Admits(candidate, value) {
+				votes[candidate]++
+			}
+		}
+	}
+	for _, candidate := range dataTypes {
+		if values > 0 && float64(votes[candidate]) >= agreement*float64(values) {
+			return candidate
+		}
+	}
+	return "string"
+}
+
+func IsDataType(dataType string) bool {
+	for _, known := range dataTypes {
+		if dataType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitColumnType separates the type a column in mapping.tsv may declare
+// after a colon, as in amount:float, from its name. A declared type overrides
+// the inferred one like -types does. Names with a colon not followed by a
+// type keep it.
+func SplitColumnType(column string) (name string, dataType string) {
+	if i := strings.LastIndex(column, ":"); i > 0 && IsDataType(column[i+1:]) {
+		return column[:i], column[i+1:]
+	}
+	return column, ""
+}
+
+// OverrideTypes reads a file of table.column<TAB>type lines and forces those
+// columns to the given type instead of inferring it from their values,
+// e.g. for numeric codes that must be compared as strings.
+func (db Database) OverrideTypes(fileName string) {
+	lineReader := NewLineReader(fileName)
+	for {
+		fields := ReadRow(lineReader)
+		if len(fields) == 0 {
+			break
+		}
+		if len(fields) != 2 || !IsDataType(fields[1]) {
+			panic("type overrides need a column and one of " + strings.Join(dataTypes, ", ") + " per line")
+		}
+		column := db.FindColumn(fields[0])
+		if column == nil {
+			panic("unknown column in type overrides: " + fields[0])
+		}
+		column.typeOverride = fields[1]
+	}
+}
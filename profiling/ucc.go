@@ -0,0 +1,124 @@
+package profiling
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unique column combinations are the sets of columns whose combined values
+// differ in every row of a table, so each is a candidate key. Only minimal
+// ones are reported: a combination is left out if some of its columns are
+// unique on their own already. Empty values count as values.
+
+// CombineKeys joins non-unique combinations of n columns agreeing on all but
+// their last column to candidates of n+1 columns, keeping only those all of
+// whose combinations of n columns are non-unique, as in Apriori.
+func CombineKeys(level [][]*Column) (candidates [][]*Column) {
+	nonUnique := make(map[string]bool, len(level))
+	for _, columns := range level {
+		nonUnique[ProjectionKey(columns)] = true
+	}
+	for i, x := range level {
+		for _, y := range level[i+1:] {
+			last := len(x) - 1
+			if ProjectionKey(x[:last]) != ProjectionKey(y[:last]) || x[last].index == y[last].index {
+				continue
+			}
+			candidate := append(append([]*Column(nil), x...), y[last])
+			if x[last].index > y[last].index {
+				candidate[last], candidate[last+1] = y[last], x[last]
+			}
+			pruned := false
+			for j := 0; j < len(candidate)-2 && !pruned; j++ {
+				subset := append(append([]*Column(nil), candidate[:j]...), candidate[j+1:]...)
+				pruned = !nonUnique[ProjectionKey(subset)]
+			}
+			if !pruned {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	return candidates
+}
+
+// MayBeUnique tells whether the columns have enough distinct values for a
+// different combination in every row.
+func (this *Table) MayBeUnique(columns []*Column) bool {
+	combinations := 1
+	for _, column := range columns {
+		if combinations *= column.DistinctValues(); combinations >= this.rowCount {
+			return true
+		}
+	}
+	return false
+}
+
+// FindKeys searches the minimal unique column combinations of up to
+// maxColumns columns level by level, reading the table once per level for
+// the combinations not ruled out by their columns' distinct values. Empty
+// tables have no keys.
+func (this *Table) FindKeys(maxColumns int) {
+	this.keys = nil
+	if this.rowCount == 0 {
+		return
+	}
+	var level [][]*Column
+	for _, column := range this.columns {
+		if column.IsUnique() {
+			this.keys = append(this.keys, []*Column{column})
+		} else {
+			level = append(level, []*Column{column})
+		}
+	}
+	for size := 2; size <= maxColumns && len(level) > 1; size++ {
+		var next, read [][]*Column
+		for _, columns := range CombineKeys(level) {
+			if this.MayBeUnique(columns) {
+				read = append(read, columns)
+			} else {
+				next = append(next, columns)
+			}
+		}
+		if len(read) == 0 {
+			level = next
+			continue
+		}
+		for i, tuples := range this.ReadProjections(read) {
+			if len(tuples) == this.rowCount {
+				this.keys = append(this.keys, read[i])
+			} else {
+				next = append(next, read[i])
+			}
+		}
+		level = next
+	}
+}
+
+// FindKeys searches the keys of all tables, of -max-key-size columns at most.
+func (db Database) FindKeys(options *Options) {
+	RunWorkers(options.analysisWorkers, len(db), func(i int) {
+		db[i].FindKeys(options.maxKeySize)
+	})
+}
+
+func KeyString(columns []*Column) string {
+	var names []string
+	for _, column := range columns {
+		names = append(names, column.name)
+	}
+	return fmt.Sprintf("%v[%v]", columns[0].table.QualifiedName(), strings.Join(names, ", "))
+}
+
+func (db Database) PrintKeys() {
+	count := 0
+	for _, table := range db {
+		count += len(table.keys)
+	}
+	fmt.Println("found", count, "unique column combinations")
+	status.Result("unique column combinations", count)
+	for _, table := range db {
+		for _, key := range table.keys {
+			fmt.Println(KeyString(key))
+		}
+	}
+}
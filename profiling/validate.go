@@ -0,0 +1,119 @@
+package profiling
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// number of rows of each data file compared against the mapping
+const validateRows = 100
+
+// ValidateMapping checks mapping.tsv and the files it references, returning
+// every problem found instead of stopping at the first one.
+func ValidateMapping(dataDir string, hasHeader bool, format *FileFormat) (problems []string) {
+	mappingFileName := dataDir + "mapping.tsv"
+	if _, err := os.Stat(mappingFileName); err != nil {
+		return []string{err.Error()}
+	}
+	report := func(line int, format string, arguments ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%v:%v: ", mappingFileName, line)+fmt.Sprintf(format, arguments...))
+	}
+	names := make(map[string]int)
+	ids := make(map[string]int)
+	lineReader := NewLineReader(mappingFileName)
+	for line := 1; ; line++ {
+		fields := ReadRow(lineReader)
+		if len(fields) == 0 {
+			break
+		}
+		if len(fields) < 3 {
+			report(line, "expected a table name, a file and at least one column")
+			continue
+		}
+		name, columns := fields[0], fields[2:]
+		file, spec := SplitFileFormat(fields[1])
+		if spec != "" {
+			if _, err := ParseFileFormat(spec); err != nil {
+				report(line, "%v", err)
+				continue
+			}
+		}
+		if previous, ok := names[name]; ok {
+			report(line, "table %v is already defined on line %v", name, previous)
+		} else {
+			names[name] = line
+		}
+		id := TableId(file)
+		if previous, ok := ids[id]; ok {
+			report(line, "table id %v of %v is already used on line %v", id, file, previous)
+		} else {
+			ids[id] = line
+		}
+		seen := make(map[string]bool)
+		for _, column := range columns {
+			column, _ = SplitColumnType(column)
+			if column == "" {
+				report(line, "table %v has an empty column name", name)
+			} else if seen[column] {
+				report(line, "table %v has column %v twice", name, column)
+			}
+			seen[column] = true
+		}
+		if filepath.IsAbs(file) || strings.HasPrefix(filepath.Clean(file), "..") {
+			report(line, "file %v is not inside the data directory", file)
+			continue
+		}
+		path := dataDir + file
+		info, err := os.Stat(path)
+		if err != nil {
+			report(line, "%v", err)
+			continue
+		}
+		if info.IsDir() {
+			if _, _, err := DiscoverPartitions(path); err != nil {
+				report(line, "%v", err)
+				continue
+			}
+		} else if !info.Mode().IsRegular() {
+			report(line, "%v is not a regular file", path)
+			continue
+		} else {
+			handle, err := os.Open(path)
+			if err != nil {
+				report(line, "%v", err)
+				continue
+			}
+			handle.Close()
+		}
+		table, err := BuildTable(dataDir, fields, format)
+		check(err)
+		table.hasHeader = hasHeader
+		rows := table.OpenRows()
+		for row := 1; row <= validateRows; row++ {
+			values := rows.Read()
+			if len(values) == 0 {
+				break
+			}
+			if len(values) != table.fields {
+				report(line, "%v has %v columns in row %v, but %v are mapped", file, len(values), row, table.fields)
+				break
+			}
+		}
+	}
+	return problems
+}
+
+func RunValidateConfig(options *Options) {
+	problems := ValidateMapping(options.dataDir, options.header, options.FileFormat())
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Println("found", len(problems), "problems")
+		options.exitCode = 1
+		return
+	}
+	fmt.Println("mapping is valid")
+}
@@ -0,0 +1,55 @@
+package profiling
+
+// Validator decides whether a candidate inclusion holds, or with
+// -min-coverage how many of its dependent values are included. Parallel
+// validators may check several candidates at once.
+type Validator interface {
+	Check(candidate *Candidate) bool
+	Coverage(candidate *Candidate) float64
+	Parallel() bool
+	Close()
+}
+
+// memoryValidator compares the value sets collected during analysis.
+type memoryValidator struct {
+	db          Database
+	ignoreNulls bool
+}
+
+func (this memoryValidator) Check(candidate *Candidate) bool {
+	if candidate.a.spilled != nil || candidate.b.spilled != nil {
+		distinct, included := this.db.CompareValues(candidate, this.ignoreNulls, true)
+		return distinct == included
+	}
+	return this.db.Check(candidate, this.ignoreNulls)
+}
+
+func (this memoryValidator) Coverage(candidate *Candidate) float64 {
+	if candidate.a.spilled != nil || candidate.b.spilled != nil {
+		distinct, included := this.db.CompareValues(candidate, this.ignoreNulls, false)
+		if distinct == 0 {
+			return 1
+		}
+		return float64(included) / float64(distinct)
+	}
+	return this.db.Coverage(candidate, this.ignoreNulls)
+}
+
+func (this memoryValidator) Parallel() bool {
+	return true
+}
+
+func (this memoryValidator) Close() {
+}
+
+func NewValidator(options *Options, db Database) Validator {
+	switch options.validator {
+	case "memory":
+		return memoryValidator{db, options.IgnoreNulls()}
+	case "duckdb":
+		return NewDuckDBValidator(db, options.IgnoreNulls())
+	case "spider":
+		return NewSpiderValidator(options, db)
+	}
+	panic("unknown validator " + options.validator + ", use memory, duckdb or spider")
+}